@@ -17,14 +17,37 @@
 package analytics
 
 import (
+	"context"
 	"fmt"
+	"github.com/loopholelabs/releaser/analytics/bolt"
 	"github.com/loopholelabs/releaser/analytics/posthog"
+	"math/rand"
+	"strconv"
+	"sync"
 )
 
-var _ Handler = (*posthog.PostHog)(nil)
+var (
+	_ Handler = (*posthog.PostHog)(nil)
+	_ Handler = (*bolt.Bolt)(nil)
+)
+
+const (
+	// distinctIDKey is the key of the Property created by DistinctID
+	distinctIDKey = "$distinct_id"
+
+	// SampleRateKey is set on sampled events to the percentage of them that is sent
+	SampleRateKey = "sample_rate"
+)
 
 var (
-	handler Handler
+	mu          sync.RWMutex
+	handler     Handler
+	defaults    []Property
+	sampleRates map[string]int
+	enrichers   []Enricher
+
+	// allowedEvents are the names of the events that are sent, every event is sent if it is empty
+	allowedEvents map[string]struct{}
 )
 
 func init() {
@@ -40,19 +63,132 @@ type Handler interface {
 	Cleanup()
 }
 
-func Event(id string, name string, properties ...map[string]string) {
-	if handler != nil {
-		if len(properties) > 0 {
-			handler.Event(id, name, properties[0])
-		} else {
-			handler.Event(id, name, nil)
+// Enricher adds properties to an event before it is sent (e.g. the region of the server or the
+// country of the client), properties holds the properties of the event so far and may be modified.
+// The context is the one the event was sent with, and may carry the request the event belongs to.
+type Enricher func(ctx context.Context, name string, properties map[string]string)
+
+// Property is a single property of an analytics event
+type Property struct {
+	Key   string
+	Value string
+}
+
+// NewProperty returns a property with the given key and value
+func NewProperty(key string, value string) Property {
+	return Property{Key: key, Value: value}
+}
+
+// DistinctID identifies who triggered an event, it is sent as the distinct ID of the event
+// instead of as a property. Events without one are attributed to the event name.
+func DistinctID(id string) Property {
+	return Property{Key: distinctIDKey, Value: id}
+}
+
+// SetHandler replaces the handler events are sent to (e.g. a local database instead of PostHog),
+// the previous handler is cleaned up
+func SetHandler(h Handler) {
+	mu.Lock()
+	previous := handler
+	handler = h
+	mu.Unlock()
+
+	if previous != nil {
+		previous.Cleanup()
+	}
+}
+
+// SetDefaults sets the properties included in every event (e.g. the identity of the server)
+func SetDefaults(properties ...Property) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaults = properties
+}
+
+// SetSampleRates sets the percentage (0 to 100) of the events with the given names that are sent,
+// events without a sample rate are always sent
+func SetSampleRates(rates map[string]int) {
+	mu.Lock()
+	defer mu.Unlock()
+	sampleRates = rates
+}
+
+// SetAllowedEvents sets the names of the events that are sent, other events are dropped before
+// they are sampled or enriched. If no names are given, every event is sent.
+func SetAllowedEvents(names []string) {
+	allowed := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		allowed[name] = struct{}{}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	allowedEvents = allowed
+}
+
+// Use adds the given enrichers to the chain every event is passed through before it is sent,
+// enrichers run in the order they were added
+func Use(enricher ...Enricher) {
+	mu.Lock()
+	defer mu.Unlock()
+	enrichers = append(enrichers, enricher...)
+}
+
+// Event sends an event with the given name and properties, the properties override the defaults
+func Event(name string, properties ...Property) {
+	EventContext(context.Background(), name, properties...)
+}
+
+// EventContext is like Event, but passes the given context to the enrichers
+func EventContext(ctx context.Context, name string, properties ...Property) {
+	mu.RLock()
+	h := handler
+	if h == nil {
+		mu.RUnlock()
+		return
+	}
+	if _, ok := allowedEvents[name]; !ok && len(allowedEvents) > 0 {
+		mu.RUnlock()
+		return
+	}
+	rate, sampled := sampleRates[name]
+	props := make(map[string]string, len(defaults)+len(properties)+1)
+	for _, property := range defaults {
+		props[property.Key] = property.Value
+	}
+	chain := enrichers
+	mu.RUnlock()
+
+	if sampled {
+		if rand.Intn(100) >= rate {
+			return
+		}
+		props[SampleRateKey] = strconv.Itoa(rate)
+	}
+
+	id := name
+	for _, property := range properties {
+		if property.Key == distinctIDKey {
+			id = property.Value
+			continue
 		}
+		props[property.Key] = property.Value
+	}
+
+	for _, enrich := range chain {
+		enrich(ctx, name, props)
 	}
+
+	h.Event(id, name, props)
 }
 
 func Cleanup() {
-	if handler != nil {
-		handler.Cleanup()
-		handler = nil
+	mu.Lock()
+	h := handler
+	handler = nil
+	mu.Unlock()
+
+	if h != nil {
+		h.Cleanup()
 	}
 }
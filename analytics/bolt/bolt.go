@@ -0,0 +1,248 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bolt
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"go.etcd.io/bbolt"
+	"sync"
+	"time"
+)
+
+const (
+	// queueSize bounds the number of events waiting to be written to the database,
+	// events sent while the queue is full are dropped instead of blocking the caller
+	queueSize = 4096
+
+	// batchSize is the maximum number of events written to the database in a single transaction
+	batchSize = 100
+
+	// openTimeout is how long Open waits for the database to be released by another process
+	openTimeout = time.Second
+
+	// pruneInterval is how often events older than the retention are deleted
+	pruneInterval = time.Hour
+
+	// pruneBatchSize is the maximum number of events deleted in a single transaction
+	pruneBatchSize = 1000
+)
+
+// eventsBucket is the bucket events are stored in, keyed by their sequence number
+var eventsBucket = []byte("events")
+
+// Record is a single analytics event stored in the database
+type Record struct {
+	Time       time.Time         `json:"time"`
+	ID         string            `json:"id"`
+	Name       string            `json:"name"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+// Bolt is an analytics handler that stores events in a local bbolt database
+// instead of sending them to a third party
+type Bolt struct {
+	db *bbolt.DB
+
+	// retention is how long events are kept, they are kept forever if it is zero
+	retention time.Duration
+
+	// mu guards closing the queue against concurrent events
+	mu     sync.RWMutex
+	closed bool
+	queue  chan *Record
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// Open opens the database at the given path for storing events, creating it if it does not exist.
+// Events older than the given retention are deleted periodically, unless the retention is zero.
+func Open(path string, retention time.Duration) (*Bolt, error) {
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bbolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(eventsBucket)
+		return err
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	b := &Bolt{
+		db:        db,
+		retention: retention,
+		queue:     make(chan *Record, queueSize),
+		stop:      make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go b.deliver()
+	if retention > 0 {
+		b.wg.Add(1)
+		go b.pruneLoop()
+	}
+	return b, nil
+}
+
+func (b *Bolt) Event(id string, name string, properties map[string]string) {
+	r := &Record{
+		Time:       time.Now().UTC(),
+		ID:         id,
+		Name:       name,
+		Properties: properties,
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	select {
+	case b.queue <- r:
+	default:
+	}
+}
+
+// deliver writes the queued events to the database, in batches of the events queued at the time
+func (b *Bolt) deliver() {
+	defer b.wg.Done()
+	batch := make([]*Record, 0, batchSize)
+	for r := range b.queue {
+		batch = append(batch[:0], r)
+	fill:
+		for len(batch) < batchSize {
+			select {
+			case r, ok := <-b.queue:
+				if !ok {
+					break fill
+				}
+				batch = append(batch, r)
+			default:
+				break fill
+			}
+		}
+		_ = b.write(batch)
+	}
+}
+
+// write stores the given events in the database in a single transaction
+func (b *Bolt) write(batch []*Record) error {
+	return b.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(eventsBucket)
+		for _, r := range batch {
+			value, err := json.Marshal(r)
+			if err != nil {
+				return err
+			}
+			sequence, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			key := make([]byte, 8)
+			binary.BigEndian.PutUint64(key, sequence)
+			err = bucket.Put(key, value)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// pruneLoop deletes the events older than the retention until the database is closed
+func (b *Bolt) pruneLoop() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		_ = b.Prune(time.Now().UTC().Add(-b.retention))
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Prune deletes the events sent before the given time, in batches so that
+// writing new events is not blocked for long
+func (b *Bolt) Prune(before time.Time) error {
+	for {
+		var keys [][]byte
+		err := b.db.Update(func(tx *bbolt.Tx) error {
+			// events are stored in the order they were sent, so the cursor
+			// walks forwards from the oldest event until it reaches before
+			bucket := tx.Bucket(eventsBucket)
+			cursor := bucket.Cursor()
+			for key, value := cursor.First(); key != nil && len(keys) < pruneBatchSize; key, value = cursor.Next() {
+				r := new(Record)
+				err := json.Unmarshal(value, r)
+				if err != nil {
+					return err
+				}
+				if !r.Time.Before(before) {
+					break
+				}
+				keys = append(keys, key)
+			}
+			for _, key := range keys {
+				err := bucket.Delete(key)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil || len(keys) < pruneBatchSize {
+			return err
+		}
+	}
+}
+
+// Records calls fn with every stored event in the order they were sent, stopping at the first error
+func (b *Bolt) Records(fn func(r *Record) error) error {
+	return b.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket(eventsBucket).ForEach(func(_ []byte, value []byte) error {
+			r := new(Record)
+			err := json.Unmarshal(value, r)
+			if err != nil {
+				return err
+			}
+			return fn(r)
+		})
+	})
+}
+
+// Cleanup writes the queued events and closes the database, events
+// sent after Cleanup has been called are dropped
+func (b *Bolt) Cleanup() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.queue)
+	close(b.stop)
+	b.mu.Unlock()
+
+	b.wg.Wait()
+	_ = b.db.Close()
+}
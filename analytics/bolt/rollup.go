@@ -0,0 +1,113 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package bolt
+
+import (
+	"encoding/json"
+	"go.etcd.io/bbolt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// sampleRateKey is the property sampled events carry the percentage of them that is sent in,
+// see analytics.SampleRateKey
+const sampleRateKey = "sample_rate"
+
+// Rollup is the number of events with the same version, platform, and source within an interval,
+// sampled events are counted as the number of events they were sampled from
+type Rollup struct {
+	Start    time.Time
+	Version  string
+	Platform string
+	Source   string
+	Count    int
+}
+
+// Rollups counts the events with the given name sent since the given time by version, platform, and source,
+// grouping them into the intervals returned by truncate (e.g. the start of the day of an event).
+// The version is the release_name property of the events, the platform their os and arch properties,
+// and the source their source property. Every sampled event counts as 100/sample_rate events, so that the counts
+// estimate the number of events sent before sampling. Rollups are sorted by interval, and then by version,
+// platform, and source.
+func (b *Bolt) Rollups(name string, since time.Time, truncate func(time.Time) time.Time) ([]*Rollup, error) {
+	type rollupKey struct {
+		start    time.Time
+		version  string
+		platform string
+		source   string
+	}
+	counts := make(map[rollupKey]float64)
+
+	err := b.db.View(func(tx *bbolt.Tx) error {
+		// events are stored in the order they were sent, so the cursor
+		// walks backwards from the newest event until it passes since
+		cursor := tx.Bucket(eventsBucket).Cursor()
+		for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+			r := new(Record)
+			err := json.Unmarshal(value, r)
+			if err != nil {
+				return err
+			}
+			if r.Time.Before(since) {
+				return nil
+			}
+			if r.Name != name {
+				continue
+			}
+			weight := 1.0
+			if rate, err := strconv.Atoi(r.Properties[sampleRateKey]); err == nil && rate > 0 {
+				weight = 100 / float64(rate)
+			}
+			counts[rollupKey{
+				start:    truncate(r.Time),
+				version:  r.Properties["release_name"],
+				platform: r.Properties["os"] + "/" + r.Properties["arch"],
+				source:   r.Properties["source"],
+			}] += weight
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rollups := make([]*Rollup, 0, len(counts))
+	for key, count := range counts {
+		rollups = append(rollups, &Rollup{
+			Start:    key.start,
+			Version:  key.version,
+			Platform: key.platform,
+			Source:   key.source,
+			Count:    int(math.Round(count)),
+		})
+	}
+	sort.Slice(rollups, func(i, j int) bool {
+		if !rollups[i].Start.Equal(rollups[j].Start) {
+			return rollups[i].Start.Before(rollups[j].Start)
+		}
+		if rollups[i].Version != rollups[j].Version {
+			return rollups[i].Version < rollups[j].Version
+		}
+		if rollups[i].Platform != rollups[j].Platform {
+			return rollups[i].Platform < rollups[j].Platform
+		}
+		return rollups[i].Source < rollups[j].Source
+	})
+	return rollups, nil
+}
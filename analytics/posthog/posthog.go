@@ -18,9 +18,21 @@ package posthog
 
 import (
 	"github.com/posthog/posthog-go"
+	"sync"
 	"time"
 )
 
+const (
+	// queueSize bounds the number of events waiting to be handed to the PostHog client,
+	// events sent while the queue is full are dropped instead of blocking the caller
+	queueSize = 4096
+
+	// batchSize and batchInterval control how many events are sent to PostHog in a
+	// single request, and how long events may wait for a batch to fill up
+	batchSize     = 100
+	batchInterval = time.Second * 5
+)
+
 var (
 	// APIKey is the PostHog API Key
 	APIKey = ""
@@ -31,6 +43,12 @@ var (
 
 type PostHog struct {
 	client posthog.Client
+
+	// mu guards closing the queue against concurrent events
+	mu     sync.RWMutex
+	closed bool
+	queue  chan posthog.Capture
+	wg     sync.WaitGroup
 }
 
 func Init() *PostHog {
@@ -40,13 +58,18 @@ func Init() *PostHog {
 
 	client, _ := posthog.NewWithConfig(APIKey, posthog.Config{
 		Endpoint:  APIHost,
-		BatchSize: 1,
+		BatchSize: batchSize,
+		Interval:  batchInterval,
 		Logger:    new(noopLogger),
 	})
 	if client != nil {
-		return &PostHog{
+		p := &PostHog{
 			client: client,
+			queue:  make(chan posthog.Capture, queueSize),
 		}
+		p.wg.Add(1)
+		go p.deliver()
+		return p
 	}
 
 	return nil
@@ -67,9 +90,37 @@ func (p *PostHog) Event(id string, name string, properties map[string]string) {
 		c.Properties = props
 	}
 
-	_ = p.client.Enqueue(c)
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- c:
+	default:
+	}
 }
 
+// deliver hands the queued events to the PostHog client, which batches them
+func (p *PostHog) deliver() {
+	defer p.wg.Done()
+	for c := range p.queue {
+		_ = p.client.Enqueue(c)
+	}
+}
+
+// Cleanup delivers the queued events and flushes them to PostHog, events
+// sent after Cleanup has been called are dropped
 func (p *PostHog) Cleanup() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	close(p.queue)
+	p.mu.Unlock()
+
+	p.wg.Wait()
 	_ = p.client.Close()
 }
@@ -18,7 +18,11 @@ package main
 
 import (
 	"github.com/loopholelabs/cmdutils/pkg/command"
+	configCmd "github.com/loopholelabs/releaser/cmd/config"
+	"github.com/loopholelabs/releaser/cmd/doctor"
+	"github.com/loopholelabs/releaser/cmd/manifest"
 	"github.com/loopholelabs/releaser/cmd/run"
+	"github.com/loopholelabs/releaser/cmd/stats"
 	"github.com/loopholelabs/releaser/internal/config"
 	"github.com/loopholelabs/releaser/version"
 )
@@ -30,5 +34,5 @@ var Cmd = command.New[*config.Config](
 	true,
 	version.V,
 	config.New,
-	[]command.SetupCommand[*config.Config]{run.Cmd()},
+	[]command.SetupCommand[*config.Config]{run.Cmd(), doctor.Cmd(), stats.Cmd(), manifest.Cmd(), configCmd.Cmd()},
 )
@@ -0,0 +1,150 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/command"
+	"github.com/loopholelabs/cmdutils/pkg/printer"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+	"os"
+)
+
+var (
+	ErrNoConfigFile  = errors.New("no config file given or found")
+	ErrInvalidConfig = errors.New("config file is invalid")
+)
+
+// Cmd encapsulates the commands for working with the configuration of the releaser.
+func Cmd() command.SetupCommand[*config.Config] {
+	return func(cmd *cobra.Command, ch *cmdutils.Helper[*config.Config]) {
+		configCmd := &cobra.Command{
+			Use:  "config",
+			Long: "Work with the configuration of the releaser",
+		}
+
+		validateCmd := &cobra.Command{
+			Use:  "validate [file]",
+			Long: "Validate a config file against the config schema, reporting unknown fields (e.g. typos) and invalid values. If no file is given, the config file in use is validated.",
+			Args: cobra.MaximumNArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				file := ch.Config.GetConfigFile()
+				if len(args) > 0 {
+					file = args[0]
+				}
+				if file == "" {
+					return ErrNoConfigFile
+				}
+
+				data, err := os.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("unable to read config file: %w", err)
+				}
+
+				values := make(map[string]interface{})
+				err = yaml.Unmarshal(data, &values)
+				if err != nil {
+					return fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+				}
+
+				fieldErrs := config.ValidateSchema(values)
+				for _, fieldErr := range fieldErrs {
+					ch.Printer.Printf("%s: %s\n", file, fieldErr)
+				}
+				if len(fieldErrs) > 0 {
+					return ErrInvalidConfig
+				}
+
+				v := viper.New()
+				v.SetConfigFile(file)
+				err = v.ReadInConfig()
+				if err != nil {
+					return fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+				}
+				c := config.New()
+				err = v.Unmarshal(c)
+				if err != nil {
+					return fmt.Errorf("%w: %s", ErrInvalidConfig, err)
+				}
+				err = c.InterpolateSecrets()
+				if err == nil {
+					err = c.Check()
+				}
+				if err != nil {
+					ch.Printer.Printf("%s: %s\n", file, err)
+					return ErrInvalidConfig
+				}
+
+				ch.Printer.Printf("%s is valid\n", file)
+				return nil
+			},
+		}
+
+		schemaCmd := &cobra.Command{
+			Use:  "schema",
+			Long: "Print the JSON schema of the config file, for validation in editors",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				encoder := json.NewEncoder(cmd.OutOrStdout())
+				encoder.SetIndent("", "  ")
+				return encoder.Encode(config.Schema())
+			},
+		}
+
+		showCmd := &cobra.Command{
+			Use:  "show",
+			Long: "Print the configuration the releaser runs with, merged from the flags, the environment, and the config file, with secrets redacted",
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return ch.Config.Load()
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if ch.Printer.Format() == printer.JSON {
+					return ch.Printer.PrintJSON(ch.Config.Effective())
+				}
+
+				out, err := yaml.Marshal(ch.Config.Effective())
+				if err != nil {
+					return err
+				}
+				_, err = cmd.OutOrStdout().Write(out)
+				return err
+			},
+		}
+
+		envCmd := &cobra.Command{
+			Use:  "env",
+			Long: "List the environment variables every config field can be set with",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				for _, key := range config.Keys() {
+					_, err := fmt.Fprintf(cmd.OutOrStdout(), "%s\t%s\n", config.EnvVar(key), key)
+					if err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+
+		configCmd.AddCommand(validateCmd, schemaCmd, showCmd, envCmd)
+		cmd.AddCommand(configCmd)
+	}
+}
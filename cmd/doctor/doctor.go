@@ -0,0 +1,79 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package doctor
+
+import (
+	"context"
+	"errors"
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/command"
+	"github.com/loopholelabs/releaser/cmd/run"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/internal/log"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/cache"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ErrChecksFailed = errors.New("one or more checks failed")
+)
+
+// Cmd encapsulates the commands for checking the configuration of the releaser.
+func Cmd() command.SetupCommand[*config.Config] {
+	return func(cmd *cobra.Command, ch *cmdutils.Helper[*config.Config]) {
+		doctorCmd := &cobra.Command{
+			Use:  "doctor",
+			Long: "Check that the configured providers are accessible and have releases the releaser can serve",
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				log.Init(ch.Config.GetLogFile(), ch.Debug())
+				err := ch.Config.GlobalRequiredFlags(cmd)
+				if err != nil {
+					return err
+				}
+
+				return ch.Config.Validate()
+			},
+			PostRunE: utils.PostRunAnalytics(ch),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				p, err := run.NewProvider(context.Background(), ch.Config)
+				if err != nil {
+					return err
+				}
+
+				results := cache.Check(context.Background(), p, ch.Config)
+				for _, result := range results {
+					switch {
+					case result.Err == nil:
+						ch.Printer.Printf("[ok]   %s\n", result.Name)
+					case errors.Is(result.Err, cache.ErrSkipped):
+						ch.Printer.Printf("[skip] %s\n", result.Name)
+					default:
+						ch.Printer.Printf("[fail] %s: %s\n", result.Name, result.Err)
+					}
+				}
+
+				if cache.CheckFailed(results) {
+					return ErrChecksFailed
+				}
+				return nil
+			},
+		}
+
+		cmd.AddCommand(doctorCmd)
+	}
+}
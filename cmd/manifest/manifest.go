@@ -0,0 +1,117 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package manifest
+
+import (
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/command"
+	"github.com/loopholelabs/releaser/embed"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/valyala/fasttemplate"
+	"net"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// excludedFlags are not passed on to the deployment, the github token is read from a
+// secret instead and the listen address and kubernetes mode are set by the manifest
+var excludedFlags = map[string]struct{}{
+	"github-token":      {},
+	"github-token-file": {},
+	"listen-address":    {},
+	"kubernetes":        {},
+	"config":            {},
+	"log":               {},
+}
+
+// Cmd encapsulates the commands for generating Kubernetes manifests.
+func Cmd() command.SetupCommand[*config.Config] {
+	return func(cmd *cobra.Command, ch *cmdutils.Helper[*config.Config]) {
+		var name, namespace, image string
+		var replicas int
+
+		manifestCmd := &cobra.Command{
+			Use: "manifest",
+			Long: "Print a Kubernetes Deployment and Service running the releaser in Kubernetes mode. " +
+				"The flags given to this command are passed on to the deployment, settings from a config file are not. " +
+				"The GitHub token is read from the github-token key of the <name>-github-token secret.",
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				return ch.Config.Load()
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				port := "8080"
+				if _, listenPort, err := net.SplitHostPort(ch.Config.ListenAddress); err == nil && listenPort != "" {
+					port = listenPort
+				}
+
+				containerArgs := []string{"run", "--kubernetes", "--listen-address=0.0.0.0:" + port}
+				cmd.Flags().Visit(func(f *pflag.Flag) {
+					if _, ok := excludedFlags[f.Name]; ok || cmd.LocalNonPersistentFlags().Lookup(f.Name) != nil {
+						return
+					}
+					containerArgs = append(containerArgs, "--"+f.Name+"="+flagValue(f))
+				})
+
+				var b strings.Builder
+				for i, arg := range containerArgs {
+					if i > 0 {
+						b.WriteString("\n")
+					}
+					b.WriteString("            - ")
+					b.WriteString(strconv.Quote(arg))
+				}
+
+				gracePeriod := ch.Config.GetShutdownDelay() + time.Second*30
+				_, err := fasttemplate.New(embed.Kubernetes, embed.StartTag, embed.EndTag).Execute(cmd.OutOrStdout(), map[string]interface{}{
+					"name":                     name,
+					"namespace":                namespace,
+					"image":                    image,
+					"replicas":                 strconv.Itoa(replicas),
+					"args":                     b.String(),
+					"port":                     port,
+					"termination_grace_period": strconv.Itoa(int(gracePeriod.Seconds())),
+					"github_token_directory":   path.Dir(config.DefaultKubernetesGithubTokenFile),
+					"github_token_file":        path.Base(config.DefaultKubernetesGithubTokenFile),
+				})
+				return err
+			},
+		}
+
+		manifestCmd.Flags().StringVar(&name, "name", "releaser", "Name of the Kubernetes Resources")
+		manifestCmd.Flags().StringVar(&namespace, "namespace", "default", "Namespace of the Kubernetes Resources")
+		manifestCmd.Flags().StringVar(&image, "image", "", "Container Image of the Releaser")
+		manifestCmd.Flags().IntVar(&replicas, "replicas", 2, "Number of Replicas")
+		_ = manifestCmd.MarkFlagRequired("image")
+
+		cmd.AddCommand(manifestCmd)
+	}
+}
+
+// flagValue returns the value of the given flag in the form it is parsed from
+func flagValue(f *pflag.Flag) string {
+	if slice, ok := f.Value.(pflag.SliceValue); ok {
+		return strings.Join(slice.GetSlice(), ",")
+	}
+	if strings.HasPrefix(f.Value.Type(), "stringTo") {
+		return strings.TrimSuffix(strings.TrimPrefix(f.Value.String(), "["), "]")
+	}
+	return f.Value.String()
+}
@@ -18,17 +18,30 @@ package run
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/google/go-github/v55/github"
 	"github.com/loopholelabs/cmdutils"
 	"github.com/loopholelabs/cmdutils/pkg/command"
+	"github.com/loopholelabs/releaser/analytics"
+	"github.com/loopholelabs/releaser/analytics/bolt"
+	"github.com/loopholelabs/releaser/internal/audit"
 	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/internal/httpclient"
 	"github.com/loopholelabs/releaser/internal/log"
+	"github.com/loopholelabs/releaser/internal/secrets"
 	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/cache"
+	"github.com/loopholelabs/releaser/pkg/provider"
+	githubProvider "github.com/loopholelabs/releaser/pkg/provider/github"
+	"github.com/loopholelabs/releaser/pkg/provider/oci"
+	"github.com/loopholelabs/releaser/pkg/reference"
+	"github.com/loopholelabs/releaser/pkg/registry"
 	"github.com/loopholelabs/releaser/pkg/server"
 	"github.com/spf13/cobra"
 	"golang.org/x/oauth2"
 	"net/http"
+	"time"
 )
 
 // Cmd encapsulates the commands for running the CLI.
@@ -53,31 +66,70 @@ func Cmd() command.SetupCommand[*config.Config] {
 			},
 			PostRunE: utils.PostRunAnalytics(ch),
 			RunE: func(cmd *cobra.Command, args []string) error {
-				ctx := context.Background()
-				httpClient := http.DefaultClient
-				if ch.Config.GithubToken != "" {
-					tokenSource := oauth2.StaticTokenSource(
-						&oauth2.Token{AccessToken: ch.Config.GithubToken},
-					)
-					httpClient = oauth2.NewClient(ctx, tokenSource)
+				p, err := NewProvider(context.Background(), ch.Config)
+				if err != nil {
+					return err
+				}
+
+				var stats server.StatsStore
+				if ch.Config.AnalyticsDatabase != "" {
+					db, err := bolt.Open(ch.Config.AnalyticsDatabase, ch.Config.AnalyticsRetention)
+					if err != nil {
+						return fmt.Errorf("failed to open analytics database: %w", err)
+					}
+					analytics.SetHandler(db)
+					stats = db
+					ch.Printer.Printf("Analytics are stored in %s\n", ch.Config.AnalyticsDatabase)
 				}
 
-				githubClient := github.NewClient(httpClient)
+				var auditLog *audit.Log
+				if ch.Config.AuditLog != "" {
+					auditLog, err = audit.Open(ch.Config.AuditLog)
+					if err != nil {
+						return fmt.Errorf("failed to open audit log: %w", err)
+					}
+					defer auditLog.Close()
+					err = auditLog.Record(&audit.Entry{
+						Action: "config.load",
+						Actor:  audit.ActorSystem,
+						After:  ch.Config.Effective(),
+					})
+					if err != nil {
+						return fmt.Errorf("failed to write to audit log: %w", err)
+					}
+					ch.Printer.Printf("Admin actions are recorded in %s\n", ch.Config.AuditLog)
+				}
+
+				ch.Printer.Printf("Releaser starting for %s, binaries will be created as %s\n", p.Name(), ch.Config.GetInstallName(""))
 
-				ch.Printer.Printf("Releaser starting for Github Repository %s/%s, binaries will be created as %s\n", ch.Config.RepositoryOwner, ch.Config.Repository, ch.Config.Binary)
+				for _, result := range cache.Check(context.Background(), p, ch.Config) {
+					if result.Err != nil && !errors.Is(result.Err, cache.ErrSkipped) {
+						ch.Printer.Printf("warning: startup check '%s' failed: %s\n", result.Name, result.Err)
+					}
+				}
 
 				errCh := make(chan error, 1)
-				s := server.New(githubClient, ch)
+				s := server.New(p, ch)
+				if stats != nil {
+					s.SetStatsStore(stats)
+				}
+				s.SetAuditLog(auditLog)
 				go func() {
 					errCh <- s.Start(ch.Config.ListenAddress, nil, ch.Config.TLS)
 				}()
 
-				err := utils.WaitForSignal(errCh)
+				err = utils.WaitForSignal(errCh)
 				if err != nil {
 					_ = s.Stop()
 					return fmt.Errorf("error while starting Releaser API: %w", err)
 				}
 
+				if delay := ch.Config.GetShutdownDelay(); delay > 0 {
+					ch.Printer.Printf("Draining for %s before shutting down\n", delay)
+					s.Drain()
+					time.Sleep(delay)
+				}
+
 				err = s.Stop()
 				if err != nil {
 					return fmt.Errorf("failed to stop Releaser API: %w", err)
@@ -89,3 +141,78 @@ func Cmd() command.SetupCommand[*config.Config] {
 		cmd.AddCommand(runCmd)
 	}
 }
+
+// NewProvider creates the release provider selected by the given config, chaining
+// the providers together if more than one is configured
+func NewProvider(ctx context.Context, c *config.Config) (provider.Provider, error) {
+	names := c.GetProviders()
+	providers := make([]provider.Provider, 0, len(names))
+	for _, name := range names {
+		p, err := newNamedProvider(ctx, c, name)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	if len(providers) == 1 {
+		return providers[0], nil
+	}
+	return provider.NewChain(providers...), nil
+}
+
+func newNamedProvider(ctx context.Context, c *config.Config, name string) (provider.Provider, error) {
+	downloadClient, err := httpclient.New(c.GetDownloadOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case config.ProviderOCI:
+		repository, err := reference.ParseRepository(c.OCIRepository)
+		if err != nil {
+			return nil, err
+		}
+		registryClient := registry.New(downloadClient)
+		if c.OCIUsername != "" {
+			registryClient.SetCredentials(c.OCIUsername, c.OCIPassword)
+		}
+		return oci.New(registryClient, repository), nil
+	case config.ProviderGitHub:
+		httpClient, err := httpclient.New(c.GetAPIOptions())
+		if err != nil {
+			return nil, err
+		}
+		tokenSource, err := githubTokenSource(c, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		if tokenSource != nil {
+			httpClient = oauth2.NewClient(context.WithValue(ctx, oauth2.HTTPClient, httpClient), tokenSource)
+		}
+		return githubProvider.New(github.NewClient(httpClient), c.RepositoryOwner, c.Repository).
+			SetKeyOnTitle(c.ReleaseKey == config.ReleaseKeyTitle).
+			SetMaxConcurrentDownloads(c.MaxConcurrentDownloads).
+			SetDownloadClient(downloadClient), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", config.ErrInvalidProvider, name)
+	}
+}
+
+// githubTokenSource returns the source of the GitHub token, which is read from the configured secret
+// manager if there is one, or nil if no token is configured
+func githubTokenSource(c *config.Config, httpClient *http.Client) (oauth2.TokenSource, error) {
+	if c.SecretSource.Backend != "" {
+		backend, err := secrets.New(&c.SecretSource, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		return secrets.TokenSource(backend, c.SecretSource.GithubToken, c.SecretSource.GetRefreshInterval()), nil
+	}
+
+	token, err := c.GetGithubToken()
+	if err != nil || token == "" {
+		return nil, err
+	}
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token}), nil
+}
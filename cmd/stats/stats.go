@@ -0,0 +1,142 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package stats
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/command"
+	"github.com/loopholelabs/releaser/analytics/bolt"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/internal/log"
+	"github.com/spf13/cobra"
+	"go.etcd.io/bbolt"
+	"io"
+	"time"
+)
+
+const (
+	// FormatCSV exports one row per event, with the properties of the event as a JSON object
+	FormatCSV = "csv"
+
+	// FormatJSON exports a JSON array of events
+	FormatJSON = "json"
+)
+
+var (
+	ErrInvalidFormat  = errors.New("invalid format")
+	ErrDatabaseLocked = errors.New("analytics database is in use by another process")
+)
+
+// Cmd encapsulates the commands for working with the analytics stored in the local database.
+func Cmd() command.SetupCommand[*config.Config] {
+	return func(cmd *cobra.Command, ch *cmdutils.Helper[*config.Config]) {
+		statsCmd := &cobra.Command{
+			Use:  "stats",
+			Long: "Work with the analytics events stored in the local analytics database",
+		}
+
+		var format string
+		exportCmd := &cobra.Command{
+			Use:  "export",
+			Long: "Export the analytics events stored in the local analytics database",
+			PreRunE: func(cmd *cobra.Command, args []string) error {
+				log.Init(ch.Config.GetLogFile(), ch.Debug())
+				err := ch.Config.Load()
+				if err != nil {
+					return err
+				}
+				if ch.Config.AnalyticsDatabase == "" {
+					return config.ErrAnalyticsDatabaseRequired
+				}
+				if format != FormatCSV && format != FormatJSON {
+					return fmt.Errorf("%w: %s", ErrInvalidFormat, format)
+				}
+				return nil
+			},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				// exporting never deletes events, they are only pruned by the server
+				db, err := bolt.Open(ch.Config.AnalyticsDatabase, 0)
+				if err != nil {
+					if errors.Is(err, bbolt.ErrTimeout) {
+						return ErrDatabaseLocked
+					}
+					return fmt.Errorf("failed to open analytics database: %w", err)
+				}
+				defer db.Cleanup()
+
+				if format == FormatCSV {
+					return exportCSV(db, cmd.OutOrStdout())
+				}
+				return exportJSON(db, cmd.OutOrStdout())
+			},
+		}
+		exportCmd.Flags().StringVar(&format, "format", FormatCSV, "Export Format (csv or json)")
+
+		statsCmd.AddCommand(exportCmd)
+		cmd.AddCommand(statsCmd)
+	}
+}
+
+func exportCSV(db *bolt.Bolt, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	err := writer.Write([]string{"time", "id", "name", "properties"})
+	if err != nil {
+		return err
+	}
+
+	err = db.Records(func(r *bolt.Record) error {
+		properties, err := json.Marshal(r.Properties)
+		if err != nil {
+			return err
+		}
+		return writer.Write([]string{r.Time.Format(time.RFC3339), r.ID, r.Name, string(properties)})
+	})
+	if err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func exportJSON(db *bolt.Bolt, w io.Writer) error {
+	_, err := io.WriteString(w, "[")
+	if err != nil {
+		return err
+	}
+
+	separator := "\n  "
+	err = db.Records(func(r *bolt.Record) error {
+		value, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, separator+string(value))
+		separator = ",\n  "
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n]\n")
+	return err
+}
@@ -25,3 +25,18 @@ const (
 
 //go:embed templates/shell.tpl
 var Shell string
+
+//go:embed templates/goimport.tpl
+var GoImport string
+
+//go:embed templates/systemd.tpl
+var Systemd string
+
+//go:embed templates/kubernetes.tpl
+var Kubernetes string
+
+//go:embed templates/dashboard.tpl
+var Dashboard string
+
+//go:embed templates/dashboard.js
+var DashboardScript string
@@ -0,0 +1,240 @@
+"use strict";
+
+(function () {
+  const tokenKey = "releaser_admin_token";
+  const colors = ["#0969da", "#1a7f37", "#9a6700", "#8250df", "#cf222e", "#bf3989", "#1b7c83", "#57606a"];
+  let overview = null;
+
+  const $ = (id) => document.getElementById(id);
+
+  function show(id, visible) {
+    $(id).classList.toggle("hidden", !visible);
+  }
+
+  function message(text, error) {
+    const el = $("message");
+    el.textContent = text;
+    el.className = error ? "bad" : "";
+    show("message", text !== "");
+  }
+
+  function element(tag, text, className) {
+    const el = document.createElement(tag);
+    if (text !== undefined) {
+      el.textContent = text;
+    }
+    if (className) {
+      el.className = className;
+    }
+    return el;
+  }
+
+  async function request(method, path) {
+    const headers = {};
+    const token = sessionStorage.getItem(tokenKey);
+    if (token) {
+      headers["Authorization"] = "Bearer " + token;
+    }
+    const res = await fetch(path, { method: method, headers: headers, credentials: "same-origin" });
+    if (res.status === 401) {
+      sessionStorage.removeItem(tokenKey);
+      show("overview", false);
+      show("login", true);
+      throw new Error("Sign in to continue");
+    }
+    if (!res.ok) {
+      throw new Error((await res.text()) || res.statusText);
+    }
+    return res.json();
+  }
+
+  function age(seconds) {
+    if (!seconds) {
+      return "never";
+    }
+    const elapsed = Math.max(0, Math.round(Date.now() / 1000 - seconds));
+    if (elapsed < 120) {
+      return elapsed + "s ago";
+    }
+    if (elapsed < 7200) {
+      return Math.round(elapsed / 60) + "m ago";
+    }
+    return Math.round(elapsed / 3600) + "h ago";
+  }
+
+  function renderStatus() {
+    const status = overview.status;
+    const cache = overview.cache;
+    const cards = [
+      ["Latest release", status.latest_release_name || "none"],
+      ["Upstream latest release", status.upstream_latest_release_name || "none"],
+      ["Pinned release", status.pinned_release_name || "none", status.pinned_release_name ? "warn" : ""],
+      ["Rollout", status.rollout ? status.rollout.percentage + "% (from " + status.rollout.previous_release_name + ")" : "complete"],
+      ["Provider", status.provider + (status.provider_reachable ? "" : " (unreachable)"), status.provider_reachable ? "" : "bad"],
+      ["Last update", age(cache.last_update), cache.stale ? "bad" : ""],
+      ["Failed updates", String(cache.failures), cache.failures > 0 ? "warn" : ""],
+      ["Integrity issues", String(cache.integrity_issues), cache.integrity_issues > 0 ? "bad" : ""],
+      ["Maintenance", status.maintenance ? "enabled" : "disabled", status.maintenance ? "bad" : ""],
+    ];
+    const container = $("status");
+    container.replaceChildren();
+    for (const [label, value, className] of cards) {
+      const card = element("div", undefined, "card");
+      card.append(element("div", label, "label"), element("div", value, "value " + (className || "")));
+      container.append(card);
+    }
+
+    show("complete-rollout", !!status.rollout);
+    show("unpin", !!status.pinned_release_name);
+    $("maintenance").textContent = status.maintenance ? "Disable maintenance mode" : "Enable maintenance mode";
+  }
+
+  function renderReleases() {
+    const body = $("releases");
+    body.replaceChildren();
+    for (const release of overview.releases) {
+      const row = element("tr");
+      const name = element("td", release.name);
+      if (release.latest) {
+        name.append(element("span", "latest", "badge"));
+      }
+      if (release.pinned) {
+        name.append(element("span", "pinned", "badge"));
+      }
+      const actions = element("td");
+      if (!release.pinned) {
+        const pin = element("button", "Pin");
+        pin.addEventListener("click", () => act("POST", "/admin/pin?release_name=" + encodeURIComponent(release.name), "Serve " + release.name + " as the latest release?"));
+        actions.append(pin);
+      }
+      row.append(name, element("td", release.title || ""), element("td", String(release.artifacts)), actions);
+      body.append(row);
+    }
+  }
+
+  function renderInstalls() {
+    const container = $("installs");
+    container.replaceChildren();
+    if (!overview.stats_enabled) {
+      container.append(element("p", "Stats are only available if analytics are stored locally (--analytics-database)."));
+      return;
+    }
+
+    const days = [];
+    for (let i = 29; i >= 0; i--) {
+      days.push(new Date(Date.now() - i * 86400000).toISOString().slice(0, 10));
+    }
+    const versions = [];
+    const counts = {};
+    for (const install of overview.installs || []) {
+      if (!versions.includes(install.version)) {
+        versions.push(install.version);
+      }
+      counts[install.start] = counts[install.start] || {};
+      counts[install.start][install.version] = (counts[install.start][install.version] || 0) + install.installs;
+    }
+    versions.sort();
+
+    let max = 1;
+    for (const day of days) {
+      const total = Object.values(counts[day] || {}).reduce((sum, count) => sum + count, 0);
+      max = Math.max(max, total);
+    }
+
+    const ns = "http://www.w3.org/2000/svg";
+    const width = 900;
+    const height = 200;
+    const barWidth = width / days.length;
+    const svg = document.createElementNS(ns, "svg");
+    svg.setAttribute("viewBox", "0 0 " + width + " " + (height + 20));
+    svg.setAttribute("width", "100%");
+    days.forEach((day, i) => {
+      let y = height;
+      versions.forEach((version, j) => {
+        const count = (counts[day] || {})[version] || 0;
+        if (count === 0) {
+          return;
+        }
+        const barHeight = (count / max) * height;
+        y -= barHeight;
+        const rect = document.createElementNS(ns, "rect");
+        rect.setAttribute("x", String(i * barWidth + 2));
+        rect.setAttribute("y", String(y));
+        rect.setAttribute("width", String(barWidth - 4));
+        rect.setAttribute("height", String(barHeight));
+        rect.setAttribute("fill", colors[j % colors.length]);
+        const title = document.createElementNS(ns, "title");
+        title.textContent = day + " " + version + ": " + count;
+        rect.append(title);
+        svg.append(rect);
+      });
+      if (i % 5 === 0) {
+        const label = document.createElementNS(ns, "text");
+        label.setAttribute("x", String(i * barWidth + 2));
+        label.setAttribute("y", String(height + 15));
+        label.setAttribute("font-size", "11");
+        label.textContent = day.slice(5);
+        svg.append(label);
+      }
+    });
+    container.append(svg);
+
+    const legend = element("div", undefined, "legend");
+    versions.forEach((version, j) => {
+      const item = element("span", version);
+      item.style.setProperty("--color", colors[j % colors.length]);
+      legend.append(item);
+    });
+    container.append(legend);
+  }
+
+  async function load() {
+    try {
+      overview = await request("GET", "/admin/overview");
+    } catch (err) {
+      message(err.message, true);
+      return;
+    }
+    show("login", false);
+    show("overview", true);
+    show("sign-out", !!sessionStorage.getItem(tokenKey));
+    renderStatus();
+    renderReleases();
+    renderInstalls();
+  }
+
+  async function act(method, path, confirmation) {
+    if (confirmation && !window.confirm(confirmation)) {
+      return;
+    }
+    try {
+      await request(method, path);
+      message("");
+    } catch (err) {
+      message(err.message, true);
+    }
+    await load();
+  }
+
+  $("token-form").addEventListener("submit", (event) => {
+    event.preventDefault();
+    sessionStorage.setItem(tokenKey, $("token").value);
+    $("token").value = "";
+    message("");
+    load();
+  });
+  $("sign-out").addEventListener("click", () => {
+    sessionStorage.removeItem(tokenKey);
+    location.reload();
+  });
+  $("refresh").addEventListener("click", () => act("POST", "/admin/refresh"));
+  $("evict").addEventListener("click", () => act("POST", "/admin/evict", "Download the artifacts of the latest release again?"));
+  $("complete-rollout").addEventListener("click", () => act("POST", "/admin/rollout", "Serve the latest release to all clients now?"));
+  $("unpin").addEventListener("click", () => act("POST", "/admin/pin?release_name=", "Serve the latest upstream release again?"));
+  $("maintenance").addEventListener("click", () => {
+    const enabled = !overview.status.maintenance;
+    act("POST", "/admin/maintenance?enabled=" + enabled, enabled ? "Answer every request with a 503?" : "");
+  });
+
+  load();
+})();
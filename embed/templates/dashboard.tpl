@@ -0,0 +1,81 @@
+<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <meta name="robots" content="noindex">
+  <title>Releaser Admin</title>
+  <style>
+    body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 0; color: #1f2328; background: #f6f8fa; }
+    header { background: #24292f; color: #fff; padding: 12px 24px; display: flex; align-items: center; justify-content: space-between; }
+    header h1 { font-size: 18px; margin: 0; }
+    main { max-width: 1100px; margin: 0 auto; padding: 24px; }
+    section { background: #fff; border: 1px solid #d0d7de; border-radius: 6px; padding: 16px; margin-bottom: 16px; }
+    h2 { font-size: 16px; margin: 0 0 12px; }
+    .cards { display: grid; grid-template-columns: repeat(auto-fill, minmax(180px, 1fr)); gap: 12px; }
+    .card { border: 1px solid #d0d7de; border-radius: 6px; padding: 8px 12px; }
+    .card .label { font-size: 12px; color: #57606a; }
+    .card .value { font-size: 15px; font-weight: 600; word-break: break-all; }
+    .warn { color: #9a6700; }
+    .bad { color: #cf222e; }
+    table { border-collapse: collapse; width: 100%; }
+    th, td { text-align: left; padding: 6px 8px; border-bottom: 1px solid #d0d7de; font-size: 14px; }
+    button { font: inherit; font-size: 13px; padding: 4px 10px; border: 1px solid #d0d7de; border-radius: 6px; background: #f6f8fa; cursor: pointer; }
+    button:hover { background: #eaeef2; }
+    button.danger { color: #cf222e; }
+    .actions { display: flex; flex-wrap: wrap; gap: 8px; }
+    .badge { font-size: 12px; padding: 1px 6px; border-radius: 10px; background: #ddf4ff; color: #0969da; margin-left: 4px; }
+    .hidden { display: none; }
+    #message { margin-bottom: 16px; }
+    #login input { font: inherit; padding: 4px 8px; width: 320px; max-width: 100%; }
+    .legend { display: flex; flex-wrap: wrap; gap: 12px; font-size: 12px; margin-top: 8px; }
+    .legend span::before { content: ""; display: inline-block; width: 10px; height: 10px; margin-right: 4px; background: var(--color); }
+  </style>
+</head>
+<body>
+  <header>
+    <h1>Releaser Admin</h1>
+    <button id="sign-out" class="hidden">Sign out</button>
+  </header>
+  <main>
+    <div id="message" class="hidden"></div>
+    <section id="login" class="hidden">
+      <h2>Sign in</h2>
+      <p>Enter an admin token, it is kept in this browser tab until it is closed.</p>
+      <form id="token-form">
+        <input id="token" type="password" autocomplete="off" placeholder="Admin token">
+        <button type="submit">Sign in</button>
+      </form>
+      <p class="{{oidc_class}}"><a href="{{oidc_login}}">Sign in with SSO</a></p>
+    </section>
+    <div id="overview" class="hidden">
+      <section>
+        <h2>Status</h2>
+        <div id="status" class="cards"></div>
+      </section>
+      <section>
+        <h2>Actions</h2>
+        <div class="actions">
+          <button id="refresh">Refresh cache</button>
+          <button id="evict">Evict latest release</button>
+          <button id="complete-rollout" class="hidden">Complete rollout</button>
+          <button id="unpin" class="hidden">Unpin release</button>
+          <button id="maintenance" class="danger"></button>
+        </div>
+      </section>
+      <section>
+        <h2>Installs over the last 30 days</h2>
+        <div id="installs"></div>
+      </section>
+      <section>
+        <h2>Releases</h2>
+        <table>
+          <thead><tr><th>Release</th><th>Title</th><th>Artifacts</th><th></th></tr></thead>
+          <tbody id="releases"></tbody>
+        </table>
+      </section>
+    </div>
+  </main>
+  <script src="{{script}}"></script>
+</body>
+</html>
@@ -0,0 +1,10 @@
+<!DOCTYPE html>
+<html>
+<head>
+<meta name="go-import" content="{{import_path}} git {{repository_url}}">
+<meta name="go-source" content="{{import_path}} {{repository_url}} {{repository_url}}/tree/HEAD{/dir} {{repository_url}}/blob/HEAD{/dir}/{file}#L{line}">
+</head>
+<body>
+go get {{import_path}}
+</body>
+</html>
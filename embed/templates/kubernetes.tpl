@@ -0,0 +1,97 @@
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: {{name}}
+  namespace: {{namespace}}
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: Role
+metadata:
+  name: {{name}}-events
+  namespace: {{namespace}}
+rules:
+  - apiGroups: [""]
+    resources: ["events"]
+    verbs: ["create"]
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: RoleBinding
+metadata:
+  name: {{name}}-events
+  namespace: {{namespace}}
+roleRef:
+  apiGroup: rbac.authorization.k8s.io
+  kind: Role
+  name: {{name}}-events
+subjects:
+  - kind: ServiceAccount
+    name: {{name}}
+    namespace: {{namespace}}
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: {{name}}
+  namespace: {{namespace}}
+  labels:
+    app.kubernetes.io/name: {{name}}
+spec:
+  replicas: {{replicas}}
+  selector:
+    matchLabels:
+      app.kubernetes.io/name: {{name}}
+  template:
+    metadata:
+      labels:
+        app.kubernetes.io/name: {{name}}
+    spec:
+      serviceAccountName: {{name}}
+      terminationGracePeriodSeconds: {{termination_grace_period}}
+      containers:
+        - name: releaser
+          image: {{image}}
+          args:
+{{args}}
+          env:
+            - name: POD_NAME
+              valueFrom:
+                fieldRef:
+                  fieldPath: metadata.name
+          ports:
+            - name: http
+              containerPort: {{port}}
+          readinessProbe:
+            httpGet:
+              path: /readyz
+              port: http
+            periodSeconds: 5
+          livenessProbe:
+            httpGet:
+              path: /ping
+              port: http
+            periodSeconds: 10
+          volumeMounts:
+            - name: github-token
+              mountPath: {{github_token_directory}}
+              readOnly: true
+      volumes:
+        - name: github-token
+          secret:
+            secretName: {{name}}-github-token
+            optional: true
+            items:
+              - key: github-token
+                path: {{github_token_file}}
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: {{name}}
+  namespace: {{namespace}}
+spec:
+  selector:
+    app.kubernetes.io/name: {{name}}
+  ports:
+    - name: http
+      port: 80
+      targetPort: http
@@ -21,9 +21,27 @@ echoerr() {
 }
 
 log_info() {
+  if [ "$quiet" = "true" ]; then
+    return 0
+  fi
+  if [ "$printPath" = "true" ]; then
+    printf "\033[38;5;61m  ==>\033[0;00m $@\n" 1>&2
+    return 0
+  fi
   printf "\033[38;5;61m  ==>\033[0;00m $@\n"
 }
 
+log_newline() {
+  if [ "$quiet" = "true" ]; then
+    return 0
+  fi
+  if [ "$printPath" = "true" ]; then
+    echo 1>&2
+    return 0
+  fi
+  echo
+}
+
 log_crit() {
   echoerr
   echoerr "  \033[38;5;125m$@\033[0;00m"
@@ -34,15 +52,26 @@ is_command() {
   command -v "$1" >/dev/null
 }
 
+# url_encode percent-encodes every byte of the given value so that it can be used in a query string
+url_encode() {
+  printf '%s' "$1" | od -An -v -tx1 | tr -d ' \n' | sed 's/\(..\)/%\1/g'
+}
+
+http_retries=3
+http_timeout=30
+
 http_download_curl() {
   local_file=$1
   source_url=$2
   header=$3
-  if [ -z "$header" ]; then
-    code=$(curl -w '%{http_code}' -fsSL -o "$local_file" "$source_url")
-  else
-    code=$(curl -w '%{http_code}' -fsSL -H "$header" -o "$local_file" "$source_url")
+  set -- -w '%{http_code}' -fsSL --retry "$http_retries" --connect-timeout "$http_timeout" -o "$local_file"
+  if [ -n "$header" ]; then
+    set -- "$@" -H "$header"
   fi
+  if [ -n "$cacert" ]; then
+    set -- "$@" --cacert "$cacert"
+  fi
+  code=$(curl "$@" "$source_url")
   if [ "$code" != "200" ]; then
     log_crit "Error downloading, got $code response from server"
     return 1
@@ -54,11 +83,36 @@ http_download_wget() {
   local_file=$1
   source_url=$2
   header=$3
-  if [ -z "$header" ]; then
-    wget -q -O "$local_file" "$source_url"
-  else
-    wget -q --header "$header" -O "$local_file" "$source_url"
+  set -- -q --tries="$http_retries" --timeout="$http_timeout" -O "$local_file"
+  if [ -n "$header" ]; then
+    set -- "$@" --header "$header"
+  fi
+  if [ -n "$cacert" ]; then
+    set -- "$@" --ca-certificate="$cacert"
   fi
+  wget "$@" "$source_url"
+}
+
+http_download_fetch() {
+  local_file=$1
+  source_url=$2
+  header=$3
+  if [ -n "$header" ]; then
+    log_crit "fetch does not support custom headers, please install curl or wget"
+    return 1
+  fi
+  set -- -q -T "$http_timeout" -o "$local_file"
+  if [ -n "$cacert" ]; then
+    set -- "$@" --ca-cert="$cacert"
+  fi
+  attempt=1
+  until fetch "$@" "$source_url"; do
+    if [ "$attempt" -ge "$http_retries" ]; then
+      return 1
+    fi
+    attempt=$((attempt + 1))
+    sleep 1
+  done
 }
 
 http_download() {
@@ -68,11 +122,35 @@ http_download() {
   elif is_command wget; then
     http_download_wget "$@"
     return
+  elif is_command fetch; then
+    http_download_fetch "$@"
+    return
   fi
-  log_crit "http_download unable to find wget or curl"
+  log_crit "http_download unable to find curl, wget or fetch"
   return 1
 }
 
+# setup_proxy exports both spellings of the proxy variables, since curl, wget and fetch
+# disagree on whether they honor the upper or the lower case variants
+setup_proxy() {
+  http_proxy=${http_proxy:-$HTTP_PROXY}
+  HTTP_PROXY=${HTTP_PROXY:-$http_proxy}
+  https_proxy=${https_proxy:-$HTTPS_PROXY}
+  HTTPS_PROXY=${HTTPS_PROXY:-$https_proxy}
+  no_proxy=${no_proxy:-$NO_PROXY}
+  NO_PROXY=${NO_PROXY:-$no_proxy}
+  for name in http_proxy HTTP_PROXY https_proxy HTTPS_PROXY no_proxy NO_PROXY; do
+    eval "value=\$$name"
+    if [ -n "$value" ]; then
+      export "$name"
+    fi
+  done
+  if [ -n "$cacert" ] && [ ! -r "$cacert" ]; then
+    log_crit "CA certificate $cacert does not exist or is not readable"
+    return 1
+  fi
+}
+
 http_copy() {
   tmp=$(mktemp)
   http_download "${tmp}" "$1" "$2" || return 1
@@ -81,12 +159,86 @@ http_copy() {
   echo "$body"
 }
 
+# hash_sha256 prints the SHA-256 checksum of the given file, it fails if no tool to compute it is available
+hash_sha256() {
+  if is_command sha256sum; then
+    sha256sum "$1" | cut -d ' ' -f 1
+  elif is_command shasum; then
+    shasum -a 256 "$1" | cut -d ' ' -f 1
+  elif is_command sha256; then
+    sha256 -q "$1"
+  elif is_command openssl; then
+    openssl dgst -sha256 "$1" | awk '{print $NF}'
+  else
+    return 1
+  fi
+}
+
+# verify_checksum verifies the downloaded artifact against its checksum, which is fetched again for
+# every attempt so that a corrupted checksum is not trusted twice
+verify_checksum() {
+  if [ "$checksums" != "true" ]; then
+    return 0
+  fi
+  expected=$(http_copy "$prefix://$domain/checksum$pathPrefix/$releaseName/$os/$arch?analytics=false") || {
+    log_info "Unable to download the checksum for release $releaseName"
+    return 1
+  }
+  actual=$(hash_sha256 "$tmp") || {
+    if [ "$skipChecksum" = "true" ]; then
+      log_info "WARNING: Unable to verify the checksum of release $releaseName, installing it anyway since SKIP_CHECKSUM is set"
+      return 0
+    fi
+    log_crit "Unable to verify the checksum of release $releaseName, please install sha256sum, shasum or openssl, or set SKIP_CHECKSUM=true to install it without verifying it"
+    exit 1
+  }
+  if [ "$expected" != "$actual" ]; then
+    log_info "Checksum mismatch, expected $expected but got $actual"
+    return 1
+  fi
+}
+
+# check_platform fails with the platforms the release is available for if it was not built for this
+# machine, it succeeds if they can not be determined so that the download is tried again
+check_platform() {
+  available=$(http_copy "$prefix://$domain/platforms$pathPrefix/$releaseName?analytics=false") || return 0
+  available=$(echo $available)
+  case " $available " in
+    *" $os/$arch "*) return 0 ;;
+  esac
+  message="Release $releaseName is not available for $os $arch, it is available for: $(echo "$available" | sed 's/ /, /g')"
+  if [ -n "$buildFromSource" ]; then
+    message="$message\n  To build it from source, see $buildFromSource"
+  fi
+  log_crit "$message"
+  return 1
+}
+
+# download_artifact downloads the artifact and verifies it, trying again with an exponential
+# backoff if the download fails or the artifact does not match its checksum
+download_artifact() {
+  attempt=1
+  delay=1
+  until http_download "$tmp" "$artifactURL?$query" && verify_checksum; do
+    check_platform || return 1
+    if [ "$attempt" -ge "$downloadAttempts" ]; then
+      log_crit "Unable to download release $releaseName for $os $arch after $attempt attempts"
+      return 1
+    fi
+    log_info "Retrying in ${delay}s (attempt $((attempt + 1)) of $downloadAttempts)"
+    sleep "$delay"
+    attempt=$((attempt + 1))
+    delay=$((delay * 2))
+  done
+}
+
 uname_os() {
   os=$(uname -s | tr '[:upper:]' '[:lower:]')
 
   case "$os" in
     msys_nt*) os="windows" ;;
     mingw*) os="windows" ;;
+    cygwin_nt*) os="windows" ;;
   esac
 
   # other fixups here
@@ -120,10 +272,24 @@ uname_arch() {
     i686) arch="386" ;;
     i386) arch="386" ;;
     aarch64) arch="arm64" ;;
+    arm64) arch="arm64" ;;
+    riscv64) arch="riscv64" ;;
     armv5*) arch="armv5" ;;
     armv6*) arch="armv6" ;;
     armv7*) arch="armv7" ;;
   esac
+
+  # msys and cygwin report the architecture they were built for, which is x86_64 when
+  # they run emulated on Windows on ARM, so the native architecture is checked instead
+  case "$(uname_os)" in
+    windows)
+      case "${PROCESSOR_ARCHITEW6432:-$PROCESSOR_ARCHITECTURE}" in
+        ARM64) arch="arm64" ;;
+        AMD64) arch="amd64" ;;
+        x86) arch="386" ;;
+      esac
+      ;;
+  esac
   echo ${arch}
 }
 
@@ -143,12 +309,344 @@ uname_arch_check() {
     mips64) return 0 ;;
     mips64le) return 0 ;;
     s390x) return 0 ;;
+    riscv64) return 0 ;;
+    loong64) return 0 ;;
     amd64p32) return 0 ;;
   esac
   log_crit "uname_arch_check '$(uname -m)' got converted to '$arch' which is not a GOARCH value."
   return 1
 }
 
+binary_member() {
+  case "$1" in
+{{binary_members}}
+  esac
+}
+
+binary_install_name() {
+  case "$1-$os" in
+{{binary_install_names}}
+    *) echo "$1" ;;
+  esac
+}
+
+parse_args() {
+  only=""
+  acceptLicense=""
+  assumeYes=""
+  dryRun=""
+  telemetry=""
+  hooks=""
+  systemd=""
+  quiet=""
+  printPath=""
+  cacert=""
+  while [ $# -gt 0 ]; do
+    case "$1" in
+      --cacert)
+        cacert="$2"
+        shift
+        ;;
+      --cacert=*) cacert="${1#--cacert=}" ;;
+      -y|--yes)
+        acceptLicense="true"
+        assumeYes="true"
+        ;;
+      -q|--quiet) quiet="true" ;;
+      --print-path) printPath="true" ;;
+      --dry-run) dryRun="true" ;;
+      --no-telemetry) telemetry="false" ;;
+      --no-hooks) hooks="false" ;;
+      --systemd) systemd="true" ;;
+      --accept-license) acceptLicense="true" ;;
+      --only)
+        only="$only $(echo "$2" | tr ',' ' ')"
+        shift
+        ;;
+      --only=*) only="$only $(echo "${1#--only=}" | tr ',' ' ')" ;;
+      *)
+        log_crit "Unknown argument $1"
+        return 1
+        ;;
+    esac
+    shift
+  done
+}
+
+select_binaries() {
+  selected="$binaries"
+  if [ -z "$only" ]; then
+    return 0
+  fi
+  if [ -z "$binaries" ]; then
+    log_crit "--only is not supported, this release only contains $binary"
+    return 1
+  fi
+  for name in $only; do
+    case " $binaries " in
+      *" $name "*) ;;
+      *)
+        log_crit "Unknown binary $name, available binaries are: $binaries"
+        return 1
+        ;;
+    esac
+  done
+  selected="$only"
+}
+
+# extract writes the given members of the downloaded artifact, or all of its files, to stdout. Artifacts
+# are tar.gz archives, except on windows where they may be zip archives.
+extract() {
+  if [ "$(dd if="$tmp" bs=2 count=1 2>/dev/null)" = "PK" ]; then
+    if ! is_command unzip; then
+      log_crit "Unable to extract the zip archive of release $releaseName, please install unzip"
+      return 1
+    fi
+    unzip -p "$tmp" "$@"
+    return
+  fi
+  tar -xf "$tmp" -O "$@"
+}
+
+install_binaries() {
+  dest=$1
+  if [ -z "$selected" ]; then
+    extract > "$dest/$binary"
+    chmod +x "$dest/$binary"
+    print_path "$dest/$binary"
+    return 0
+  fi
+  for name in $selected; do
+    target="$dest/$(binary_install_name "$name")"
+    extract "$(binary_member "$name")" > "$target"
+    chmod +x "$target"
+    print_path "$target"
+  done
+}
+
+print_path() {
+  if [ "$printPath" = "true" ]; then
+    echo "$1"
+  fi
+}
+
+accept_license() {
+  if [ "$licenseAcceptance" != "true" ]; then
+    return 0
+  fi
+  license=$(http_copy "$prefix://$domain/license/$releaseName?$query") || {
+    log_crit "Unable to download the license for release $releaseName"
+    return 1
+  }
+  if [ "$printPath" = "true" ]; then
+    printf "\n%s\n\n" "$license" 1>&2
+  else
+    printf "\n%s\n\n" "$license"
+  fi
+  if [ "$acceptLicense" = "true" ]; then
+    log_info "License accepted non-interactively"
+    return 0
+  fi
+  if [ ! -r /dev/tty ]; then
+    log_crit "Unable to prompt for license acceptance, rerun with --accept-license or --yes to accept it"
+    return 1
+  fi
+  printf "Do you accept the license above? [y/N] " 1>&2
+  read -r answer < /dev/tty
+  case "$answer" in
+    y|Y|yes|YES) return 0 ;;
+  esac
+  log_crit "License not accepted, aborting installation"
+  return 1
+}
+
+dry_run() {
+  checksum=$(http_copy "$prefix://$domain/checksum$pathPrefix/$releaseName/$os/$arch?analytics=false") || checksum="unavailable"
+  destination=$(install_destination)
+  log_info "Dry run, nothing will be downloaded or installed"
+  log_info "  Release:     $releaseName"
+  log_info "  Platform:    $os $arch"
+  log_info "  Artifact:    $artifactURL"
+  log_info "  Checksum:    $checksum"
+  log_info "  Binaries:    $installing"
+  log_info "  Destination: $destination"
+}
+
+# install_destination prints the directory binaries will be installed to, which is $install if
+# it is writable (or the user is root) and the user install directory otherwise
+install_destination() {
+  if [ -w "$install" ] || [ "$(id -u)" = "0" ]; then
+    echo "$install"
+    return 0
+  fi
+  echo "$userInstall"
+}
+
+on_path() {
+  case ":$PATH:" in
+    *":$1:"*) return 0 ;;
+  esac
+  return 1
+}
+
+shell_rc() {
+  case "$(basename "${SHELL:-sh}")" in
+    zsh) echo "${ZDOTDIR:-$HOME}/.zshrc" ;;
+    bash)
+      if [ "$os" = "darwin" ]; then
+        echo "$HOME/.bash_profile"
+      else
+        echo "$HOME/.bashrc"
+      fi
+      ;;
+    fish) echo "$HOME/.config/fish/config.fish" ;;
+    *) echo "$HOME/.profile" ;;
+  esac
+}
+
+# ensure_path offers to append the given directory to the PATH in the user's shell rc file
+# if it is not on the PATH already, it never modifies the rc file without consent
+ensure_path() {
+  dir=$1
+  if on_path "$dir"; then
+    return 0
+  fi
+  rc=$(shell_rc)
+  case "$rc" in
+    *.fish) exportPath="fish_add_path \"$dir\"" ;;
+    *) exportPath="export PATH=\"\$PATH:$dir\"" ;;
+  esac
+  if [ -f "$rc" ] && grep -qF "$exportPath" "$rc"; then
+    log_info "Please run 'source $rc' to update your current shell or open a new one."
+    return 0
+  fi
+  consent=""
+  if [ "$assumeYes" = "true" ]; then
+    consent="true"
+  elif [ -r /dev/tty ] && [ "$quiet" != "true" ]; then
+    printf "%s is not on your PATH, append it to %s? [y/N] " "$dir" "$rc" 1>&2
+    read -r answer < /dev/tty
+    case "$answer" in
+      y|Y|yes|YES) consent="true" ;;
+    esac
+  fi
+  if [ "$consent" != "true" ]; then
+    log_info "$dir is not on your PATH, please add the following to your shell profile:"
+    log_info "  $ $exportPath"
+    return 0
+  fi
+  mkdir -p "$(dirname "$rc")"
+  echo "$exportPath" >> "$rc"
+  log_info "Appended $dir to the PATH in $rc"
+  log_info "Please run 'source $rc' to update your current shell or open a new one."
+}
+
+on_exit() {
+  status=$?
+  if [ "$status" != "0" ] && [ -n "$os" ] && [ -n "$arch" ]; then
+    log_crit "Installation failed, for an explanation see $prefix://$domain/why$pathPrefix/$releaseName/$os/$arch"
+  fi
+  report_result "$status"
+}
+
+# report_result tells the server whether the installation succeeded, it only runs
+# when analytics are enabled and never fails the installation itself
+report_result() {
+  status=$1
+  if [ "$analytics" != "true" ] || [ "$telemetry" = "false" ] || [ "$dryRun" = "true" ]; then
+    return 0
+  fi
+  success="true"
+  if [ "$status" != "0" ]; then
+    success="false"
+  fi
+  data="release_name=$releaseName&binary=$binary&os=$os&arch=$arch&success=$success&stage=$stage"
+  url="$prefix://$domain/telemetry/install-result?$query"
+  if is_command curl; then
+    curl -fsS -m 5 -o /dev/null -d "$data" "$url" >/dev/null 2>&1 || true
+  elif is_command wget; then
+    wget -q -T 5 -O /dev/null --post-data="$data" "$url" >/dev/null 2>&1 || true
+  fi
+}
+
+pre_install_hook() {
+  :
+{{pre_install_hook}}
+}
+
+post_install_hook() {
+  :
+{{post_install_hook}}
+}
+
+run_hook() {
+  if [ "$hooks" = "false" ]; then
+    return 0
+  fi
+  case " $hookPhases " in
+    *" $1 "*) ;;
+    *) return 0 ;;
+  esac
+  log_info "Running $1 hook"
+  case "$1" in
+    pre-install) pre_install_hook ;;
+    post-install) post_install_hook ;;
+  esac
+}
+
+confirm() {
+  if [ "$assumeYes" = "true" ]; then
+    return 0
+  fi
+  if [ ! -r /dev/tty ]; then
+    return 1
+  fi
+  printf "%s [y/N] " "$1" 1>&2
+  read -r answer < /dev/tty
+  case "$answer" in
+    y|Y|yes|YES) return 0 ;;
+  esac
+  return 1
+}
+
+# install_systemd installs a systemd unit for the installed binary and, with consent,
+# enables it and restarts it so that the new release is running
+install_systemd() {
+  if [ "$systemd" != "true" ]; then
+    return 0
+  fi
+  if [ "$os" != "linux" ] || ! is_command systemctl; then
+    log_crit "--systemd requires linux with systemd"
+    return 1
+  fi
+  if [ "$(id -u)" != "0" ]; then
+    log_crit "--systemd requires root to install /etc/systemd/system/$binary.service"
+    return 1
+  fi
+  unit="/etc/systemd/system/$binary.service"
+  log_info "Installing systemd unit $unit"
+  http_download "$unit" "$prefix://$domain/systemd/$releaseName?path=$(url_encode "$destination")&$query"
+  systemctl daemon-reload
+  if confirm "Enable $binary.service and (re)start it now?"; then
+    systemctl enable "$binary.service"
+    systemctl restart "$binary.service"
+    log_info "Started $binary.service"
+  else
+    log_info "Enable and start the service with:"
+    log_info "  $ systemctl enable --now $binary.service"
+  fi
+}
+
+# install_id prints a random ID for this installation, which the server attributes analytics
+# events to instead of the (anonymized) IP address, it prints nothing if no source is available
+install_id() {
+  if [ -r /proc/sys/kernel/random/uuid ]; then
+    cat /proc/sys/kernel/random/uuid
+  elif is_command uuidgen; then
+    uuidgen | tr '[:upper:]' '[:lower:]'
+  fi
+}
+
 mktmpdir() {
   test -z "$TMPDIR" && TMPDIR="$(mktemp -d)"
   mkdir -p "${TMPDIR}"
@@ -156,67 +654,94 @@ mktmpdir() {
 }
 
 start() {
+  parse_args "$@"
   uname_os_check
   uname_arch_check
 
-  domain="{{domain}}"
-  releaseName="{{release_name}}"
-  prefix="{{prefix}}"
-  binary="{{binary}}"
+  domain={{domain}}
+  releaseName={{release_name}}
+  prefix={{prefix}}
+  pathPrefix={{path_prefix}}
+  binary={{binary}}
+  binaries={{binaries}}
   analytics="{{analytics}}"
+  source="{{source}}"
+  licenseAcceptance="{{license_acceptance}}"
+  defaultCACert={{cacert}}
+  cacert=${cacert:-$defaultCACert}
+  defaultUserInstall={{user_install}}
+  userInstall=${USER_INSTALL:-$defaultUserInstall}
+  hookPhases="{{hook_phases}}"
+  checksums="{{checksums}}"
+  buildFromSource={{build_from_source}}
+  downloadAttempts=${DOWNLOAD_ATTEMPTS:-"{{download_attempts}}"}
+  skipChecksum=${SKIP_CHECKSUM:-"false"}
+
+  query="analytics=$analytics"
+  if [ -n "$source" ]; then
+    query="$query&source=$source"
+  fi
+  if [ "$analytics" = "true" ] && [ "$telemetry" != "false" ]; then
+    installID=$(install_id)
+    if [ -n "$installID" ]; then
+      query="$query&install_id=$installID"
+    fi
+  fi
+
+  stage="setup"
+  trap on_exit EXIT
+
+  setup_proxy
+
+  case "$os" in
+{{install_names}}
+  esac
+
+  select_binaries
+  installing=${selected:-$binary}
 
   install=${INSTALL:-"/usr/local/bin"}
+  artifactURL="$prefix://$domain$pathPrefix/$releaseName/$os/$arch"
+
+  if [ "$dryRun" = "true" ]; then
+    log_newline
+    dry_run
+    log_newline
+    return 0
+  fi
+
+  stage="license"
+  accept_license
+
   tmpDir="$(mktmpdir)"
   tmp="$tmpDir/$binary"
 
-  echo
+  log_newline
+  stage="download"
   log_info "Downloading Release $releaseName for $os $arch"
-  http_download $tmp "$prefix://$domain/$releaseName/$os/$arch?analytics=$analytics"
+  download_artifact
 
-  if [ -w "$install" ]; then
-    log_info "Installing $binary to $install"
-    tar -xf "$tmp" -O > "$install/$binary"
-    chmod +x "$install/$binary"
-  else
-    otherInstall="$HOME/.config/$binary/bin"
-    mkdir -p "$otherInstall"
-    log_info "Permissions required for installation to $install, using $otherInstall instead — alternatively specify a new directory with:"
-    log_info "  $ curl -fsSL $prefix://$domain/$releaseName | INSTALL=. sh"
-    tar -xf "$tmp" -O | tee "$otherInstall/$binary" > /dev/null
-    chmod +x "$otherInstall/$binary"
-    EXPORT_PATH="export PATH=\"\$PATH:$otherInstall\""
-    if [ -w "$HOME/.zshrc" ]; then
-        if ! grep -q "$EXPORT_PATH" "$HOME/.zshrc" ; then
-            log_info "Appending scale source string to ~/.zshrc"
-            echo "$EXPORT_PATH" >> "$HOME/.zshrc"
-            log_info "Please run 'source ~/.zshrc' to update your current shell or open a new one."
-        fi
-    elif [ -w "$HOME/.zprofile" ]; then
-        if ! grep -q "$EXPORT_PATH" "$HOME/.zprofile" ; then
-            log_info "Appending scale source string to ~/.zprofile"
-            echo "$EXPORT_PATH" >> "$HOME/.zprofile"
-            log_info "Please run 'source ~/.zprofile' to update your current shell or open a new one."
-        fi
-    elif [ -w "$HOME/.bashrc" ]; then
-        if ! grep -q "$EXPORT_PATH" "$HOME/.bashrc" ; then
-            log_info "Appending scale source string to ~/.bashrc"
-            echo "$EXPORT_PATH" >> "$HOME/.bashrc"
-            log_info "Please run 'source ~/.bashrc' to update your current shell or open a new one."
-        fi
-    elif [ -w "$HOME/.bash_profile" ]; then
-        if ! grep -q "$EXPORT_PATH" "$HOME/.bash_profile" ; then
-            log_info "Appending scale source string to ~/.bash_profile"
-            echo "$EXPORT_PATH" >> "$HOME/.bash_profile"
-            log_info "Please run 'source ~/.bash_profile' to update your current shell or open a new one."
-        fi
-    else
-        log_info "Please add the following to your shell profile:"
-        log_info "  $ $EXPORT_PATH"
-    fi
+  stage="install"
+  destination=$(install_destination)
+  if [ "$destination" != "$install" ]; then
+    log_info "Permissions required for installation to $install, using $destination instead — alternatively specify a new directory with:"
+    log_info "  $ curl -fsSL $prefix://$domain$pathPrefix/$releaseName | INSTALL=. sh"
   fi
+  mkdir -p "$destination"
+  stage="pre-install"
+  run_hook pre-install
+  stage="install"
+  log_info "Installing $installing to $destination"
+  install_binaries "$destination"
+  stage="post-install"
+  run_hook post-install
+  stage="systemd"
+  install_systemd
+  ensure_path "$destination"
+  stage="complete"
 
   log_info "Installation complete"
-  echo
+  log_newline
 }
 
-start
\ No newline at end of file
+start "$@"
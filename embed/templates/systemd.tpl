@@ -0,0 +1,14 @@
+[Unit]
+Description={{description}}
+Documentation={{documentation}}
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+Type=simple
+ExecStart={{exec_start}}
+Restart=on-failure
+RestartSec=5
+{{user}}
+[Install]
+WantedBy=multi-user.target
@@ -0,0 +1,105 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+// Package audit records the actions that change the state of the releaser (admin requests, rollouts,
+// evictions, and configuration changes) to an append-only log, for operators that need to account for them.
+package audit
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// ActorSystem is the actor of the actions the releaser takes on its own (e.g. while updating the cache)
+	ActorSystem = "system"
+)
+
+// Entry is a single action recorded in the audit log, it is written as one line of JSON
+type Entry struct {
+	// Time is when the action was taken
+	Time time.Time `json:"time"`
+
+	// Action is the kind of action that was taken (e.g. admin.maintenance or cache.evict)
+	Action string `json:"action"`
+
+	// Actor is who took the action, the address of the client for admin requests or ActorSystem
+	Actor string `json:"actor"`
+
+	// Target is what the action was taken on (e.g. a release name or an admin route), it may be empty
+	Target string `json:"target,omitempty"`
+
+	// Before is the value that was replaced by the action, it is omitted if there was none
+	Before interface{} `json:"before,omitempty"`
+
+	// After is the value that was set by the action, it is omitted if there is none
+	After interface{} `json:"after,omitempty"`
+}
+
+// Log appends entries to an audit log file. A nil *Log discards every entry, so that
+// callers do not have to check whether an audit log is configured.
+type Log struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Open opens the audit log at the given path, creating it if it does not exist.
+// Entries are only ever appended to it.
+func Open(path string) (*Log, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &Log{
+		file: file,
+	}, nil
+}
+
+// Record appends an entry for the given action to the audit log, setting its time if it is not set
+func (l *Log) Record(entry *Entry) error {
+	if l == nil {
+		return nil
+	}
+	if entry.Time.IsZero() {
+		entry.Time = time.Now().UTC()
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.file.Write(b)
+	if err != nil {
+		return err
+	}
+	return l.file.Sync()
+}
+
+// Close closes the audit log, no entries can be recorded afterwards
+func (l *Log) Close() error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
@@ -20,25 +20,73 @@ import (
 	"errors"
 	"fmt"
 	"github.com/loopholelabs/cmdutils/pkg/config"
+	"github.com/loopholelabs/releaser/internal/httpclient"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/reference"
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
+	"net"
+	"net/url"
 	"os"
 	"path"
+	"regexp"
+	"strings"
+	"time"
 )
 
 var _ config.Config = (*Config)(nil)
 
 var (
-	ErrRepositoryRequired      = errors.New("repository is required")
-	ErrRepositoryOwnerRequired = errors.New("repository owner is required")
-	ErrHostnameRequired        = errors.New("hostname is required")
-	ErrListenAddressRequired   = errors.New("listen address is required")
-	ErrDomainRequired          = errors.New("domain is required")
-	ErrBinaryRequired          = errors.New("binary is required")
+	ErrRepositoryRequired        = errors.New("repository is required")
+	ErrRepositoryOwnerRequired   = errors.New("repository owner is required")
+	ErrHostnameRequired          = errors.New("hostname is required")
+	ErrListenAddressRequired     = errors.New("listen address is required")
+	ErrDomainRequired            = errors.New("domain is required")
+	ErrBinaryRequired            = errors.New("binary is required")
+	ErrBinaryNameRequired        = errors.New("binary name is required")
+	ErrDuplicateBinaryName       = errors.New("duplicate binary name")
+	ErrInvalidBinaryName         = errors.New("invalid binary name")
+	ErrReservedBinaryName        = errors.New("reserved binary name")
+	ErrWingetLicenseRequired     = errors.New("winget license is required")
+	ErrWingetDescriptionRequired = errors.New("winget short description is required")
+	ErrInvalidImageRepository    = errors.New("invalid image repository")
+	ErrInvalidProvider           = errors.New("invalid provider")
+	ErrOCIRepositoryRequired     = errors.New("oci repository is required")
+	ErrInvalidOCIRepository      = errors.New("invalid oci repository")
+	ErrInvalidEmbargo            = errors.New("invalid embargo")
+	ErrInvalidRolloutWindow      = errors.New("invalid rollout window")
+	ErrInvalidCohort             = errors.New("invalid cohort")
+	ErrDuplicateCohort           = errors.New("duplicate cohort")
+	ErrInvalidReleaseKey         = errors.New("invalid release key")
+	ErrInvalidHTTPSProxy         = errors.New("invalid https proxy")
+	ErrInvalidTrustedProxy       = errors.New("invalid trusted proxy")
+	ErrInvalidBuildFromSourceURL = errors.New("invalid build from source url")
+	ErrInvalidSampleRate         = errors.New("invalid sample rate")
+	ErrInvalidEnricher           = errors.New("invalid analytics enricher")
+	ErrAnalyticsDatabaseRequired = errors.New("analytics database is required")
+	ErrInvalidSecret             = errors.New("invalid secret")
+	ErrInvalidSecretBackend      = errors.New("invalid secret backend")
+	ErrInvalidLimit              = errors.New("invalid limit")
+	ErrSecretReferenceRequired   = errors.New("secret reference is required")
+	ErrInvalidSecurityProfile    = errors.New("invalid security profile")
+	ErrAdminTokenNameRequired    = errors.New("admin token name is required")
+	ErrAdminTokenRequired        = errors.New("admin token is required")
+	ErrDuplicateAdminToken       = errors.New("duplicate admin token name")
+	ErrInvalidAdminScope         = errors.New("invalid admin scope")
+	ErrInvalidAdminToken         = errors.New("invalid admin token")
+	ErrOIDCClientIDRequired      = errors.New("oidc client id is required")
+	ErrInvalidOIDCIssuer         = errors.New("invalid oidc issuer")
+	ErrAdminAuthRequired         = errors.New("admin authentication is required")
 )
 
+// validName matches the binary and install names that are safe to use in file names and in the install script
+var validName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// DefaultInstallSources are the installation sources (?source=) recorded in analytics by default
+var DefaultInstallSources = []string{"docs", "readme", "homebrew", "ci"}
+
 var (
 	configFile string
 	logFile    string
@@ -53,61 +101,803 @@ const (
 	DefaultTLS           = false
 	DefaultDomain        = "localhost"
 	DefaultBinary        = "bin"
+	DefaultImageTag      = "{{release_name}}"
+	DefaultProvider      = ProviderGitHub
+	DefaultReleaseKey    = ReleaseKeyTag
+
+	DefaultMaxConcurrentDownloads = 4
+
+	DefaultMaxHeaderSize = 8 * 1024
+	DefaultMaxURILength  = 2048
+	DefaultMaxBodySize   = 64 * 1024
+	DefaultConcurrency   = 256 * 1024
+
+	DefaultRateLimitWindow = time.Minute
+
+	DefaultTransferQueueSize    = 64
+	DefaultTransferQueueTimeout = time.Second * 10
+
+	DefaultMaxEventSubscribers = 1024
+
+	// DefaultAnalyticsRetention keeps the locally stored analytics events a little longer than the longest stats period
+	DefaultAnalyticsRetention = time.Hour * 24 * 400
+
+	// DefaultOIDCGroupsClaim is the ID token claim the groups of an OIDC user are read from
+	DefaultOIDCGroupsClaim = "groups"
+
+	HookPreInstall  = "pre-install"
+	HookPostInstall = "post-install"
+
+	// CohortLatest serves a cohort the latest release, regardless of any rollout in progress
+	CohortLatest = "latest"
+
+	DefaultUserInstallDirectory   = "$HOME/.local/bin"
+	DefaultScriptDownloadAttempts = 3
+	DefaultMaintenanceRetryAfter  = time.Minute * 5
+
+	// DefaultKubernetesGithubTokenFile is where the GitHub token is read from in Kubernetes mode,
+	// if neither a token nor a token file is configured
+	DefaultKubernetesGithubTokenFile = "/var/run/secrets/releaser/github-token"
+
+	// DefaultKubernetesShutdownDelay is how long the server keeps serving after SIGTERM in Kubernetes mode,
+	// if no shutdown delay is configured, so that it is removed from its endpoints before it stops
+	DefaultKubernetesShutdownDelay = time.Second * 5
+)
+
+const (
+	// ProviderGitHub sources releases from GitHub Releases
+	ProviderGitHub = "github"
+
+	// ProviderOCI sources releases from OCI artifacts pushed to a registry with ORAS
+	ProviderOCI = "oci"
+)
+
+const (
+	// SecretBackendVault reads secrets from the KV secrets engine of HashiCorp Vault
+	SecretBackendVault = "vault"
+
+	// SecretBackendAWS reads secrets from AWS Secrets Manager
+	SecretBackendAWS = "aws"
+
+	// SecretBackendGCP reads secrets from GCP Secret Manager
+	SecretBackendGCP = "gcp"
+
+	// DefaultSecretRefreshInterval is how long a secret read from a secret backend is used before it is read again
+	DefaultSecretRefreshInterval = time.Minute * 5
+)
+
+const (
+	// ReleaseKeyTag identifies releases by their tag name (e.g. v1.2.3)
+	ReleaseKeyTag = "tag"
+
+	// ReleaseKeyTitle identifies releases by their title, falling back to the tag name for untitled releases
+	ReleaseKeyTitle = "title"
+)
+
+const (
+	// EnricherGeo adds the country of the client to analytics events, as reported by the CDN in front of the server
+	EnricherGeo = "geo"
+
+	// EnricherRegion adds the configured region of the server to analytics events
+	EnricherRegion = "region"
+
+	// EnricherChannel adds the cohort the client selected to analytics events
+	EnricherChannel = "channel"
+
+	// EnricherReferrer adds the referrer of the request to analytics events
+	EnricherReferrer = "referrer"
+)
+
+const (
+	// AdminScopeRead allows reading the integrity, metrics, maintenance, overview, and profiling admin routes
+	AdminScopeRead = "read"
+
+	// AdminScopeCache allows changing what the cache serves, which is turning maintenance mode on and off,
+	// refreshing the cache, and evicting the artifacts of the latest release
+	AdminScopeCache = "cache"
+
+	// AdminScopeRollout allows changing the release served as the latest release by pinning one
+	// or completing its rollout
+	AdminScopeRollout = "rollout"
+)
+
+// AdminScopes are all the admin scopes, which are granted to the admin token
+var AdminScopes = []string{AdminScopeRead, AdminScopeCache, AdminScopeRollout}
+
+const (
+	// SecurityProfileDefault sends the helmet defaults, without HSTS or a content security policy
+	SecurityProfileDefault = "default"
+
+	// SecurityProfileStrict additionally sends HSTS with preload, a content security policy that
+	// blocks everything, and denies framing, for internet-facing installer domains
+	SecurityProfileStrict = "strict"
 )
 
+// Binary describes a single executable shipped inside a release artifact
+type Binary struct {
+	// Name is used to select the binary (e.g. --only cli) and is the default install name
+	Name string `mapstructure:"name"`
+
+	// Path is the path of the binary inside the release artifact. If it is empty, Name is used.
+	Path string `mapstructure:"path"`
+
+	// InstallNames overrides Name for specific operating systems (e.g. windows=cli.exe)
+	InstallNames map[string]string `mapstructure:"install_names"`
+}
+
+// GetPath returns the path of the binary inside the release artifact
+func (b *Binary) GetPath() string {
+	if b.Path != "" {
+		return b.Path
+	}
+	return b.Name
+}
+
+// GetInstallName returns the name the binary should be installed as on the given operating system
+func (b *Binary) GetInstallName(os string) string {
+	if installName, ok := b.InstallNames[os]; ok && installName != "" {
+		return installName
+	}
+	return b.Name
+}
+
+// Winget configures the generation of winget package manifests
+type Winget struct {
+	// PackageIdentifier is the winget package identifier (e.g. Publisher.Tool). If it is empty,
+	// no winget manifests are served.
+	PackageIdentifier string `mapstructure:"package_identifier"`
+
+	// Publisher is the name of the publisher. If it is empty, the repository owner is used.
+	Publisher string `mapstructure:"publisher"`
+
+	// PackageName is the name of the package. If it is empty, the repository name is used.
+	PackageName string `mapstructure:"package_name"`
+
+	// License is the license of the package (e.g. Apache-2.0)
+	License string `mapstructure:"license"`
+
+	// ShortDescription is a short description of the package
+	ShortDescription string `mapstructure:"short_description"`
+}
+
 // Config is dynamically sourced from various files and environment variables.
 type Config struct {
-	GithubToken     string `mapstructure:"github_token"`
-	Repository      string `mapstructure:"repository"`
-	RepositoryOwner string `mapstructure:"repository_owner"`
-	Hostname        string `mapstructure:"hostname"`
-	ListenAddress   string `mapstructure:"listen_address"`
-	TLS             bool   `mapstructure:"tls"`
-	Domain          string `mapstructure:"domain"`
-	Binary          string `mapstructure:"binary"`
+	Provider        string   `mapstructure:"provider"`
+	Providers       []string `mapstructure:"providers"`
+	GithubToken     string   `mapstructure:"github_token"`
+	GithubTokenFile string   `mapstructure:"github_token_file"`
+	Repository      string   `mapstructure:"repository"`
+	RepositoryOwner string   `mapstructure:"repository_owner"`
+	ReleaseKey      string   `mapstructure:"release_key"`
+	Hostname        string   `mapstructure:"hostname"`
+	ListenAddress   string   `mapstructure:"listen_address"`
+	TLS             bool     `mapstructure:"tls"`
+	Domain          string   `mapstructure:"domain"`
+	Binary          string   `mapstructure:"binary"`
+
+	// MaxConcurrentDownloads limits the number of assets downloaded from GitHub at the same time,
+	// both while updating the cache and while proxying artifacts. It is not limited if it is 0.
+	MaxConcurrentDownloads int `mapstructure:"max_concurrent_downloads"`
+
+	// DownloadConnectTimeout, DownloadReadTimeout, and DownloadRetries configure the HTTP client
+	// used to download assets from the providers, see httpclient.Options
+	DownloadConnectTimeout time.Duration `mapstructure:"download_connect_timeout"`
+	DownloadReadTimeout    time.Duration `mapstructure:"download_read_timeout"`
+	DownloadRetries        int           `mapstructure:"download_retries"`
+
+	// HTTPSProxy, NoProxy, and CABundle configure how the providers are reached, they
+	// override the HTTPS_PROXY and NO_PROXY environment variables if they are set
+	HTTPSProxy string `mapstructure:"https_proxy"`
+	NoProxy    string `mapstructure:"no_proxy"`
+	CABundle   string `mapstructure:"ca_bundle"`
+
+	// MinTLSVersion is the minimum TLS version (e.g. 1.2) accepted from the providers and registries
+	MinTLSVersion string `mapstructure:"min_tls_version"`
+
+	// HTTP2 serves HTTP/2 on TLS listeners, H2C additionally accepts HTTP/2 without TLS
+	// (e.g. behind a load balancer that terminates TLS). Both serve with net/http instead of
+	// fasthttp, which buffers proxied artifacts instead of streaming them.
+	HTTP2 bool `mapstructure:"http2"`
+	H2C   bool `mapstructure:"h2c"`
+
+	// GETOnly rejects all requests but GET and HEAD, which disables the routes that require
+	// POST (e.g. install telemetry and the admin maintenance and pin routes)
+	GETOnly bool `mapstructure:"get_only"`
+
+	// MaxHeaderSize is the maximum size of the request line and headers of a request in bytes,
+	// requests with larger headers are rejected with a 431
+	MaxHeaderSize int `mapstructure:"max_header_size"`
+
+	// MaxURILength is the maximum length of the URI of a request, longer URIs are rejected with a 414
+	MaxURILength int `mapstructure:"max_uri_length"`
+
+	// MaxBodySize is the maximum size of the body of a request in bytes, larger bodies are rejected
+	// with a 413. Routes that accept a body (e.g. install telemetry) use smaller limits of their own.
+	MaxBodySize int `mapstructure:"max_body_size"`
+
+	// Concurrency is the maximum number of connections that are served at the same time
+	Concurrency int `mapstructure:"concurrency"`
+
+	// RateLimit is the maximum number of requests a client may send within the rate limit window,
+	// further requests are rejected with a 429 until the window ends. If it is 0, clients are not rate limited.
+	RateLimit       int           `mapstructure:"rate_limit"`
+	RateLimitWindow time.Duration `mapstructure:"rate_limit_window"`
+
+	// TrustedProxies are the IPs and CIDR ranges (e.g. 10.0.0.0/8) of the reverse proxies in front of the
+	// releaser. The client IP used for rate limiting and analytics is only read from the X-Forwarded-For
+	// header of requests sent by them, otherwise the address of the connection is used.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+
+	// BandwidthLimit is the maximum number of bytes per second sent across all artifact responses,
+	// and ConnectionBandwidthLimit the maximum sent by each of them, so that the releaser cannot
+	// saturate the network of a shared host during release spikes. If they are 0, artifacts are
+	// sent as fast as clients read them.
+	BandwidthLimit           int `mapstructure:"bandwidth_limit"`
+	ConnectionBandwidthLimit int `mapstructure:"connection_bandwidth_limit"`
+
+	// MaxConcurrentTransfers is the maximum number of artifact responses sent (or proxied from the
+	// provider) at the same time. Further requests wait for up to the transfer queue timeout in a queue
+	// of up to the transfer queue size, and are rejected with a 503 once it is full or the wait times
+	// out. If it is 0, transfers are unlimited.
+	MaxConcurrentTransfers int           `mapstructure:"max_concurrent_transfers"`
+	TransferQueueSize      int           `mapstructure:"transfer_queue_size"`
+	TransferQueueTimeout   time.Duration `mapstructure:"transfer_queue_timeout"`
+
+	// MaxEventSubscribers is the maximum number of clients subscribed to the release event stream at
+	// the same time, each of which keeps a connection open. If it is 0, subscribers are unlimited.
+	MaxEventSubscribers int `mapstructure:"max_event_subscribers"`
+
+	// AssetPrefix restricts the release assets that are served to those whose name starts with
+	// the given prefix (followed by an underscore), which may itself contain underscores (e.g. my_tool).
+	// If it is empty, all assets are considered.
+	AssetPrefix string `mapstructure:"asset_prefix"`
+
+	// InstallName is the name the binary will be installed as. If it is empty, Binary is used.
+	InstallName string `mapstructure:"install_name"`
+
+	// InstallNames overrides InstallName for specific operating systems (e.g. windows=tool.exe)
+	InstallNames map[string]string `mapstructure:"install_names"`
+
+	// Binaries describes the executables shipped in each release artifact. If it is empty,
+	// the artifact is expected to contain a single binary which is installed as InstallName.
+	Binaries []Binary `mapstructure:"binaries"`
+
+	// MultiBinary enables serving several binaries that are released as separate artifacts
+	// (e.g. toolctl_v1.0.0_linux_amd64.tar.gz and toold_v1.0.0_linux_amd64.tar.gz) under
+	// their own sub-paths. The unprefixed routes serve the artifacts named after Binary.
+	MultiBinary bool `mapstructure:"multi_binary"`
+
+	// GoImportPath is the vanity import path (e.g. dl.example.com/tool) served to the go tool
+	// for requests with ?go-get=1. If it is empty, no go-import meta tags are served.
+	GoImportPath string `mapstructure:"go_import_path"`
+
+	// Winget configures the generation of winget package manifests
+	Winget Winget `mapstructure:"winget"`
+
+	// ImageRepository is the container image repository (e.g. ghcr.io/owner/tool) the project
+	// publishes images to. If it is empty, no image references are served.
+	ImageRepository string `mapstructure:"image_repository"`
+
+	// ImageTag is the template used to derive an image tag from a release,
+	// {{release_name}} and {{version}} (the release name without the "v" prefix) are replaced
+	ImageTag string `mapstructure:"image_tag"`
+
+	// DockerRunArgs are the options (e.g. "-p 8080:8080") and DockerCommand the command (e.g. "serve")
+	// included in the docker run snippet served at /docker
+	DockerRunArgs string `mapstructure:"docker_run_args"`
+	DockerCommand string `mapstructure:"docker_command"`
+
+	// OCIRepository is the repository (e.g. ghcr.io/owner/tool-releases) releases are pulled from
+	// when using the oci provider, every tag is a release and every titled layer a release asset
+	OCIRepository string `mapstructure:"oci_repository"`
+
+	// OCIUsername and OCIPassword are the optional credentials for the OCIRepository
+	OCIUsername string `mapstructure:"oci_username"`
+	OCIPassword string `mapstructure:"oci_password"`
+
+	// LicenseAcceptance makes the install script display the license of the release
+	// (served from /license) and require the user to accept it before installing. Releases
+	// without license or notice files are installed without a prompt.
+	LicenseAcceptance bool `mapstructure:"license_acceptance"`
+
+	// ScriptCACert is the default path of a CA certificate bundle on the installing machine the
+	// install script trusts (e.g. for TLS-intercepting proxies), it can be overridden with --cacert.
+	// It is used literally, except for a leading $HOME or ~ which expands to the home directory.
+	ScriptCACert string `mapstructure:"script_cacert"`
+
+	// UserInstallDirectory is the directory the install script falls back to when the user
+	// can not write to the default install directory. It is used literally, except for a
+	// leading $HOME or ~ which expands to the home directory of the installing user.
+	UserInstallDirectory string `mapstructure:"user_install_directory"`
+
+	// ScriptDownloadAttempts is how many times the install script tries to download an artifact and
+	// verify it against its checksum before giving up, waiting twice as long after every attempt
+	ScriptDownloadAttempts int `mapstructure:"script_download_attempts"`
+
+	// BuildFromSourceURL links to the instructions for building the binary from source, which are
+	// shown when a release was not built for a platform. If it is empty, the repository is linked.
+	BuildFromSourceURL string `mapstructure:"build_from_source_url"`
+
+	// DirectLatestScript serves the install script of the latest release from / instead of redirecting
+	// to the script of the release, so that `curl | sh` works without -L and saves a round trip
+	DirectLatestScript bool `mapstructure:"direct_latest_script"`
+
+	// ArtifactDirectory is the directory the artifacts of the latest release are stored in and
+	// served from. If it is empty, they are kept in memory.
+	ArtifactDirectory string `mapstructure:"artifact_directory"`
+
+	// AnalyticsSampleRates sets the percentage of analytics events that are sent by event name
+	// (e.g. release_artifact=10), events without a sample rate are always sent
+	AnalyticsSampleRates map[string]int `mapstructure:"analytics_sample_rates"`
+
+	// AnalyticsEvents are the names of the analytics events that are sent (e.g. release_artifact),
+	// the others are dropped. If it is empty, every event is sent. It also applies to the events
+	// stored in the analytics database, the stats routes are built from release_artifact events.
+	AnalyticsEvents []string `mapstructure:"analytics_events"`
+
+	// AnalyticsSalt is the secret client IPs are hashed with before they are used as analytics IDs,
+	// a random one is generated on startup if it is empty
+	AnalyticsSalt string `mapstructure:"analytics_salt"`
+
+	// AnalyticsEnrichers selects the enrichers that add properties to analytics events (geo, region, channel or referrer)
+	AnalyticsEnrichers []string `mapstructure:"analytics_enrichers"`
+
+	// AnalyticsRegion is the region of this deployment, which the region enricher adds to analytics events
+	AnalyticsRegion string `mapstructure:"analytics_region"`
+
+	// AnalyticsDatabase is the path of a local database analytics events are stored in. If it is set,
+	// no events are sent to PostHog.
+	AnalyticsDatabase string `mapstructure:"analytics_database"`
+
+	// AnalyticsRetention is how long the events stored in the AnalyticsDatabase are kept, they are kept forever if it is zero
+	AnalyticsRetention time.Duration `mapstructure:"analytics_retention"`
+
+	// InstallSources are the installation sources (e.g. docs or ci) clients may report with ?source=,
+	// which are recorded in analytics so that the channels driving installs can be compared
+	InstallSources []string `mapstructure:"install_sources"`
+
+	// AdminToken is the bearer token required by the /admin routes, it is granted every admin scope.
+	// If neither it nor any admin tokens are set, no admin routes are served.
+	AdminToken string `mapstructure:"admin_token"`
+
+	// AdminTokens are additional bearer tokens accepted by the /admin routes, each limited to the
+	// given scopes. Tokens are rotated by adding the new token before removing the old one, or by
+	// letting the old one expire.
+	AdminTokens []AdminToken `mapstructure:"admin_tokens"`
+
+	// AdminListenAddress is a separate address the admin, metrics, and profiling routes are served on
+	// (e.g. 127.0.0.1:9090) instead of the listen address. If it is set, the admin routes are served
+	// without authentication unless an admin token is configured, which is only allowed on a loopback
+	// address unless AdminInsecure is set.
+	AdminListenAddress string `mapstructure:"admin_listen_address"`
+
+	// AdminInsecure allows serving the admin routes without authentication on an AdminListenAddress
+	// that is not a loopback address (e.g. when it is only reachable from a private network)
+	AdminInsecure bool `mapstructure:"admin_insecure"`
+
+	// AuditLog is the path of an append-only file admin requests, rollouts, evictions, and configuration
+	// changes are recorded to. If it is empty, no audit log is kept.
+	AuditLog string `mapstructure:"audit_log"`
+
+	// Kubernetes runs the releaser as a Kubernetes workload: the GitHub token is read from a mounted
+	// secret, the server drains before shutting down, and refresh failures are reported as events
+	Kubernetes bool `mapstructure:"kubernetes"`
+
+	// ShutdownDelay is how long the server keeps serving after SIGTERM while reporting that it is
+	// not ready, so that load balancers stop sending it requests before it stops
+	ShutdownDelay time.Duration `mapstructure:"shutdown_delay"`
+
+	// AlertWebhookURL receives a JSON POST request whenever new problems
+	// (e.g. integrity issues) are found while updating the cache
+	AlertWebhookURL string `mapstructure:"alert_webhook_url"`
+
+	// Maintenance starts the server in maintenance mode, in which every route except /ping and
+	// the admin routes responds with a 503. It can be toggled at runtime with /admin/maintenance.
+	Maintenance bool `mapstructure:"maintenance"`
+
+	// MaintenanceRetryAfter is the Retry-After sent with responses in maintenance mode
+	MaintenanceRetryAfter time.Duration `mapstructure:"maintenance_retry_after"`
+
+	// PinnedRelease is served as the latest release instead of the latest release reported by the
+	// provider (e.g. while a bad release is investigated). It can be changed at runtime with /admin/pin.
+	PinnedRelease string `mapstructure:"pinned_release"`
+
+	// Embargoes withholds releases until the given RFC 3339 timestamps
+	// (e.g. v1.2.0=2024-01-01T17:00:00Z) even though they are published
+	Embargoes map[string]string `mapstructure:"embargoes"`
+
+	// RolloutWindow is the time over which a new latest release is progressively rolled out, starting
+	// with no clients and ramping linearly to all of them. The other clients get the previous release, which
+	// stays the release served before a rollout that is superseded by a newer release.
+	// If it is zero, new releases are served to all clients immediately.
+	RolloutWindow time.Duration `mapstructure:"rollout_window"`
+
+	// Cohorts maps cohorts (selected with ?cohort= on the latest routes) to the release they are served,
+	// which is either a release name or "latest" (e.g. canary=latest,general=v1.2.0)
+	Cohorts map[string]string `mapstructure:"cohorts"`
+
+	// Hooks configures shell snippets the install script runs before and after installing,
+	// they are also served at /hooks/:phase
+	Hooks Hooks `mapstructure:"hooks"`
+
+	// SecretSource reads the GitHub token from a secret manager instead of the config
+	SecretSource SecretSource `mapstructure:"secret_source"`
+
+	// Systemd configures the systemd unit served at /systemd/:release_name and
+	// installed by the install script with --systemd
+	Systemd Systemd `mapstructure:"systemd"`
+
+	// SecurityHeaders configures the security headers sent with every response
+	SecurityHeaders SecurityHeaders `mapstructure:"security_headers"`
+
+	// OIDC lets the members of the allowed groups of an OpenID Connect provider sign in to the
+	// admin routes, in addition to the admin tokens
+	OIDC OIDC `mapstructure:"oidc"`
+}
+
+// Hooks are shell snippets run by the install script, with $releaseName, $installing
+// (the installed binaries), and $destination (the install directory) set
+type Hooks struct {
+	// PreInstall runs after the release is downloaded and before the binaries are replaced
+	// (e.g. to stop a systemd service)
+	PreInstall string `mapstructure:"pre_install"`
+
+	// PostInstall runs after the binaries are installed (e.g. to restart a systemd service)
+	PostInstall string `mapstructure:"post_install"`
+}
+
+// GetHook returns the hook for the given phase (pre-install or post-install)
+func (h *Hooks) GetHook(phase string) (string, bool) {
+	switch phase {
+	case HookPreInstall:
+		return h.PreInstall, true
+	case HookPostInstall:
+		return h.PostInstall, true
+	}
+	return "", false
+}
+
+// Systemd configures the systemd unit generated for daemon-style binaries
+type Systemd struct {
+	// Description is the description of the unit, it defaults to the binary name
+	Description string `mapstructure:"description"`
+
+	// Args are the arguments the binary is started with (e.g. "serve --port 8080")
+	Args string `mapstructure:"args"`
+
+	// User is the user the service runs as, it runs as root if it is empty
+	User string `mapstructure:"user"`
+}
+
+// AdminToken is a bearer token accepted by the admin routes, which is limited to the given scopes
+type AdminToken struct {
+	// Name identifies the token in the audit log, it must be unique
+	Name string `mapstructure:"name"`
+
+	// Token is the bearer token, it supports the same file:// and ${ENV_VAR} references as other secrets
+	Token string `mapstructure:"token"`
+
+	// Scopes are the admin scopes granted to the token (read, cache, or rollout)
+	Scopes []string `mapstructure:"scopes"`
+
+	// ExpiresAt is the RFC 3339 timestamp after which the token is rejected (e.g. while it
+	// is being rotated). If it is empty, the token does not expire.
+	ExpiresAt string `mapstructure:"expires_at"`
+}
+
+// HasScope returns true if the token was granted the given scope
+func (t *AdminToken) HasScope(scope string) bool {
+	for _, granted := range t.Scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Expired returns true if the token has expired at the given time
+func (t *AdminToken) Expired(now time.Time) bool {
+	if t.ExpiresAt == "" {
+		return false
+	}
+	expiresAt, err := time.Parse(time.RFC3339, t.ExpiresAt)
+	return err != nil || !now.Before(expiresAt)
+}
+
+// SecurityHeaders configures the security headers sent on top of the helmet defaults, the values
+// that are set override the ones of the profile
+type SecurityHeaders struct {
+	// Profile selects the defaults of the headers (default or strict)
+	Profile string `mapstructure:"profile"`
+
+	// HSTSMaxAge is the max-age of the Strict-Transport-Security header in seconds, which is only
+	// sent over TLS. If it is 0, the header is only sent by the strict profile.
+	HSTSMaxAge int `mapstructure:"hsts_max_age"`
+
+	// HSTSPreload marks the domain for inclusion in the HSTS preload lists, it is implied by the strict profile
+	HSTSPreload bool `mapstructure:"hsts_preload"`
+
+	// ReferrerPolicy is the Referrer-Policy header (e.g. strict-origin-when-cross-origin)
+	ReferrerPolicy string `mapstructure:"referrer_policy"`
+
+	// ContentSecurityPolicy is the Content-Security-Policy header, which only applies to HTML responses
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"`
+}
+
+// OIDC configures the OpenID Connect provider users sign in to the admin routes with, either in the
+// browser at /admin/oidc/login or by sending an ID token issued to the client as the bearer token
+type OIDC struct {
+	// Issuer is the issuer URL of the provider (e.g. https://accounts.google.com). If it is empty,
+	// users cannot sign in with OIDC.
+	Issuer string `mapstructure:"issuer"`
+
+	// ClientID and ClientSecret identify the releaser to the provider, the client secret supports
+	// the same file:// and ${ENV_VAR} references as other secrets
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+
+	// RedirectURL is the URL the provider redirects to after signing in, which must be registered
+	// with the provider. It defaults to /admin/oidc/callback on the domain.
+	RedirectURL string `mapstructure:"redirect_url"`
+
+	// AllowedGroups are the groups whose members may sign in. If it is empty, every user
+	// of the provider that the client is issued ID tokens for may sign in.
+	AllowedGroups []string `mapstructure:"allowed_groups"`
+
+	// GroupsClaim is the ID token claim the groups of a user are read from
+	GroupsClaim string `mapstructure:"groups_claim"`
+
+	// Scopes are the admin scopes granted to the users that sign in (read, cache, or rollout).
+	// If it is empty, they are granted every admin scope.
+	Scopes []string `mapstructure:"scopes"`
+}
+
+// GetScopes returns the admin scopes granted to the users that sign in with OIDC
+func (o *OIDC) GetScopes() []string {
+	if len(o.Scopes) > 0 {
+		return o.Scopes
+	}
+	return AdminScopes
+}
+
+// GetGroupsClaim returns the ID token claim the groups of a user are read from
+func (o *OIDC) GetGroupsClaim() string {
+	if o.GroupsClaim != "" {
+		return o.GroupsClaim
+	}
+	return DefaultOIDCGroupsClaim
+}
+
+// SecretSource configures the secret manager the GitHub token is read from, which is re-read
+// periodically so that rotated tokens are picked up without a restart. It is the only secret read
+// from it, since the releaser does not hold signing keys: signatures are attached to releases
+// when they are built and only served by the releaser. Renewable Vault tokens are renewed
+// when the secret is read, so the refresh interval has to be shorter than half of their TTL.
+type SecretSource struct {
+	// Backend is the secret manager (vault, aws, or gcp). If it is empty, no secret manager is used.
+	Backend string `mapstructure:"backend"`
+
+	// GithubToken references the secret holding the GitHub token: a path and field for vault
+	// (e.g. secret/data/releaser#github_token), a secret ID with an optional JSON key for aws
+	// (e.g. releaser#github_token), and a secret resource name for gcp (e.g. projects/p/secrets/github-token)
+	GithubToken string `mapstructure:"github_token"`
+
+	// RefreshInterval is how long a secret is used before it is read again
+	RefreshInterval time.Duration `mapstructure:"refresh_interval"`
+
+	// VaultAddress and VaultToken are used to access Vault, they default to VAULT_ADDR and VAULT_TOKEN
+	VaultAddress string `mapstructure:"vault_address"`
+	VaultToken   string `mapstructure:"vault_token"`
+
+	// AWSRegion is the region of AWS Secrets Manager, it defaults to AWS_REGION. The credentials
+	// are read from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN.
+	AWSRegion string `mapstructure:"aws_region"`
+}
+
+// GetRefreshInterval returns how long a secret is used before it is read again
+func (s *SecretSource) GetRefreshInterval() time.Duration {
+	if s.RefreshInterval > 0 {
+		return s.RefreshInterval
+	}
+	return DefaultSecretRefreshInterval
 }
 
 func New() *Config {
 	return &Config{
+		Provider:      DefaultProvider,
+		ReleaseKey:    DefaultReleaseKey,
 		ListenAddress: DefaultListenAddress,
 		TLS:           DefaultTLS,
 		Domain:        DefaultDomain,
 		Binary:        DefaultBinary,
+		ImageTag:      DefaultImageTag,
+
+		UserInstallDirectory:   DefaultUserInstallDirectory,
+		ScriptDownloadAttempts: DefaultScriptDownloadAttempts,
+		MaintenanceRetryAfter:  DefaultMaintenanceRetryAfter,
+		InstallSources:         DefaultInstallSources,
+
+		MaxHeaderSize: DefaultMaxHeaderSize,
+		MaxURILength:  DefaultMaxURILength,
+		MaxBodySize:   DefaultMaxBodySize,
+		Concurrency:   DefaultConcurrency,
+
+		RateLimitWindow: DefaultRateLimitWindow,
+
+		TransferQueueSize:    DefaultTransferQueueSize,
+		TransferQueueTimeout: DefaultTransferQueueTimeout,
+
+		MaxEventSubscribers: DefaultMaxEventSubscribers,
+
+		AnalyticsRetention: DefaultAnalyticsRetention,
+
+		SecurityHeaders: SecurityHeaders{
+			Profile: SecurityProfileDefault,
+		},
+
+		MaxConcurrentDownloads: DefaultMaxConcurrentDownloads,
+		DownloadConnectTimeout: httpclient.DefaultConnectTimeout,
+		DownloadReadTimeout:    httpclient.DefaultReadTimeout,
+		DownloadRetries:        httpclient.DefaultRetries,
 	}
 }
 
 func (c *Config) RootPersistentFlags(flags *pflag.FlagSet) {
+	rootFlags = flags
 	defaultHostname, err := os.Hostname()
 	if err != nil {
 		panic(err)
 	}
 
+	flags.StringVar(&c.Provider, "provider", DefaultProvider, "Release Provider (github or oci)")
+	flags.StringSliceVar(&c.Providers, "providers", nil, "Ordered Release Providers to Fall Back Through (overrides provider)")
 	flags.StringVar(&c.GithubToken, "github-token", "", "Github Token")
+	flags.StringVar(&c.GithubTokenFile, "github-token-file", "", "File the Github Token Is Read From (e.g. a mounted secret)")
 	flags.StringVar(&c.Repository, "repository", "", "Github Repository")
 	flags.StringVar(&c.RepositoryOwner, "repository-owner", "", "Github Repository Owner")
+	flags.StringVar(&c.ReleaseKey, "release-key", DefaultReleaseKey, "Github Release Field Used as the Release Name (tag or title)")
+	flags.IntVar(&c.MaxConcurrentDownloads, "max-concurrent-downloads", DefaultMaxConcurrentDownloads, "Maximum Number of Concurrent Github Asset Downloads (0 for unlimited)")
+	flags.DurationVar(&c.DownloadConnectTimeout, "download-connect-timeout", httpclient.DefaultConnectTimeout, "Connect Timeout for Asset Downloads")
+	flags.DurationVar(&c.DownloadReadTimeout, "download-read-timeout", httpclient.DefaultReadTimeout, "Read Timeout for Asset Downloads")
+	flags.IntVar(&c.DownloadRetries, "download-retries", httpclient.DefaultRetries, "Number of Times Failed Asset Downloads Are Retried")
+	flags.StringVar(&c.HTTPSProxy, "https-proxy", "", "Proxy Used to Reach the Providers (overrides HTTPS_PROXY)")
+	flags.StringVar(&c.NoProxy, "no-proxy", "", "Hosts Reached Without the Proxy (overrides NO_PROXY)")
+	flags.StringVar(&c.CABundle, "ca-bundle", "", "PEM File with Additional Certificates Trusted When Reaching the Providers")
+	flags.StringVar(&c.MinTLSVersion, "min-tls-version", "", "Minimum TLS Version Accepted From the Providers (e.g. 1.2)")
 	flags.StringVar(&c.Hostname, "hostname", defaultHostname, "Hostname")
 	flags.StringVar(&c.ListenAddress, "listen-address", DefaultListenAddress, "Listen Address")
 	flags.BoolVar(&c.TLS, "TLS", DefaultTLS, "TLS")
 	flags.StringVar(&c.Domain, "domain", DefaultDomain, "Domain Name")
 	flags.StringVar(&c.Binary, "binary", DefaultBinary, "Binary Name")
+	flags.BoolVar(&c.HTTP2, "http2", false, "Serve HTTP/2 over TLS")
+	flags.BoolVar(&c.H2C, "h2c", false, "Serve HTTP/2 over TLS and Plaintext (h2c)")
+	flags.BoolVar(&c.GETOnly, "get-only", false, "Reject All Requests but GET and HEAD (disables telemetry and admin POST routes)")
+	flags.IntVar(&c.MaxHeaderSize, "max-header-size", DefaultMaxHeaderSize, "Maximum Size of the Request Line and Headers of a Request in Bytes")
+	flags.IntVar(&c.MaxURILength, "max-uri-length", DefaultMaxURILength, "Maximum Length of the URI of a Request")
+	flags.IntVar(&c.MaxBodySize, "max-body-size", DefaultMaxBodySize, "Maximum Size of the Body of a Request in Bytes")
+	flags.IntVar(&c.Concurrency, "concurrency", DefaultConcurrency, "Maximum Number of Connections Served at the Same Time")
+	flags.IntVar(&c.RateLimit, "rate-limit", 0, "Maximum Number of Requests per Client Within the Rate Limit Window (0 for unlimited)")
+	flags.DurationVar(&c.RateLimitWindow, "rate-limit-window", DefaultRateLimitWindow, "Window Client Requests Are Counted In for the Rate Limit")
+	flags.StringSliceVar(&c.TrustedProxies, "trusted-proxies", nil, "IPs and CIDR Ranges of Reverse Proxies Whose X-Forwarded-For Header Is Trusted (e.g. 10.0.0.0/8)")
+	flags.IntVar(&c.BandwidthLimit, "bandwidth-limit", 0, "Maximum Bytes per Second Sent Across All Artifact Responses (0 for unlimited)")
+	flags.IntVar(&c.ConnectionBandwidthLimit, "connection-bandwidth-limit", 0, "Maximum Bytes per Second Sent by Each Artifact Response (0 for unlimited)")
+	flags.IntVar(&c.MaxConcurrentTransfers, "max-concurrent-transfers", 0, "Maximum Number of Artifact Responses Sent at the Same Time (0 for unlimited)")
+	flags.IntVar(&c.TransferQueueSize, "transfer-queue-size", DefaultTransferQueueSize, "Maximum Number of Artifact Requests Waiting for a Transfer Slot")
+	flags.DurationVar(&c.TransferQueueTimeout, "transfer-queue-timeout", DefaultTransferQueueTimeout, "Time Artifact Requests Wait for a Transfer Slot Before Being Rejected")
+	flags.IntVar(&c.MaxEventSubscribers, "max-event-subscribers", DefaultMaxEventSubscribers, "Maximum Number of Clients Subscribed to the Release Event Stream (0 for unlimited)")
+	flags.StringVar(&c.AssetPrefix, "asset-prefix", "", "Asset Name Prefix")
+	flags.StringVar(&c.InstallName, "install-name", "", "Install Name (defaults to the Binary Name)")
+	flags.StringToStringVar(&c.InstallNames, "install-names", nil, "Per-OS Install Names (e.g. windows=bin.exe)")
+	flags.BoolVar(&c.MultiBinary, "multi-binary", false, "Serve Multiple Binaries Released as Separate Artifacts")
+	flags.StringVar(&c.GoImportPath, "go-import-path", "", "Go Vanity Import Path (e.g. dl.example.com/tool)")
+	flags.StringVar(&c.ImageRepository, "image-repository", "", "Container Image Repository (e.g. ghcr.io/owner/tool)")
+	flags.StringVar(&c.ImageTag, "image-tag", DefaultImageTag, "Container Image Tag Template")
+	flags.StringVar(&c.DockerRunArgs, "docker-run-args", "", "Options Included in the Docker Run Snippet (e.g. -p 8080:8080)")
+	flags.StringVar(&c.DockerCommand, "docker-command", "", "Command Included in the Docker Run Snippet")
+	flags.StringVar(&c.OCIRepository, "oci-repository", "", "OCI Repository to Pull Releases From")
+	flags.StringVar(&c.OCIUsername, "oci-username", "", "OCI Registry Username")
+	flags.StringVar(&c.OCIPassword, "oci-password", "", "OCI Registry Password")
+	flags.BoolVar(&c.LicenseAcceptance, "license-acceptance", false, "Require License Acceptance in the Install Script")
+	flags.StringVar(&c.ScriptCACert, "script-cacert", "", "Default CA Certificate Path Trusted by the Install Script")
+	flags.StringVar(&c.UserInstallDirectory, "user-install-directory", DefaultUserInstallDirectory, "Install Directory Used by the Install Script When the Default One Is Not Writable")
+	flags.StringVar(&c.BuildFromSourceURL, "build-from-source-url", "", "Build From Source Instructions URL Shown for Unsupported Platforms (defaults to the Repository)")
+	flags.IntVar(&c.ScriptDownloadAttempts, "script-download-attempts", DefaultScriptDownloadAttempts, "Number of Times the Install Script Tries to Download and Verify an Artifact")
+	flags.BoolVar(&c.DirectLatestScript, "direct-latest-script", false, "Serve the Install Script of the Latest Release Directly Instead of Redirecting to It")
+	flags.StringVar(&c.ArtifactDirectory, "artifact-directory", "", "Directory the Latest Release Artifacts Are Stored In Instead of Memory")
+	flags.StringToIntVar(&c.AnalyticsSampleRates, "analytics-sample-rates", nil, "Percentage of Analytics Events Sent by Event Name (e.g. release_artifact=10)")
+	flags.StringSliceVar(&c.AnalyticsEvents, "analytics-events", nil, "Names of the Analytics Events That Are Sent (e.g. release_artifact,release_shell), Every Event if Empty")
+	flags.StringVar(&c.AnalyticsSalt, "analytics-salt", "", "Secret Used to Anonymize Client IPs in Analytics (shared between replicas)")
+	flags.StringSliceVar(&c.AnalyticsEnrichers, "analytics-enrichers", nil, "Enrichers Adding Properties to Analytics Events (geo, region, channel or referrer)")
+	flags.StringVar(&c.AnalyticsRegion, "analytics-region", "", "Region of this Deployment Added to Analytics Events by the Region Enricher")
+	flags.StringSliceVar(&c.InstallSources, "install-sources", DefaultInstallSources, "Installation Sources Clients May Report With ?source= (e.g. docs,readme,homebrew,ci)")
+	flags.StringVar(&c.AnalyticsDatabase, "analytics-database", "", "Local Database Analytics Events Are Stored In Instead of PostHog")
+	flags.DurationVar(&c.AnalyticsRetention, "analytics-retention", DefaultAnalyticsRetention, "Time Events Are Kept in the Local Analytics Database, Forever if Zero")
+	flags.StringVar(&c.AdminToken, "admin-token", "", "Bearer Token Required by the Admin Routes")
+	flags.BoolVar(&c.Kubernetes, "kubernetes", false, "Run as a Kubernetes Workload")
+	flags.DurationVar(&c.ShutdownDelay, "shutdown-delay", 0, "Time the Server Keeps Serving After SIGTERM While Not Ready")
+	flags.StringVar(&c.AdminListenAddress, "admin-listen-address", "", "Separate Listen Address for the Admin, Metrics, and Profiling Routes (e.g. 127.0.0.1:9090)")
+	flags.BoolVar(&c.AdminInsecure, "admin-insecure", false, "Serve the Admin Routes Without Authentication on a Non-Loopback Admin Listen Address")
+	flags.StringVar(&c.AuditLog, "audit-log", "", "Append-Only File Admin Actions, Rollouts, and Configuration Changes Are Recorded To")
+	flags.StringVar(&c.AlertWebhookURL, "alert-webhook-url", "", "Webhook URL Alerts Are Posted To")
+	flags.BoolVar(&c.Maintenance, "maintenance", false, "Start in Maintenance Mode")
+	flags.DurationVar(&c.MaintenanceRetryAfter, "maintenance-retry-after", DefaultMaintenanceRetryAfter, "Retry-After Sent in Maintenance Mode")
+	flags.StringVar(&c.PinnedRelease, "pinned-release", "", "Release Served as the Latest Release")
+	flags.DurationVar(&c.RolloutWindow, "rollout-window", 0, "Time Over Which New Releases Are Rolled Out to All Clients")
+	flags.StringToStringVar(&c.Cohorts, "cohorts", nil, "Releases Served to Cohorts (e.g. canary=latest,general=v1.2.0)")
+	flags.StringVar(&c.Hooks.PreInstall, "pre-install-hook", "", "Shell Snippet Run by the Install Script Before Installing")
+	flags.StringVar(&c.Hooks.PostInstall, "post-install-hook", "", "Shell Snippet Run by the Install Script After Installing")
+	flags.StringVar(&c.Systemd.Description, "systemd-description", "", "Description of the Systemd Unit")
+	flags.StringVar(&c.Systemd.Args, "systemd-args", "", "Arguments the Binary Is Started With by the Systemd Unit")
+	flags.StringVar(&c.Systemd.User, "systemd-user", "", "User the Systemd Unit Runs As")
+	flags.StringVar(&c.SecurityHeaders.Profile, "security-profile", SecurityProfileDefault, "Security Headers Profile (default or strict)")
+	flags.IntVar(&c.SecurityHeaders.HSTSMaxAge, "hsts-max-age", 0, "Max-Age of the Strict-Transport-Security Header in Seconds (0 for the profile default)")
+	flags.BoolVar(&c.SecurityHeaders.HSTSPreload, "hsts-preload", false, "Mark the Domain for the HSTS Preload Lists")
+	flags.StringVar(&c.SecurityHeaders.ReferrerPolicy, "referrer-policy", "", "Referrer-Policy Header (overrides the profile default)")
+	flags.StringVar(&c.SecurityHeaders.ContentSecurityPolicy, "content-security-policy", "", "Content-Security-Policy Header (overrides the profile default)")
+	flags.StringVar(&c.OIDC.Issuer, "oidc-issuer", "", "Issuer URL of the OIDC Provider Users Sign In to the Admin Routes With")
+	flags.StringVar(&c.OIDC.ClientID, "oidc-client-id", "", "OIDC Client ID")
+	flags.StringVar(&c.OIDC.ClientSecret, "oidc-client-secret", "", "OIDC Client Secret")
+	flags.StringVar(&c.OIDC.RedirectURL, "oidc-redirect-url", "", "URL the OIDC Provider Redirects to After Signing In (defaults to /admin/oidc/callback on the Domain)")
+	flags.StringSliceVar(&c.OIDC.AllowedGroups, "oidc-allowed-groups", nil, "Groups Whose Members May Sign In With OIDC (empty for every user)")
+	flags.StringVar(&c.OIDC.GroupsClaim, "oidc-groups-claim", DefaultOIDCGroupsClaim, "ID Token Claim the Groups of OIDC Users Are Read From")
+	flags.StringToStringVar(&c.Embargoes, "embargoes", nil, "Releases Withheld Until a Timestamp (e.g. v1.2.0=2024-01-01T17:00:00Z)")
 }
 
 func (c *Config) GlobalRequiredFlags(_ *cobra.Command) error {
 	return nil
 }
 
-func (c *Config) Validate() error {
-	err := viper.Unmarshal(c)
+// Load reads the config from the config file and environment into c, without validating it.
+// Every field can be set with an environment variable (see EnvVar).
+func (c *Config) Load() error {
+	err := bindEnv(viper.GetViper())
+	if err != nil {
+		return fmt.Errorf("unable to bind environment variables: %w", err)
+	}
+
+	err = viper.Unmarshal(c, viper.DecodeHook(decodeHook()))
 	if err != nil {
 		return fmt.Errorf("unable to unmarshal config: %w", err)
 	}
+	return c.InterpolateSecrets()
+}
 
-	if c.Repository == "" {
-		return ErrRepositoryRequired
+func (c *Config) Validate() error {
+	err := c.Load()
+	if err != nil {
+		return err
 	}
+	return c.Check()
+}
 
-	if c.RepositoryOwner == "" {
-		return ErrRepositoryOwnerRequired
+// Check validates the values of the config, without loading it first
+func (c *Config) Check() error {
+	var err error
+	for _, provider := range c.GetProviders() {
+		switch provider {
+		case ProviderGitHub:
+			if c.Repository == "" {
+				return ErrRepositoryRequired
+			}
+
+			if c.RepositoryOwner == "" {
+				return ErrRepositoryOwnerRequired
+			}
+
+			if c.ReleaseKey != ReleaseKeyTag && c.ReleaseKey != ReleaseKeyTitle {
+				return fmt.Errorf("%w: %s", ErrInvalidReleaseKey, c.ReleaseKey)
+			}
+		case ProviderOCI:
+			if c.OCIRepository == "" {
+				return ErrOCIRepositoryRequired
+			}
+
+			if _, err = reference.ParseRepository(c.OCIRepository); err != nil {
+				return fmt.Errorf("%w: %s", ErrInvalidOCIRepository, c.OCIRepository)
+			}
+		default:
+			return fmt.Errorf("%w: %s", ErrInvalidProvider, provider)
+		}
 	}
 
 	if c.Hostname == "" {
@@ -118,6 +908,62 @@ func (c *Config) Validate() error {
 		return ErrListenAddressRequired
 	}
 
+	for _, limit := range []struct {
+		name  string
+		value int
+	}{
+		{"max_header_size", c.MaxHeaderSize},
+		{"max_uri_length", c.MaxURILength},
+		{"max_body_size", c.MaxBodySize},
+		{"concurrency", c.Concurrency},
+	} {
+		if limit.value <= 0 {
+			return fmt.Errorf("%w: %s must be positive, got %d", ErrInvalidLimit, limit.name, limit.value)
+		}
+	}
+
+	if c.RateLimit < 0 {
+		return fmt.Errorf("%w: rate_limit must not be negative, got %d", ErrInvalidLimit, c.RateLimit)
+	}
+
+	for _, proxy := range c.TrustedProxies {
+		if _, _, err = net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("%w: %s", ErrInvalidTrustedProxy, proxy)
+		}
+	}
+
+	if c.RateLimit > 0 && c.RateLimitWindow <= 0 {
+		return fmt.Errorf("%w: rate_limit_window must be positive, got %s", ErrInvalidLimit, c.RateLimitWindow)
+	}
+
+	if c.BandwidthLimit < 0 {
+		return fmt.Errorf("%w: bandwidth_limit must not be negative, got %d", ErrInvalidLimit, c.BandwidthLimit)
+	}
+
+	if c.ConnectionBandwidthLimit < 0 {
+		return fmt.Errorf("%w: connection_bandwidth_limit must not be negative, got %d", ErrInvalidLimit, c.ConnectionBandwidthLimit)
+	}
+
+	if c.MaxConcurrentTransfers < 0 {
+		return fmt.Errorf("%w: max_concurrent_transfers must not be negative, got %d", ErrInvalidLimit, c.MaxConcurrentTransfers)
+	}
+
+	if c.MaxConcurrentTransfers > 0 && c.TransferQueueSize < 0 {
+		return fmt.Errorf("%w: transfer_queue_size must not be negative, got %d", ErrInvalidLimit, c.TransferQueueSize)
+	}
+
+	if c.MaxConcurrentTransfers > 0 && c.TransferQueueTimeout <= 0 {
+		return fmt.Errorf("%w: transfer_queue_timeout must be positive, got %s", ErrInvalidLimit, c.TransferQueueTimeout)
+	}
+
+	if c.ScriptDownloadAttempts < 1 {
+		return fmt.Errorf("%w: script_download_attempts must be at least 1, got %d", ErrInvalidLimit, c.ScriptDownloadAttempts)
+	}
+
+	if c.MaxEventSubscribers < 0 {
+		return fmt.Errorf("%w: max_event_subscribers must not be negative, got %d", ErrInvalidLimit, c.MaxEventSubscribers)
+	}
+
 	if c.Domain == "" {
 		return ErrDomainRequired
 	}
@@ -126,9 +972,344 @@ func (c *Config) Validate() error {
 		return ErrBinaryRequired
 	}
 
+	if c.MultiBinary && api.IsReservedName(c.Binary) {
+		return fmt.Errorf("%w: %s is the path of a fixed route", ErrReservedBinaryName, c.Binary)
+	}
+
+	binaryNames := make(map[string]struct{}, len(c.Binaries))
+	for _, binary := range c.Binaries {
+		if binary.Name == "" {
+			return ErrBinaryNameRequired
+		}
+		if _, ok := binaryNames[binary.Name]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateBinaryName, binary.Name)
+		}
+		binaryNames[binary.Name] = struct{}{}
+	}
+
+	names := []string{c.Binary}
+	if c.InstallName != "" {
+		names = append(names, c.InstallName)
+	}
+	for os, installName := range c.InstallNames {
+		names = append(names, os, installName)
+	}
+	for _, binary := range c.Binaries {
+		names = append(names, binary.Name)
+		for os, installName := range binary.InstallNames {
+			names = append(names, os, installName)
+		}
+	}
+	for _, name := range names {
+		if name == "" {
+			// an empty install name falls back to the binary name
+			continue
+		}
+		if !validName.MatchString(name) {
+			return fmt.Errorf("%w: %q may only contain letters, digits, '.', '_' and '-'", ErrInvalidBinaryName, name)
+		}
+	}
+
+	if c.HTTPSProxy != "" {
+		if proxyURL, err := url.Parse(c.HTTPSProxy); err != nil || proxyURL.Host == "" {
+			return fmt.Errorf("%w: %s", ErrInvalidHTTPSProxy, c.HTTPSProxy)
+		}
+	}
+
+	if c.BuildFromSourceURL != "" {
+		if sourceURL, err := url.Parse(c.BuildFromSourceURL); err != nil || (sourceURL.Scheme != "http" && sourceURL.Scheme != "https") || sourceURL.Host == "" {
+			return fmt.Errorf("%w: %s", ErrInvalidBuildFromSourceURL, c.BuildFromSourceURL)
+		}
+	}
+
+	if c.AnalyticsRetention < 0 {
+		return fmt.Errorf("%w: analytics_retention must not be negative, got %s", ErrInvalidLimit, c.AnalyticsRetention)
+	}
+
+	for name, rate := range c.AnalyticsSampleRates {
+		if rate < 0 || rate > 100 {
+			return fmt.Errorf("%w: %s=%d", ErrInvalidSampleRate, name, rate)
+		}
+	}
+
+	for _, enricher := range c.AnalyticsEnrichers {
+		switch enricher {
+		case EnricherGeo, EnricherRegion, EnricherChannel, EnricherReferrer:
+		default:
+			return fmt.Errorf("%w: %s", ErrInvalidEnricher, enricher)
+		}
+	}
+
+	if c.MinTLSVersion != "" {
+		if _, err = httpclient.ParseTLSVersion(c.MinTLSVersion); err != nil {
+			return err
+		}
+	}
+
+	if c.ImageRepository != "" {
+		if _, err = reference.ParseRepository(c.ImageRepository); err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidImageRepository, c.ImageRepository)
+		}
+	}
+
+	for releaseName, embargo := range c.Embargoes {
+		if _, err = time.Parse(time.RFC3339, embargo); err != nil {
+			return fmt.Errorf("%w: %s=%s", ErrInvalidEmbargo, releaseName, embargo)
+		}
+	}
+
+	if c.RolloutWindow < 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidRolloutWindow, c.RolloutWindow)
+	}
+
+	cohorts := make(map[string]struct{}, len(c.Cohorts))
+	for cohort, releaseName := range c.Cohorts {
+		if !validName.MatchString(cohort) || !validName.MatchString(releaseName) {
+			return fmt.Errorf("%w: %s=%s", ErrInvalidCohort, cohort, releaseName)
+		}
+		if _, ok := cohorts[strings.ToLower(cohort)]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateCohort, cohort)
+		}
+		cohorts[strings.ToLower(cohort)] = struct{}{}
+	}
+
+	switch c.SecretSource.Backend {
+	case "":
+	case SecretBackendVault, SecretBackendAWS, SecretBackendGCP:
+		if c.SecretSource.GithubToken == "" {
+			return ErrSecretReferenceRequired
+		}
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidSecretBackend, c.SecretSource.Backend)
+	}
+
+	adminTokenNames := make(map[string]struct{}, len(c.AdminTokens))
+	for _, token := range c.AdminTokens {
+		if token.Name == "" {
+			return ErrAdminTokenNameRequired
+		}
+		if _, ok := adminTokenNames[token.Name]; ok {
+			return fmt.Errorf("%w: %s", ErrDuplicateAdminToken, token.Name)
+		}
+		adminTokenNames[token.Name] = struct{}{}
+		if token.Token == "" {
+			return fmt.Errorf("%w: %s", ErrAdminTokenRequired, token.Name)
+		}
+		for _, scope := range token.Scopes {
+			switch scope {
+			case AdminScopeRead, AdminScopeCache, AdminScopeRollout:
+			default:
+				return fmt.Errorf("%w: %s: %s", ErrInvalidAdminScope, token.Name, scope)
+			}
+		}
+		if token.ExpiresAt != "" {
+			if _, err = time.Parse(time.RFC3339, token.ExpiresAt); err != nil {
+				return fmt.Errorf("%w: %s: expires_at must be an RFC 3339 timestamp, got %s", ErrInvalidAdminToken, token.Name, token.ExpiresAt)
+			}
+		}
+	}
+
+	if c.OIDC.Issuer != "" {
+		if issuer, err := url.Parse(c.OIDC.Issuer); err != nil || issuer.Scheme == "" || issuer.Host == "" {
+			return fmt.Errorf("%w: %s", ErrInvalidOIDCIssuer, c.OIDC.Issuer)
+		}
+		if c.OIDC.ClientID == "" {
+			return ErrOIDCClientIDRequired
+		}
+		for _, scope := range c.OIDC.Scopes {
+			switch scope {
+			case AdminScopeRead, AdminScopeCache, AdminScopeRollout:
+			default:
+				return fmt.Errorf("%w: oidc: %s", ErrInvalidAdminScope, scope)
+			}
+		}
+	}
+
+	if c.AdminListenAddress != "" && !c.AdminAuthEnabled() && !c.AdminInsecure && !isLoopbackAddress(c.AdminListenAddress) {
+		return fmt.Errorf("%w: admin_listen_address %s is not a loopback address, configure an admin token or oidc, or set admin_insecure", ErrAdminAuthRequired, c.AdminListenAddress)
+	}
+
+	switch c.SecurityHeaders.Profile {
+	case "", SecurityProfileDefault, SecurityProfileStrict:
+	default:
+		return fmt.Errorf("%w: %s", ErrInvalidSecurityProfile, c.SecurityHeaders.Profile)
+	}
+
+	if c.SecurityHeaders.HSTSMaxAge < 0 {
+		return fmt.Errorf("%w: hsts_max_age must not be negative, got %d", ErrInvalidLimit, c.SecurityHeaders.HSTSMaxAge)
+	}
+
+	if c.Winget.PackageIdentifier != "" {
+		if c.Winget.License == "" {
+			return ErrWingetLicenseRequired
+		}
+
+		if c.Winget.ShortDescription == "" {
+			return ErrWingetDescriptionRequired
+		}
+	}
+
 	return nil
 }
 
+// GetDownloadOptions returns the options of the HTTP client used to download assets from the providers
+func (c *Config) GetDownloadOptions() httpclient.Options {
+	return httpclient.Options{
+		ConnectTimeout: c.DownloadConnectTimeout,
+		ReadTimeout:    c.DownloadReadTimeout,
+		Retries:        c.DownloadRetries,
+		HTTPSProxy:     c.HTTPSProxy,
+		NoProxy:        c.NoProxy,
+		CABundle:       c.CABundle,
+		MinTLSVersion:  c.MinTLSVersion,
+	}
+}
+
+// GetAPIOptions returns the options of the HTTP client used for the API requests of the providers,
+// which share the proxy settings of asset downloads but are left to the API client to retry
+func (c *Config) GetAPIOptions() httpclient.Options {
+	return httpclient.Options{
+		ConnectTimeout: httpclient.DefaultConnectTimeout,
+		ReadTimeout:    httpclient.DefaultReadTimeout,
+		HTTPSProxy:     c.HTTPSProxy,
+		NoProxy:        c.NoProxy,
+		CABundle:       c.CABundle,
+		MinTLSVersion:  c.MinTLSVersion,
+	}
+}
+
+// GetGithubToken returns the GitHub token, which is read from the token file if no token is set.
+// In Kubernetes mode the token file defaults to DefaultKubernetesGithubTokenFile, which may not exist.
+func (c *Config) GetGithubToken() (string, error) {
+	if c.GithubToken != "" {
+		return c.GithubToken, nil
+	}
+
+	file := c.GithubTokenFile
+	if file == "" {
+		if !c.Kubernetes {
+			return "", nil
+		}
+		file = DefaultKubernetesGithubTokenFile
+	}
+
+	token, err := os.ReadFile(file)
+	if err != nil {
+		if c.GithubTokenFile == "" && errors.Is(err, os.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("unable to read github token file: %w", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+// GetShutdownDelay returns how long the server keeps serving after SIGTERM,
+// which defaults to DefaultKubernetesShutdownDelay in Kubernetes mode
+func (c *Config) GetShutdownDelay() time.Duration {
+	if c.ShutdownDelay == 0 && c.Kubernetes {
+		return DefaultKubernetesShutdownDelay
+	}
+	return c.ShutdownDelay
+}
+
+// GetProviders returns the ordered list of release providers, which is Providers
+// if it is set and otherwise just Provider
+func (c *Config) GetProviders() []string {
+	if len(c.Providers) > 0 {
+		return c.Providers
+	}
+	return []string{c.Provider}
+}
+
+// GetInstallName returns the name the binary should be installed as on the given operating system
+func (c *Config) GetInstallName(os string) string {
+	if installName, ok := c.InstallNames[os]; ok && installName != "" {
+		return installName
+	}
+	if c.InstallName != "" {
+		return c.InstallName
+	}
+	return c.Binary
+}
+
+// GetBinaryNames returns the names of the configured binaries
+func (c *Config) GetBinaryNames() []string {
+	binaryNames := make([]string, 0, len(c.Binaries))
+	for _, binary := range c.Binaries {
+		binaryNames = append(binaryNames, binary.Name)
+	}
+	return binaryNames
+}
+
+// GetAssetPrefix returns the configured AssetPrefix in lowercase and with the underscore that
+// separates it from the rest of the asset name, it is empty if no prefix is configured
+func (c *Config) GetAssetPrefix() string {
+	if c.AssetPrefix == "" {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(c.AssetPrefix), "_") + "_"
+}
+
+// MatchesAssetPrefix returns true if the given asset name should be served given the configured AssetPrefix
+func (c *Config) MatchesAssetPrefix(assetName string) bool {
+	return strings.HasPrefix(strings.ToLower(assetName), c.GetAssetPrefix())
+}
+
+// GetCohortRelease returns the name of the release configured for the given cohort
+func (c *Config) GetCohortRelease(cohort string) (string, bool) {
+	for name, releaseName := range c.Cohorts {
+		if strings.EqualFold(name, cohort) {
+			return strings.ToLower(releaseName), true
+		}
+	}
+	return "", false
+}
+
+// isLoopbackAddress returns true if the given listen address (e.g. 127.0.0.1:9090) only accepts connections
+// from the same host, an address without a host (e.g. :9090) listens on every interface
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// AdminAuthEnabled returns true if the admin routes require a bearer token or signing in with OIDC
+func (c *Config) AdminAuthEnabled() bool {
+	return c.AdminToken != "" || len(c.AdminTokens) > 0 || c.OIDC.Issuer != ""
+}
+
+// GetEmbargo returns the time until which the given release is withheld, if it is embargoed
+func (c *Config) GetEmbargo(releaseName string) (time.Time, bool) {
+	for name, embargo := range c.Embargoes {
+		if strings.EqualFold(name, releaseName) {
+			until, err := time.Parse(time.RFC3339, embargo)
+			return until, err == nil
+		}
+	}
+	return time.Time{}, false
+}
+
+// NextEmbargoLift returns the earliest time after now at which an embargo ends
+func (c *Config) NextEmbargoLift(now time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, embargo := range c.Embargoes {
+		until, err := time.Parse(time.RFC3339, embargo)
+		if err != nil || !until.After(now) {
+			continue
+		}
+		if next.IsZero() || until.Before(next) {
+			next = until
+		}
+	}
+	return next, !next.IsZero()
+}
+
 func (c *Config) DefaultConfigDir() (string, error) {
 	dir, err := homedir.Expand(defaultConfigPath)
 	if err != nil {
@@ -0,0 +1,154 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+// fileOnlyKeys are the config fields that can only be set in the config file or with their environment
+// variable, since they are lists of structs or nested settings that do not fit on the command line
+var fileOnlyKeys = map[string]struct{}{
+	"admin_tokens":                   {},
+	"binaries":                       {},
+	"oidc.scopes":                    {},
+	"secret_source.aws_region":       {},
+	"secret_source.backend":          {},
+	"secret_source.github_token":     {},
+	"secret_source.refresh_interval": {},
+	"secret_source.vault_address":    {},
+	"secret_source.vault_token":      {},
+	"winget.license":                 {},
+	"winget.package_identifier":      {},
+	"winget.package_name":            {},
+	"winget.publisher":               {},
+	"winget.short_description":       {},
+}
+
+// fieldAddresses returns the addresses of the config fields of the given struct by their keys (see Keys)
+func fieldAddresses(prefix string, v reflect.Value, addresses map[string]uintptr) {
+	for i := 0; i < v.NumField(); i++ {
+		name := fieldName(v.Type().Field(i))
+		if name == "" {
+			continue
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct && field.Type() != durationType {
+			fieldAddresses(prefix+name+".", field, addresses)
+			continue
+		}
+		addresses[prefix+name] = field.Addr().Pointer()
+	}
+}
+
+// flagAddress returns the address of the variable the given flag sets, pflag stores scalars as
+// pointers to the variable and lists and maps as structs whose first field points to it
+func flagAddress(f *pflag.Flag) uintptr {
+	value := reflect.ValueOf(f.Value)
+	if value.Kind() == reflect.Pointer && value.Elem().Kind() == reflect.Struct {
+		return value.Elem().Field(0).Pointer()
+	}
+	return value.Pointer()
+}
+
+type envBinder map[string]string
+
+func (b envBinder) BindEnv(input ...string) error {
+	b[input[0]] = input[1]
+	return nil
+}
+
+func TestConfigFields(t *testing.T) {
+	c := New()
+	addresses := make(map[string]uintptr)
+	fieldAddresses("", reflect.ValueOf(c).Elem(), addresses)
+
+	keys := Keys()
+	if len(keys) != len(addresses) {
+		t.Fatalf("Keys returned %d keys for %d config fields", len(keys), len(addresses))
+	}
+
+	flags := pflag.NewFlagSet("releaser", pflag.ContinueOnError)
+	c.RootPersistentFlags(flags)
+	defer func() {
+		rootFlags = nil
+	}()
+	flagNames := make(map[uintptr]string)
+	flags.VisitAll(func(f *pflag.Flag) {
+		flagNames[flagAddress(f)] = f.Name
+	})
+
+	env := make(envBinder)
+	if err := bindEnv(env); err != nil {
+		t.Fatalf("unable to bind environment variables: %s", err)
+	}
+	envKeys := make(map[string]string)
+
+	properties := Schema()["properties"].(map[string]interface{})
+
+	for _, key := range keys {
+		address, ok := addresses[key]
+		if !ok {
+			t.Errorf("%s: no config field", key)
+			continue
+		}
+
+		if _, fileOnly := fileOnlyKeys[key]; fileOnly {
+			if name, ok := flagNames[address]; ok {
+				t.Errorf("%s: has flag --%s but is listed as file only", key, name)
+			}
+		} else if _, ok := flagNames[address]; !ok {
+			t.Errorf("%s: no flag", key)
+		}
+
+		envVar, ok := env[key]
+		if !ok || envVar != EnvVar(key) {
+			t.Errorf("%s: not bound to environment variable %s", key, EnvVar(key))
+		}
+		if existing, ok := envKeys[envVar]; ok {
+			t.Errorf("%s: environment variable %s is also used by %s", key, envVar, existing)
+		}
+		envKeys[envVar] = key
+
+		schema := properties
+		path := strings.Split(key, ".")
+		for i, name := range path {
+			property, ok := schema[name].(map[string]interface{})
+			if !ok {
+				t.Errorf("%s: no schema", key)
+				break
+			}
+			if i < len(path)-1 {
+				schema, _ = property["properties"].(map[string]interface{})
+			}
+		}
+	}
+
+	fields := make(map[uintptr]struct{}, len(addresses))
+	for _, address := range addresses {
+		fields[address] = struct{}{}
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		if _, ok := fields[flagAddress(f)]; !ok {
+			t.Errorf("--%s: does not set a config field", f.Name)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redacted replaces the values of secret fields in the output of Effective
+const Redacted = "<redacted>"
+
+// Effective returns the values of the config keyed by their names in the config file, with
+// secrets redacted, so that it can be printed in the same form it is configured in
+func (c *Config) Effective() map[string]interface{} {
+	values := effectiveValue(reflect.ValueOf(c).Elem()).(map[string]interface{})
+	for name, secret := range c.secrets() {
+		if *secret == "" {
+			continue
+		}
+		redact(values, strings.Split(name, "."))
+	}
+	if proxyURL, err := url.Parse(c.HTTPSProxy); err == nil && proxyURL.User != nil {
+		values["https_proxy"] = proxyURL.Redacted()
+	}
+	return values
+}
+
+// redact replaces the value at the given path of nested sections and list indices with Redacted
+func redact(values map[string]interface{}, path []string) {
+	var parent interface{} = values
+	for _, segment := range path[:len(path)-1] {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			parent = p[segment]
+		case []interface{}:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i >= len(p) {
+				return
+			}
+			parent = p[i]
+		}
+	}
+	if p, ok := parent.(map[string]interface{}); ok {
+		p[path[len(path)-1]] = Redacted
+	}
+}
+
+func effectiveValue(v reflect.Value) interface{} {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return effectiveValue(v.Elem())
+	case reflect.Slice, reflect.Array:
+		values := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			values = append(values, effectiveValue(v.Index(i)))
+		}
+		return values
+	case reflect.Map:
+		values := make(map[string]interface{}, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = effectiveValue(iter.Value())
+		}
+		return values
+	case reflect.Struct:
+		values := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			name := fieldName(v.Type().Field(i))
+			if name == "" {
+				continue
+			}
+			values[name] = effectiveValue(v.Field(i))
+		}
+		return values
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,124 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/json"
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/pflag"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables every config field can be set with,
+// the variable of a field is its name in the config file in upper case (e.g. RELEASER_REPOSITORY_OWNER
+// or RELEASER_WINGET_PUBLISHER for nested fields)
+const EnvPrefix = "RELEASER"
+
+// rootFlags are the root persistent flags, flags that are set explicitly take precedence over environment variables
+var rootFlags *pflag.FlagSet
+
+// Keys returns the names of every config field in the config file, with nested fields
+// joined by a dot (e.g. winget.publisher)
+func Keys() []string {
+	var keys []string
+	collectKeys("", reflect.TypeOf(Config{}), &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func collectKeys(prefix string, t reflect.Type, keys *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		name := fieldName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		if t.Field(i).Type.Kind() == reflect.Struct && t.Field(i).Type != durationType {
+			collectKeys(prefix+name+".", t.Field(i).Type, keys)
+			continue
+		}
+		*keys = append(*keys, prefix+name)
+	}
+}
+
+// EnvVar returns the environment variable the config field with the given name is read from
+func EnvVar(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// bindEnv binds every config field to its environment variable, except the fields
+// whose flag was set explicitly
+func bindEnv(v interface{ BindEnv(...string) error }) error {
+	for _, key := range Keys() {
+		if rootFlags != nil {
+			if f := rootFlags.Lookup(strings.ReplaceAll(key, "_", "-")); f != nil && f.Changed {
+				continue
+			}
+		}
+		err := v.BindEnv(key, EnvVar(key))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeHook converts the strings environment variables are read as into the types of the config fields:
+// maps may be given as key=value pairs separated by commas (e.g. windows=cli.exe,darwin=cli), lists as
+// values separated by commas, and maps, lists, and structs as JSON
+func decodeHook() mapstructure.DecodeHookFunc {
+	return mapstructure.ComposeDecodeHookFunc(
+		mapstructure.StringToTimeDurationHookFunc(),
+		stringToCollectionHookFunc,
+		mapstructure.StringToSliceHookFunc(","),
+	)
+}
+
+func stringToCollectionHookFunc(from reflect.Type, to reflect.Type, data interface{}) (interface{}, error) {
+	value, ok := data.(string)
+	if !ok || from.Kind() != reflect.String {
+		return data, nil
+	}
+
+	switch to.Kind() {
+	case reflect.Map, reflect.Struct, reflect.Slice:
+	default:
+		return data, nil
+	}
+
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "{") || strings.HasPrefix(value, "[") {
+		var decoded interface{}
+		err := json.Unmarshal([]byte(value), &decoded)
+		if err != nil {
+			return nil, err
+		}
+		return decoded, nil
+	}
+
+	if to.Kind() != reflect.Map {
+		return data, nil
+	}
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		if key, val, ok := strings.Cut(pair, "="); ok {
+			pairs[strings.TrimSpace(key)] = strings.TrimSpace(val)
+		}
+	}
+	return pairs, nil
+}
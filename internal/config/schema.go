@@ -0,0 +1,290 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FieldError describes a problem with a single field of a config file
+type FieldError struct {
+	// Field is the path of the field (e.g. winget.publisher)
+	Field string
+
+	// Message describes the problem and, for unknown fields, suggests the field that was likely meant
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Schema returns the JSON schema of the config file, which is generated from the mapstructure tags
+// of Config so that it always covers every field
+func Schema() map[string]interface{} {
+	schema := typeSchema(reflect.TypeOf(Config{}))
+
+	// the output settings are read from the config file by cmdutils instead of into Config
+	properties := schema["properties"].(map[string]interface{})
+	properties["format"] = map[string]interface{}{"type": "string"}
+	properties["debug"] = map[string]interface{}{"type": "boolean"}
+	properties["no-color"] = map[string]interface{}{"type": "boolean"}
+
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "Releaser Configuration"
+	return schema
+}
+
+// typeSchema returns the JSON schema of values of the given type
+func typeSchema(t reflect.Type) map[string]interface{} {
+	if t == durationType {
+		return map[string]interface{}{"type": []string{"string", "integer"}, "format": "duration"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": typeSchema(t.Elem())}
+	case reflect.Struct:
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			name := fieldName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			properties[name] = typeSchema(t.Field(i).Type)
+		}
+		return map[string]interface{}{"type": "object", "properties": properties, "additionalProperties": false}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// fieldName returns the name of the given struct field in the config file, or an empty string if it is not part of it
+func fieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// ValidateSchema checks the values decoded from a config file against the schema, returning every unknown
+// field and every value of the wrong type. Like viper, it matches field names case-insensitively and
+// accepts strings for numbers and booleans if they can be parsed.
+func ValidateSchema(values map[string]interface{}) []*FieldError {
+	var errs []*FieldError
+	validateValue("", values, Schema(), &errs)
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Field < errs[j].Field
+	})
+	return errs
+}
+
+func validateValue(field string, value interface{}, schema map[string]interface{}, errs *[]*FieldError) {
+	if value == nil {
+		return
+	}
+
+	mismatch := func(expected string) {
+		*errs = append(*errs, &FieldError{
+			Field:   field,
+			Message: fmt.Sprintf("expected %s, got %s", expected, describe(value)),
+		})
+	}
+
+	if schema["format"] == "duration" {
+		switch v := value.(type) {
+		case int, int64, uint64:
+		case string:
+			if _, err := time.ParseDuration(v); err != nil {
+				mismatch("a duration (e.g. 30s or 5m)")
+			}
+		default:
+			mismatch("a duration (e.g. 30s or 5m)")
+		}
+		return
+	}
+
+	switch schema["type"] {
+	case "string":
+		switch value.(type) {
+		case string, time.Time, int, int64, uint64, float64, bool:
+		default:
+			mismatch("a string")
+		}
+	case "boolean":
+		switch v := value.(type) {
+		case bool:
+		case string:
+			if _, err := strconv.ParseBool(v); err != nil {
+				mismatch("true or false")
+			}
+		default:
+			mismatch("true or false")
+		}
+	case "integer":
+		switch v := value.(type) {
+		case int, int64, uint64:
+		case string:
+			if _, err := strconv.Atoi(v); err != nil {
+				mismatch("an integer")
+			}
+		default:
+			mismatch("an integer")
+		}
+	case "number":
+		switch v := value.(type) {
+		case int, int64, uint64, float64:
+		case string:
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				mismatch("a number")
+			}
+		default:
+			mismatch("a number")
+		}
+	case "array":
+		items, _ := schema["items"].(map[string]interface{})
+		switch v := value.(type) {
+		case []interface{}:
+			for i, item := range v {
+				validateValue(fmt.Sprintf("%s[%d]", field, i), item, items, errs)
+			}
+		case string:
+			// viper splits comma separated strings into slices
+		default:
+			mismatch("a list")
+		}
+	case "object":
+		v, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch("a mapping")
+			return
+		}
+		validateObject(field, v, schema, errs)
+	}
+}
+
+func validateObject(field string, values map[string]interface{}, schema map[string]interface{}, errs *[]*FieldError) {
+	if additional, ok := schema["additionalProperties"].(map[string]interface{}); ok {
+		for key, value := range values {
+			validateValue(joinField(field, key), value, additional, errs)
+		}
+		return
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for key, value := range values {
+		property, ok := properties[strings.ToLower(key)].(map[string]interface{})
+		if !ok {
+			message := "unknown field"
+			if suggestion := suggest(strings.ToLower(key), names); suggestion != "" {
+				message = fmt.Sprintf("unknown field, did you mean %q?", suggestion)
+			}
+			*errs = append(*errs, &FieldError{Field: joinField(field, key), Message: message})
+			continue
+		}
+		validateValue(joinField(field, key), value, property, errs)
+	}
+}
+
+func joinField(field string, key string) string {
+	if field == "" {
+		return key
+	}
+	return field + "." + key
+}
+
+// describe returns a short description of a value decoded from a config file for error messages
+func describe(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("string %q", v)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "a mapping"
+	default:
+		return fmt.Sprintf("%T %v", v, v)
+	}
+}
+
+// suggest returns the name closest to the given unknown field name, if it is close enough to be a typo
+func suggest(key string, names []string) string {
+	best, bestDistance := "", len(key)/3+2
+	for _, name := range names {
+		if distance := levenshtein(key, name); distance < bestDistance {
+			best, bestDistance = name, distance
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a string, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
@@ -0,0 +1,85 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// secretFilePrefix marks secrets that are read from a file (e.g. file:///run/secrets/github-token)
+const secretFilePrefix = "file://"
+
+// secretEnvRegex matches the ${ENV_VAR} references that are replaced in secrets
+var secretEnvRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// secrets returns the secret fields of the config by their names in the config file,
+// which support interpolation and are redacted when the config is printed
+func (c *Config) secrets() map[string]*string {
+	secrets := map[string]*string{
+		"github_token":      &c.GithubToken,
+		"oci_password":      &c.OCIPassword,
+		"analytics_salt":    &c.AnalyticsSalt,
+		"admin_token":       &c.AdminToken,
+		"alert_webhook_url": &c.AlertWebhookURL,
+
+		"secret_source.vault_token": &c.SecretSource.VaultToken,
+		"oidc.client_secret":        &c.OIDC.ClientSecret,
+	}
+	for i := range c.AdminTokens {
+		secrets[fmt.Sprintf("admin_tokens.%d.token", i)] = &c.AdminTokens[i].Token
+	}
+	return secrets
+}
+
+// InterpolateSecrets resolves the secret fields that reference a file or environment variables,
+// so that secrets do not have to be written to the config file in plaintext
+func (c *Config) InterpolateSecrets() error {
+	for name, secret := range c.secrets() {
+		value, err := interpolateSecret(*secret)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %s", ErrInvalidSecret, name, err)
+		}
+		*secret = value
+	}
+	return nil
+}
+
+// interpolateSecret returns the contents of the file a secret references with file://, or the
+// secret with every ${ENV_VAR} replaced by the value of the environment variable
+func interpolateSecret(secret string) (string, error) {
+	if path, ok := strings.CutPrefix(secret, secretFilePrefix); ok {
+		value, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(value)), nil
+	}
+
+	var err error
+	value := secretEnvRegex.ReplaceAllStringFunc(secret, func(reference string) string {
+		name := secretEnvRegex.FindStringSubmatch(reference)[1]
+		value, ok := os.LookupEnv(name)
+		if !ok && err == nil {
+			err = fmt.Errorf("environment variable %s is not set", name)
+		}
+		return value
+	})
+	return value, err
+}
@@ -0,0 +1,254 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"golang.org/x/net/http/httpproxy"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidCABundle   = errors.New("invalid ca bundle")
+	ErrInvalidTLSVersion = errors.New("invalid tls version")
+)
+
+// tlsVersions maps the supported minimum TLS versions to their crypto/tls values
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+const (
+	DefaultConnectTimeout = time.Second * 10
+	DefaultReadTimeout    = time.Second * 30
+	DefaultRetries        = 3
+
+	// retryBackoff is the delay before the first retry, it doubles with every retry
+	retryBackoff = time.Millisecond * 500
+)
+
+// Options configures the HTTP clients used for upstream requests
+type Options struct {
+	// ConnectTimeout limits how long establishing a connection (including the TLS handshake) may take
+	ConnectTimeout time.Duration
+
+	// ReadTimeout limits how long to wait for the response headers and, once the
+	// response has started, for each read of the response body
+	ReadTimeout time.Duration
+
+	// Retries is the number of times idempotent requests are retried after
+	// a connection error or a 429 or 5xx response
+	Retries int
+
+	// HTTPSProxy and NoProxy override the HTTPS_PROXY and NO_PROXY environment variables
+	HTTPSProxy string
+	NoProxy    string
+
+	// CABundle is the path of a PEM file with certificates trusted in addition to the system
+	// roots (e.g. the certificate of a TLS-intercepting proxy or of an internal CA)
+	CABundle string
+
+	// MinTLSVersion is the minimum TLS version (e.g. 1.2) accepted from upstream servers,
+	// the crypto/tls default is used if it is empty
+	MinTLSVersion string
+}
+
+// ParseTLSVersion returns the crypto/tls value of the given TLS version (e.g. 1.2)
+func ParseTLSVersion(version string) (uint16, error) {
+	if tlsVersion, ok := tlsVersions[version]; ok {
+		return tlsVersion, nil
+	}
+	return 0, fmt.Errorf("%w: %s", ErrInvalidTLSVersion, version)
+}
+
+// New returns an HTTP client for the given options, which uses the proxy configured in the
+// environment unless it is overridden by the options
+func New(options Options) (*http.Client, error) {
+	dialer := &net.Dialer{
+		Timeout:   options.ConnectTimeout,
+		KeepAlive: time.Second * 30,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy(options)
+	transport.DialContext = dialer.DialContext
+	transport.TLSHandshakeTimeout = options.ConnectTimeout
+
+	if options.CABundle != "" || options.MinTLSVersion != "" {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+
+	if options.CABundle != "" {
+		rootCAs, err := loadCABundle(options.CABundle)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.RootCAs = rootCAs
+	}
+
+	if options.MinTLSVersion != "" {
+		minTLSVersion, err := ParseTLSVersion(options.MinTLSVersion)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig.MinVersion = minTLSVersion
+	}
+
+	return &http.Client{
+		Transport: &roundTripper{
+			transport: transport,
+			options:   options,
+		},
+	}, nil
+}
+
+// proxy returns the proxy function for the given options, falling back to
+// the environment for the settings the options do not override
+func proxy(options Options) func(*http.Request) (*url.URL, error) {
+	if options.HTTPSProxy == "" && options.NoProxy == "" {
+		return http.ProxyFromEnvironment
+	}
+
+	proxyConfig := httpproxy.FromEnvironment()
+	if options.HTTPSProxy != "" {
+		proxyConfig.HTTPSProxy = options.HTTPSProxy
+	}
+	if options.NoProxy != "" {
+		proxyConfig.NoProxy = options.NoProxy
+	}
+
+	proxyFunc := proxyConfig.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}
+}
+
+// loadCABundle returns the system roots with the certificates in the given PEM file added
+func loadCABundle(path string) (*x509.CertPool, error) {
+	bundle, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCABundle, err)
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+	if !rootCAs.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("%w: no certificates found in %s", ErrInvalidCABundle, path)
+	}
+
+	return rootCAs, nil
+}
+
+// roundTripper retries failed idempotent requests and enforces the read timeout
+type roundTripper struct {
+	transport http.RoundTripper
+	options   Options
+}
+
+func (r *roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	retries := r.options.Retries
+	if req.Body != nil || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		retries = 0
+	}
+
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		res, err := r.roundTrip(req)
+		if attempt >= retries || !retryable(res, err) {
+			return res, err
+		}
+		if res != nil {
+			_ = res.Body.Close()
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// roundTrip sends a single request, cancelling it if the response headers
+// or any read of the response body take longer than the read timeout
+func (r *roundTripper) roundTrip(req *http.Request) (*http.Response, error) {
+	if r.options.ReadTimeout <= 0 {
+		return r.transport.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(r.options.ReadTimeout, cancel)
+	res, err := r.transport.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		timer.Stop()
+		cancel()
+		return nil, err
+	}
+
+	timer.Reset(r.options.ReadTimeout)
+	res.Body = &timeoutBody{
+		ReadCloser: res.Body,
+		timer:      timer,
+		timeout:    r.options.ReadTimeout,
+		cancel:     cancel,
+	}
+	return res, nil
+}
+
+// retryable returns true if the given outcome of a request is worth retrying
+func retryable(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= http.StatusInternalServerError
+}
+
+// timeoutBody cancels the request it belongs to if a read does not complete within the timeout
+type timeoutBody struct {
+	io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+func (b *timeoutBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.timer.Reset(b.timeout)
+	return n, err
+}
+
+func (b *timeoutBody) Close() error {
+	b.timer.Stop()
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
@@ -0,0 +1,157 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package kubernetes
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// serviceAccountPath is where Kubernetes mounts the credentials of the service account of a pod
+	serviceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+	// component is reported as the source of the events created by the releaser
+	component = "releaser"
+)
+
+var (
+	ErrNotInCluster = errors.New("not running inside a kubernetes cluster")
+)
+
+// Client creates events for the pod the releaser is running in, using the credentials
+// of its service account. It only implements the small part of the Kubernetes API the
+// releaser needs, so that it does not depend on client-go.
+type Client struct {
+	client    *http.Client
+	host      string
+	token     string
+	namespace string
+	pod       string
+}
+
+// InCluster returns a client for the cluster the releaser is running in. The name of the pod
+// is read from the POD_NAME environment variable, falling back to the hostname of the pod.
+func InCluster() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+
+	token, err := os.ReadFile(path.Join(serviceAccountPath, "token"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read service account token: %w", err)
+	}
+
+	namespace, err := os.ReadFile(path.Join(serviceAccountPath, "namespace"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read service account namespace: %w", err)
+	}
+
+	ca, err := os.ReadFile(path.Join(serviceAccountPath, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read service account ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("invalid service account ca")
+	}
+
+	pod := os.Getenv("POD_NAME")
+	if pod == "" {
+		pod, _ = os.Hostname()
+	}
+
+	return &Client{
+		client: &http.Client{
+			Timeout: time.Second * 10,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12},
+			},
+		},
+		host:      "https://" + net.JoinHostPort(host, port),
+		token:     strings.TrimSpace(string(token)),
+		namespace: strings.TrimSpace(string(namespace)),
+		pod:       pod,
+	}, nil
+}
+
+// Warning creates a warning event with the given reason (e.g. RefreshFailed) for the pod
+func (c *Client) Warning(ctx context.Context, reason string, message string) error {
+	return c.event(ctx, "Warning", reason, message)
+}
+
+// Normal creates a normal event with the given reason for the pod
+func (c *Client) Normal(ctx context.Context, reason string, message string) error {
+	return c.event(ctx, "Normal", reason, message)
+}
+
+func (c *Client) event(ctx context.Context, eventType string, reason string, message string) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	body, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Event",
+		"metadata": map[string]interface{}{
+			"generateName": c.pod + ".",
+			"namespace":    c.namespace,
+		},
+		"involvedObject": map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Pod",
+			"name":       c.pod,
+			"namespace":  c.namespace,
+		},
+		"type":           eventType,
+		"reason":         reason,
+		"message":        message,
+		"source":         map[string]interface{}{"component": component},
+		"firstTimestamp": now,
+		"lastTimestamp":  now,
+		"count":          1,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.host+"/api/v1/namespaces/"+c.namespace+"/events", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("kubernetes api responded with %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/releaser/internal/config"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAWSRegionRequired      = errors.New("aws region is required")
+	ErrAWSCredentialsRequired = errors.New("aws credentials are required")
+)
+
+// aws reads secrets from AWS Secrets Manager, references are the ID (name or ARN) of the secret,
+// followed by the key holding the value if the secret is a JSON object (e.g. releaser#github_token)
+type aws struct {
+	client          *http.Client
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+func newAWS(source *config.SecretSource, client *http.Client) (*aws, error) {
+	region := source.AWSRegion
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		return nil, ErrAWSRegionRequired
+	}
+
+	a := &aws{
+		client:          client,
+		region:          region,
+		accessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if a.accessKeyID == "" || a.secretAccessKey == "" {
+		return nil, ErrAWSCredentialsRequired
+	}
+	return a, nil
+}
+
+func (a *aws) Get(ctx context.Context, reference string) (string, error) {
+	id, key, _ := strings.Cut(reference, "#")
+	body, err := json.Marshal(map[string]string{"SecretId": id})
+	if err != nil {
+		return "", err
+	}
+
+	host := "secretsmanager." + a.region + ".amazonaws.com"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://"+host+"/", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "secretsmanager.GetSecretValue")
+	a.sign(req, host, body, time.Now().UTC())
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		var failure struct {
+			Type string `json:"__type"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&failure)
+		if strings.HasSuffix(failure.Type, "ResourceNotFoundException") {
+			return "", fmt.Errorf("%w: %s", ErrSecretNotFound, id)
+		}
+		return "", fmt.Errorf("aws secrets manager responded with %d %s", res.StatusCode, failure.Type)
+	}
+
+	var secret struct {
+		SecretString string `json:"SecretString"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&secret)
+	if err != nil {
+		return "", err
+	}
+
+	if key == "" {
+		return secret.SecretString, nil
+	}
+	var fields map[string]interface{}
+	err = json.Unmarshal([]byte(secret.SecretString), &fields)
+	if err != nil {
+		return "", fmt.Errorf("secret %s is not a JSON object: %w", id, err)
+	}
+	value, ok := fields[key].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrFieldNotFound, key)
+	}
+	return value, nil
+}
+
+// sign signs the given request with AWS Signature Version 4
+func (a *aws) sign(req *http.Request, host string, body []byte, now time.Time) {
+	const service = "secretsmanager"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	if a.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", a.sessionToken)
+	}
+
+	headers := map[string]string{"host": host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		"/",
+		"",
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + a.region + "/" + service + "/aws4_request"
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalRequestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+a.secretAccessKey), date)
+	key = hmacSHA256(key, a.region)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+a.accessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+signature)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
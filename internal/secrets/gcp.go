@@ -0,0 +1,136 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// gcpMetadataTokenURL returns an access token for the service account of the instance
+	// the releaser runs on (e.g. a GCE instance or a GKE pod with workload identity)
+	gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+	// gcpMetadataTimeout bounds requests to the metadata server, which is local to the instance
+	gcpMetadataTimeout = time.Second * 5
+
+	gcpSecretManagerURL = "https://secretmanager.googleapis.com/v1/"
+)
+
+// gcp reads secrets from GCP Secret Manager, references are the resource name of the secret
+// (e.g. projects/p/secrets/github-token), which reads its latest version, or of a secret version
+type gcp struct {
+	client *http.Client
+
+	// metadataClient requests access tokens from the metadata server, it does not use the configured
+	// proxy since the metadata server is only reachable from the instance and responds over plain HTTP
+	metadataClient *http.Client
+}
+
+func newGCP(client *http.Client) *gcp {
+	return &gcp{
+		client: client,
+		metadataClient: &http.Client{
+			Timeout:   gcpMetadataTimeout,
+			Transport: &http.Transport{Proxy: nil},
+		},
+	}
+}
+
+func (g *gcp) Get(ctx context.Context, reference string) (string, error) {
+	if !strings.Contains(reference, "/versions/") {
+		reference += "/versions/latest"
+	}
+
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("unable to get gcp access token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpSecretManagerURL+reference+":access", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	res, err := g.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, reference)
+	case res.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("gcp secret manager responded with %d", res.StatusCode)
+	}
+
+	var body struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// accessToken returns an access token for the service account of the instance from the metadata server
+func (g *gcp) accessToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, gcpMetadataTokenURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	res, err := g.metadataClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata server responded with %d", res.StatusCode)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return "", err
+	}
+	return body.AccessToken, nil
+}
@@ -0,0 +1,100 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/releaser/internal/config"
+	"golang.org/x/oauth2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSecretNotFound = errors.New("secret not found")
+	ErrFieldNotFound  = errors.New("field not found in secret")
+)
+
+// Backend reads secrets from a secret manager
+type Backend interface {
+	// Get returns the value of the secret with the given reference, whose format depends on the backend
+	Get(ctx context.Context, reference string) (string, error)
+}
+
+// New returns the backend configured by the given secret source, which accesses
+// the secret manager with the given client
+func New(source *config.SecretSource, client *http.Client) (Backend, error) {
+	switch source.Backend {
+	case config.SecretBackendVault:
+		return newVault(source, client)
+	case config.SecretBackendAWS:
+		return newAWS(source, client)
+	case config.SecretBackendGCP:
+		return newGCP(client), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", config.ErrInvalidSecretBackend, source.Backend)
+	}
+}
+
+// TokenSource returns the secret with the given reference as an OAuth2 token, which is read again
+// once the refresh interval has passed so that rotated secrets are picked up. If reading the secret
+// fails after it has been read once, the previous value is used until the next refresh interval.
+func TokenSource(backend Backend, reference string, refresh time.Duration) oauth2.TokenSource {
+	return &tokenSource{
+		backend:   backend,
+		reference: reference,
+		refresh:   refresh,
+	}
+}
+
+type tokenSource struct {
+	backend   Backend
+	reference string
+	refresh   time.Duration
+
+	mu      sync.Mutex
+	token   *oauth2.Token
+	expires time.Time
+}
+
+func (t *tokenSource) Token() (*oauth2.Token, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != nil && time.Now().Before(t.expires) {
+		return t.token, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	value, err := t.backend.Get(ctx, t.reference)
+	t.expires = time.Now().Add(t.refresh)
+	if err != nil {
+		if t.token != nil {
+			value = t.token.AccessToken
+		} else {
+			return nil, fmt.Errorf("unable to read secret %s: %w", t.reference, err)
+		}
+	}
+
+	// the token expires at the next refresh, since oauth2.ReuseTokenSource would otherwise reuse it forever
+	t.token = &oauth2.Token{AccessToken: value, Expiry: t.expires}
+	return t.token, nil
+}
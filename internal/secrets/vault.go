@@ -0,0 +1,184 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/releaser/internal/config"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrVaultAddressRequired = errors.New("vault address is required")
+	ErrVaultTokenRequired   = errors.New("vault token is required")
+)
+
+// vault reads secrets from the KV secrets engine of HashiCorp Vault, references are the
+// API path of the secret and the field holding the value (e.g. secret/data/releaser#github_token)
+type vault struct {
+	client  *http.Client
+	address string
+	token   string
+
+	// renewAt is when the token is renewed next, it is zero until the token has been looked up and
+	// never is set to true once the token turned out to be non-renewable or to have no TTL (e.g. a root token)
+	mu      sync.Mutex
+	renewAt time.Time
+	never   bool
+}
+
+func newVault(source *config.SecretSource, client *http.Client) (*vault, error) {
+	address := source.VaultAddress
+	if address == "" {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, ErrVaultAddressRequired
+	}
+
+	token := source.VaultToken
+	if token == "" {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, ErrVaultTokenRequired
+	}
+
+	return &vault{
+		client:  client,
+		address: strings.TrimSuffix(address, "/"),
+		token:   token,
+	}, nil
+}
+
+func (v *vault) Get(ctx context.Context, reference string) (string, error) {
+	// a failed renewal is tried again on the next read, which fails by itself once the token expired
+	_ = v.renew(ctx)
+
+	path, field, _ := strings.Cut(reference, "#")
+	if field == "" {
+		field = "value"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.address+"/v1/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	switch {
+	case res.StatusCode == http.StatusNotFound:
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, path)
+	case res.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("vault responded with %d", res.StatusCode)
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return "", err
+	}
+
+	// version 2 of the KV secrets engine nests the fields of a secret in another data object
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+
+	value, ok := data[field].(string)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrFieldNotFound, field)
+	}
+	return value, nil
+}
+
+// renew looks up the token the first time it is called and renews it with auth/token/renew-self once
+// half of its TTL has passed, so that renewable tokens do not expire while the releaser runs
+func (v *vault) renew(ctx context.Context) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.never || (!v.renewAt.IsZero() && time.Now().Before(v.renewAt)) {
+		return nil
+	}
+
+	method, path := http.MethodPost, "/v1/auth/token/renew-self"
+	if v.renewAt.IsZero() {
+		method, path = http.MethodGet, "/v1/auth/token/lookup-self"
+	}
+	req, err := http.NewRequestWithContext(ctx, method, v.address+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault responded with %d", res.StatusCode)
+	}
+
+	// lookup-self describes the token in data, renew-self in auth
+	var body struct {
+		Data struct {
+			TTL       int64 `json:"ttl"`
+			Renewable bool  `json:"renewable"`
+		} `json:"data"`
+		Auth *struct {
+			LeaseDuration int64 `json:"lease_duration"`
+			Renewable     bool  `json:"renewable"`
+		} `json:"auth"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&body)
+	if err != nil {
+		return err
+	}
+
+	ttl, renewable := body.Data.TTL, body.Data.Renewable
+	if body.Auth != nil {
+		ttl, renewable = body.Auth.LeaseDuration, body.Auth.Renewable
+	}
+	if !renewable || ttl <= 0 {
+		v.never = true
+		return nil
+	}
+	v.renewAt = time.Now().Add(time.Duration(ttl) * time.Second / 2)
+	return nil
+}
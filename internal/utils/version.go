@@ -0,0 +1,118 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// CompareVersions compares two semver-like release names (e.g. v1.2.3 and v1.2.3-pre1)
+// and returns -1, 0, or 1 if a is less than, equal to, or greater than b
+//
+// Releases without a pre-release suffix are greater than releases with one
+func CompareVersions(a string, b string) int {
+	aVersion, aPre, _ := strings.Cut(strings.TrimPrefix(strings.ToLower(a), "v"), "-")
+	bVersion, bPre, _ := strings.Cut(strings.TrimPrefix(strings.ToLower(b), "v"), "-")
+
+	if c := compareParts(strings.Split(aVersion, "."), strings.Split(bVersion, ".")); c != 0 {
+		return c
+	}
+
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+
+	return compareParts(strings.Split(aPre, "."), strings.Split(bPre, "."))
+}
+
+// IsPrerelease returns true if the given semver-like release name has a pre-release suffix (e.g. v1.2.3-pre1)
+func IsPrerelease(name string) bool {
+	return strings.Contains(name, "-")
+}
+
+// compareParts compares dot separated version parts
+func compareParts(a []string, b []string) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		if i >= len(a) {
+			return -1
+		}
+		if i >= len(b) {
+			return 1
+		}
+		if c := compareNatural(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+// compareNatural compares two strings, treating runs of digits as numbers (e.g. dev2 < dev10)
+func compareNatural(a string, b string) int {
+	for len(a) > 0 && len(b) > 0 {
+		aChunk, aNumeric := leadingChunk(a)
+		bChunk, bNumeric := leadingChunk(b)
+		a, b = a[len(aChunk):], b[len(bChunk):]
+
+		if aNumeric && bNumeric {
+			aNum, _ := strconv.ParseUint(aChunk, 10, 64)
+			bNum, _ := strconv.ParseUint(bChunk, 10, 64)
+			if aNum != bNum {
+				if aNum < bNum {
+					return -1
+				}
+				return 1
+			}
+			continue
+		}
+
+		if aChunk != bChunk {
+			if aChunk < bChunk {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// leadingChunk returns the leading run of either digits or non-digits of s,
+// and whether it is made up of digits
+func leadingChunk(s string) (string, bool) {
+	numeric := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == numeric {
+		i++
+	}
+	return s[:i], numeric
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
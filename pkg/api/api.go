@@ -0,0 +1,202 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+// Package api contains the paths, query parameters, and headers shared by the releaser server and its
+// clients, the request and response types are versioned in its subpackages (e.g. api/v1). It only
+// depends on the standard library so that clients stay lightweight
+package api
+
+import (
+	"path"
+	"strings"
+)
+
+// V1Path is the prefix of version 1 of the JSON and metadata routes, which are also served without
+// a prefix for compatibility with deployed install scripts. Breaking changes to them are served
+// under a new prefix.
+const V1Path = "/v1"
+
+const (
+	LatestReleasePath     = "/"
+	PingPath              = "/ping"
+	HealthPath            = "/healthz"
+	ReadyPath             = "/readyz"
+	LatestReleaseNamePath = "/latest"
+	ListReleaseNamesPath  = "/releases"
+	APIPath               = "/api"
+	ListReleasesPath      = "/releases"
+	ChecksumPath          = "/checksum"
+	ChecksumsPath         = "/checksums"
+	SignaturePath         = "/signature"
+	WingetPath            = "/winget"
+	NixPath               = "/nix"
+	VersionsPath          = "/versions"
+	DownloadPath          = "/download"
+	ImagePath             = "/image"
+	AssetPath             = "/asset"
+	LicensePath           = "/license"
+	TelemetryPath         = "/telemetry"
+	InstallResultPath     = "/install-result"
+	WhyPath               = "/why"
+	AdminPath             = "/admin"
+	IntegrityPath         = "/integrity"
+	MetricsPath           = "/metrics"
+	MaintenancePath       = "/maintenance"
+	StatusPath            = "/status"
+	PinPath               = "/pin"
+	HooksPath             = "/hooks"
+	SystemdPath           = "/systemd"
+	DockerPath            = "/docker"
+	ServerInfoPath        = "/server"
+	StatsPath             = "/stats"
+	SummaryPath           = "/summary"
+	UpdatePath            = "/update"
+	RefreshPath           = "/refresh"
+	EvictPath             = "/evict"
+	RolloutPath           = "/rollout"
+	OverviewPath          = "/overview"
+	DashboardPath         = "/dashboard"
+	EventsPath            = "/events"
+	PlatformsPath         = "/platforms"
+
+	// Analytics is the query parameter clients can set to false to opt out of analytics
+	Analytics = "analytics"
+
+	// Current is the query parameter clients use to report the release they are running when checking for updates
+	Current = "current"
+
+	// Order is the query parameter clients use to select the order releases are listed in, which is
+	// either OrderVersion (the default) or OrderDate
+	Order = "order"
+
+	// OrderVersion lists releases by version, newest version first
+	OrderVersion = "version"
+
+	// OrderDate lists releases by the time they were published, most recently published first
+	OrderDate = "date"
+
+	// Prefix is the query parameter clients use to only list releases whose name starts with it (e.g. v1.)
+	Prefix = "prefix"
+
+	// Prerelease is the query parameter clients can set to false to leave pre-releases (e.g. v1.2.3-pre1) out of listings
+	Prerelease = "prerelease"
+
+	// Limit is the query parameter clients use to list at most the given number of releases per page
+	Limit = "limit"
+
+	// Page is the query parameter clients use to select the page of a listing, starting at 1, when a limit is set
+	Page = "page"
+
+	// Source is the query parameter clients use to report where an installation was started from
+	// (e.g. docs or ci), it is only recorded if it is one of the configured install sources
+	Source = "source"
+
+	// InstallID is the query parameter the install script uses to pass the random ID of an installation,
+	// which its analytics events are attributed to
+	InstallID = "install_id"
+)
+
+const (
+	// ClientID is the query parameter clients can use to identify themselves with a stable ID
+	// during rollouts, the client IP is used otherwise
+	ClientID = "id"
+
+	// Cohort is the query parameter clients use to select the release configured for their cohort
+	Cohort = "cohort"
+
+	// CohortHeader is set to the cohort of the client if the release configured for it was served
+	CohortHeader = "X-Releaser-Cohort"
+
+	// RolloutPercentageHeader and RolloutPreviousReleaseHeader are set while a rollout is in progress
+	// to the percentage of clients served the latest release and the release served to the others
+	RolloutPercentageHeader      = "X-Releaser-Rollout-Percentage"
+	RolloutPreviousReleaseHeader = "X-Releaser-Rollout-Previous-Release"
+)
+
+const (
+	// SignatureFormat selects the format of the signature served by the signature routes (?format=minisign or ?format=cosign)
+	SignatureFormat = "format"
+
+	// SignatureFormatMinisign serves the minisign signature of an artifact, which is attached to its release as <artifact>.minisig
+	SignatureFormatMinisign = "minisign"
+
+	// SignatureFormatCosign serves the cosign signature of an artifact, which is attached to its release as <artifact>.sig
+	SignatureFormatCosign = "cosign"
+)
+
+const (
+	// RateLimitResetHeader is set by the rate limiter to the number of seconds until the
+	// rate limit window of the client ends
+	RateLimitResetHeader = "X-RateLimit-Reset"
+)
+
+const (
+	// ReprDigestHeader is set on artifact responses to the sha-256 digest of the artifact as defined
+	// by RFC 9530 (e.g. sha-256=:<base64>:), DigestHeader carries the same digest in the format of
+	// RFC 3230 (e.g. sha-256=<base64>) for clients that predate it
+	ReprDigestHeader = "Repr-Digest"
+	DigestHeader     = "Digest"
+)
+
+// ReservedNames are the first path segments of the fixed routes. In multi-binary mode the routes of a binary
+// are served under its name (e.g. /toolctl/v1.0.0), so a binary with one of these names would be shadowed.
+var ReservedNames = []string{
+	strings.TrimPrefix(V1Path, "/"),
+	strings.TrimPrefix(PingPath, "/"),
+	strings.TrimPrefix(HealthPath, "/"),
+	strings.TrimPrefix(ReadyPath, "/"),
+	strings.TrimPrefix(LatestReleaseNamePath, "/"),
+	strings.TrimPrefix(ListReleaseNamesPath, "/"),
+	strings.TrimPrefix(APIPath, "/"),
+	strings.TrimPrefix(ChecksumPath, "/"),
+	strings.TrimPrefix(ChecksumsPath, "/"),
+	strings.TrimPrefix(SignaturePath, "/"),
+	strings.TrimPrefix(WingetPath, "/"),
+	strings.TrimPrefix(NixPath, "/"),
+	strings.TrimPrefix(VersionsPath, "/"),
+	strings.TrimPrefix(DownloadPath, "/"),
+	strings.TrimPrefix(ImagePath, "/"),
+	strings.TrimPrefix(AssetPath, "/"),
+	strings.TrimPrefix(LicensePath, "/"),
+	strings.TrimPrefix(TelemetryPath, "/"),
+	strings.TrimPrefix(WhyPath, "/"),
+	strings.TrimPrefix(AdminPath, "/"),
+	strings.TrimPrefix(HooksPath, "/"),
+	strings.TrimPrefix(SystemdPath, "/"),
+	strings.TrimPrefix(DockerPath, "/"),
+	strings.TrimPrefix(StatsPath, "/"),
+	strings.TrimPrefix(EventsPath, "/"),
+	strings.TrimPrefix(PlatformsPath, "/"),
+}
+
+// IsReservedName returns true if the given binary name is the first path segment of a fixed route
+func IsReservedName(name string) bool {
+	for _, reserved := range ReservedNames {
+		if strings.EqualFold(name, reserved) {
+			return true
+		}
+	}
+	return false
+}
+
+// JoinPaths joins the given path segments into an absolute URL path
+func JoinPaths(s ...string) string {
+	ret := path.Join(s...)
+	if !strings.HasPrefix(ret, "/") {
+		return "/" + ret
+	}
+	return ret
+}
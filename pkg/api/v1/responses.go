@@ -0,0 +1,211 @@
+/*
+	Copyright 2021 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package v1
+
+// ListReleaseNamesResponse lists the names of the requested releases in the requested order, Releases
+// lists the same releases in the same order with the Unix time they were published at. Total is the
+// number of releases matching the filters of the request across all pages.
+type ListReleaseNamesResponse struct {
+	ReleaseNames []string       `json:"release_names"`
+	Releases     []*ReleaseName `json:"releases"`
+	Total        int            `json:"total"`
+}
+
+// ReleaseName is the name of a release and the Unix time it was published at,
+// which is omitted if the provider does not report it
+type ReleaseName struct {
+	Name        string `json:"name"`
+	PublishedAt int64  `json:"published_at,omitempty"`
+}
+
+type Release struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Latest      bool     `json:"latest"`
+	PublishedAt int64    `json:"published_at,omitempty"`
+	Binaries    []string `json:"binaries,omitempty"`
+	Platforms   []string `json:"platforms"`
+}
+
+// ListReleasesResponse lists the requested releases, Total is the number of releases
+// matching the filters of the request across all pages
+type ListReleasesResponse struct {
+	Releases []*Release `json:"releases"`
+	Total    int        `json:"total"`
+}
+
+// ReleaseArtifact describes a single artifact of a release, the binary name is only set in multi-binary mode
+type ReleaseArtifact struct {
+	Binary   string `json:"binary,omitempty"`
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	Name     string `json:"name"`
+	Size     int    `json:"size"`
+	Checksum string `json:"checksum,omitempty"`
+	URL      string `json:"url"`
+}
+
+// ChecksumResponse is the checksum of a single artifact, Value is the hex encoded digest computed with
+// Algorithm over the artifact named ArtifactName, which is Size bytes long
+type ChecksumResponse struct {
+	Algorithm    string `json:"algorithm"`
+	Value        string `json:"value"`
+	ArtifactName string `json:"artifact_name"`
+	Size         int    `json:"size"`
+}
+
+// ReleaseMetadata describes a release and each of its artifacts
+type ReleaseMetadata struct {
+	Name        string             `json:"name"`
+	Title       string             `json:"title"`
+	Latest      bool               `json:"latest"`
+	PublishedAt int64              `json:"published_at,omitempty"`
+	Platforms   []string           `json:"platforms"`
+	Artifacts   []*ReleaseArtifact `json:"artifacts"`
+}
+
+type NixSource struct {
+	URL  string `json:"url"`
+	Hash string `json:"hash"`
+}
+
+type NixSourcesResponse struct {
+	Version string                `json:"version"`
+	Sources map[string]*NixSource `json:"sources"`
+}
+
+type MaintenanceResponse struct {
+	Enabled bool `json:"enabled"`
+}
+
+type HealthResponse struct {
+	Ready             bool   `json:"ready"`
+	Stale             bool   `json:"stale"`
+	LastUpdate        int64  `json:"last_update,omitempty"`
+	Error             string `json:"error,omitempty"`
+	LatestReleaseName string `json:"latest_release_name,omitempty"`
+}
+
+// StatusResponse describes the release being served and the state of the cache for uptime checkers and
+// status pages. Status is one of StatusOK, StatusDegraded, or StatusDown, CacheAge is the number of seconds
+// since the last successful update, and ProviderReachable is false if the last update failed.
+type StatusResponse struct {
+	Status                    string           `json:"status"`
+	LatestReleaseName         string           `json:"latest_release_name"`
+	UpstreamLatestReleaseName string           `json:"upstream_latest_release_name"`
+	PinnedReleaseName         string           `json:"pinned_release_name,omitempty"`
+	Maintenance               bool             `json:"maintenance"`
+	Stale                     bool             `json:"stale"`
+	LastUpdate                int64            `json:"last_update,omitempty"`
+	CacheAge                  int64            `json:"cache_age,omitempty"`
+	Provider                  string           `json:"provider"`
+	ProviderReachable         bool             `json:"provider_reachable"`
+	Releases                  int              `json:"releases"`
+	Artifacts                 int              `json:"artifacts"`
+	LatestReleaseArtifacts    int              `json:"latest_release_artifacts"`
+	Rollout                   *RolloutResponse `json:"rollout,omitempty"`
+}
+
+type RolloutResponse struct {
+	PreviousReleaseName string `json:"previous_release_name"`
+	Percentage          int    `json:"percentage"`
+}
+
+// ReleaseEvent is the data of the EventLatestRelease event, PreviousReleaseName is the latest release
+// the subscriber was told about before and is empty in the first event it receives
+type ReleaseEvent struct {
+	Name                string           `json:"name"`
+	PreviousReleaseName string           `json:"previous_release_name,omitempty"`
+	PublishedAt         int64            `json:"published_at,omitempty"`
+	Rollout             *RolloutResponse `json:"rollout,omitempty"`
+}
+
+type ServerInfoResponse struct {
+	Version    string             `json:"version"`
+	GitCommit  string             `json:"git_commit"`
+	GoVersion  string             `json:"go_version"`
+	Platform   string             `json:"platform"`
+	BuildDate  string             `json:"build_date"`
+	Uptime     int64              `json:"uptime"`
+	Providers  []string           `json:"providers"`
+	Repository string             `json:"repository,omitempty"`
+	Cache      *CacheInfoResponse `json:"cache"`
+}
+
+type CacheInfoResponse struct {
+	Releases          int    `json:"releases"`
+	LatestReleaseName string `json:"latest_release_name"`
+	LastUpdate        int64  `json:"last_update,omitempty"`
+	Stale             bool   `json:"stale"`
+	Failures          int    `json:"failures"`
+	IntegrityIssues   int    `json:"integrity_issues"`
+}
+
+// OverviewResponse is the state of the releaser shown by the admin dashboard, Installs are
+// the installs per version and day over the last 30 days and are only set if stats are enabled
+type OverviewResponse struct {
+	Status       *StatusResponse        `json:"status"`
+	Cache        *CacheInfoResponse     `json:"cache"`
+	Releases     []*OverviewRelease     `json:"releases"`
+	StatsEnabled bool                   `json:"stats_enabled"`
+	Installs     []*StatsRollupResponse `json:"installs,omitempty"`
+}
+
+type OverviewRelease struct {
+	Name      string `json:"name"`
+	Title     string `json:"title,omitempty"`
+	Latest    bool   `json:"latest"`
+	Pinned    bool   `json:"pinned"`
+	Artifacts int    `json:"artifacts"`
+}
+
+type StatsSummaryResponse struct {
+	Period   string                 `json:"period"`
+	Interval string                 `json:"interval"`
+	Since    string                 `json:"since"`
+	Installs []*StatsRollupResponse `json:"installs"`
+}
+
+type StatsRollupResponse struct {
+	Start    string `json:"start"`
+	Version  string `json:"version"`
+	Platform string `json:"platform"`
+	Source   string `json:"source,omitempty"`
+	Installs int    `json:"installs"`
+}
+
+// ErrorResponse is the envelope errors are returned in by the JSON routes, RetryAfter is
+// the number of seconds to wait before retrying and is only set when a client is rate
+// limited or the provider is throttled (it is also sent as the Retry-After header).
+// Parameter is the name of the invalid route parameter of a 400.
+type ErrorResponse struct {
+	Error      string `json:"error"`
+	RetryAfter int    `json:"retry_after,omitempty"`
+	Parameter  string `json:"parameter,omitempty"`
+}
+
+// UpdateInfo describes the release a client should be running, UpdateAvailable is set if it
+// differs from the current release the client reported. Cohort, RolloutPercentage, and
+// PreviousReleaseName are only set if the latest release was selected by cohort or rollout.
+type UpdateInfo struct {
+	LatestReleaseName   string `json:"latest_release_name"`
+	CurrentReleaseName  string `json:"current_release_name,omitempty"`
+	UpdateAvailable     bool   `json:"update_available"`
+	Cohort              string `json:"cohort,omitempty"`
+	RolloutPercentage   int    `json:"rollout_percentage,omitempty"`
+	PreviousReleaseName string `json:"previous_release_name,omitempty"`
+}
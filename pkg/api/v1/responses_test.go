@@ -0,0 +1,222 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// goldenResponses are the responses whose JSON encoding is compared against testdata/<name>.json, they
+// set every field so that renaming or removing one, which breaks older clients, fails the test
+var goldenResponses = map[string]interface{}{
+	"list_release_names": &ListReleaseNamesResponse{
+		ReleaseNames: []string{"v1.2.0", "v1.1.0"},
+		Releases: []*ReleaseName{
+			{Name: "v1.2.0", PublishedAt: 1700000000},
+			{Name: "v1.1.0"},
+		},
+		Total: 2,
+	},
+	"list_releases": &ListReleasesResponse{
+		Releases: []*Release{
+			{
+				Name:        "v1.2.0",
+				Title:       "Release v1.2.0",
+				Latest:      true,
+				PublishedAt: 1700000000,
+				Binaries:    []string{"cli", "daemon"},
+				Platforms:   []string{"darwin/arm64", "linux/amd64"},
+			},
+		},
+		Total: 1,
+	},
+	"checksum": &ChecksumResponse{
+		Algorithm:    ChecksumAlgorithmSHA256,
+		Value:        "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+		ArtifactName: "cli_v1.2.0_linux_amd64.tar.gz",
+		Size:         1024,
+	},
+	"release_metadata": &ReleaseMetadata{
+		Name:        "v1.2.0",
+		Title:       "Release v1.2.0",
+		Latest:      true,
+		PublishedAt: 1700000000,
+		Platforms:   []string{"linux/amd64"},
+		Artifacts: []*ReleaseArtifact{
+			{
+				Binary:   "cli",
+				OS:       "linux",
+				Arch:     "amd64",
+				Name:     "cli_v1.2.0_linux_amd64.tar.gz",
+				Size:     1024,
+				Checksum: "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+				URL:      "https://example.com/cli_v1.2.0_linux_amd64.tar.gz",
+			},
+		},
+	},
+	"nix_sources": &NixSourcesResponse{
+		Version: "v1.2.0",
+		Sources: map[string]*NixSource{
+			"x86_64-linux": {
+				URL:  "https://example.com/cli_v1.2.0_linux_amd64.tar.gz",
+				Hash: "sha256-LCa0a2j/xo/5m0U8HTBBNBNCLXBkg7+g+YpeiGJm564=",
+			},
+		},
+	},
+	"maintenance": &MaintenanceResponse{
+		Enabled: true,
+	},
+	"health": &HealthResponse{
+		Ready:             true,
+		Stale:             true,
+		LastUpdate:        1700000000,
+		Error:             "rate limited",
+		LatestReleaseName: "v1.2.0",
+	},
+	"status": &StatusResponse{
+		Status:                    StatusDegraded,
+		LatestReleaseName:         "v1.2.0",
+		UpstreamLatestReleaseName: "v1.3.0",
+		PinnedReleaseName:         "v1.2.0",
+		Maintenance:               true,
+		Stale:                     true,
+		LastUpdate:                1700000000,
+		CacheAge:                  60,
+		Provider:                  "github",
+		ProviderReachable:         true,
+		Releases:                  2,
+		Artifacts:                 8,
+		LatestReleaseArtifacts:    4,
+		Rollout: &RolloutResponse{
+			PreviousReleaseName: "v1.1.0",
+			Percentage:          25,
+		},
+	},
+	"release_event": &ReleaseEvent{
+		Name:                "v1.2.0",
+		PreviousReleaseName: "v1.1.0",
+		PublishedAt:         1700000000,
+		Rollout: &RolloutResponse{
+			PreviousReleaseName: "v1.1.0",
+			Percentage:          25,
+		},
+	},
+	"server_info": &ServerInfoResponse{
+		Version:    "v0.1.0",
+		GitCommit:  "abcdef0",
+		GoVersion:  "go1.20",
+		Platform:   "linux/amd64",
+		BuildDate:  "2023-11-14",
+		Uptime:     3600,
+		Providers:  []string{"github", "oci"},
+		Repository: "loopholelabs/releaser",
+		Cache: &CacheInfoResponse{
+			Releases:          2,
+			LatestReleaseName: "v1.2.0",
+			LastUpdate:        1700000000,
+			Stale:             true,
+			Failures:          1,
+			IntegrityIssues:   1,
+		},
+	},
+	"overview": &OverviewResponse{
+		Status: &StatusResponse{
+			Status:            StatusOK,
+			LatestReleaseName: "v1.2.0",
+			Provider:          "github",
+			ProviderReachable: true,
+		},
+		Cache: &CacheInfoResponse{
+			Releases:          2,
+			LatestReleaseName: "v1.2.0",
+		},
+		Releases: []*OverviewRelease{
+			{
+				Name:      "v1.2.0",
+				Title:     "Release v1.2.0",
+				Latest:    true,
+				Pinned:    true,
+				Artifacts: 4,
+			},
+		},
+		StatsEnabled: true,
+		Installs: []*StatsRollupResponse{
+			{Start: "2023-11-14", Version: "v1.2.0", Installs: 42},
+		},
+	},
+	"stats_summary": &StatsSummaryResponse{
+		Period:   "30d",
+		Interval: "day",
+		Since:    "2023-10-15",
+		Installs: []*StatsRollupResponse{
+			{
+				Start:    "2023-11-14",
+				Version:  "v1.2.0",
+				Platform: "linux/amd64",
+				Source:   "docs",
+				Installs: 42,
+			},
+		},
+	},
+	"error": &ErrorResponse{
+		Error:      "invalid release name",
+		RetryAfter: 60,
+		Parameter:  "release_name",
+	},
+	"update_info": &UpdateInfo{
+		LatestReleaseName:   "v1.2.0",
+		CurrentReleaseName:  "v1.1.0",
+		UpdateAvailable:     true,
+		Cohort:              "canary",
+		RolloutPercentage:   25,
+		PreviousReleaseName: "v1.1.0",
+	},
+}
+
+func TestResponsesGolden(t *testing.T) {
+	for name, response := range goldenResponses {
+		t.Run(name, func(t *testing.T) {
+			actual, err := json.MarshalIndent(response, "", "  ")
+			if err != nil {
+				t.Fatalf("unable to marshal response: %s", err)
+			}
+			actual = append(actual, '\n')
+
+			path := filepath.Join("testdata", name+".json")
+			if *update {
+				if err = os.WriteFile(path, actual, 0644); err != nil {
+					t.Fatalf("unable to update golden file: %s", err)
+				}
+			}
+
+			expected, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("unable to read golden file: %s", err)
+			}
+			if !bytes.Equal(actual, expected) {
+				t.Errorf("response does not match %s, run go test -update if the change is backwards compatible\ngot:\n%s\nwant:\n%s", path, actual, expected)
+			}
+		})
+	}
+}
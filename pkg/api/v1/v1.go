@@ -0,0 +1,39 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+// Package v1 contains the request and response types of version 1 of the releaser API,
+// fields are only ever added to them so that older clients keep working with newer servers
+package v1
+
+// Version is the version of the API the types in this package belong to
+const Version = "v1"
+
+const (
+	// StatusOK means that the cache is fresh and the provider was reachable during the last update
+	StatusOK = "ok"
+
+	// StatusDegraded means that the last known-good cache is being served because updates are failing
+	StatusDegraded = "degraded"
+
+	// StatusDown means that nothing can be served because the cache has never been updated
+	StatusDown = "down"
+)
+
+// ChecksumAlgorithmSHA256 is the algorithm of the checksums in the checksums.txt files of releases
+const ChecksumAlgorithmSHA256 = "sha256"
+
+// EventLatestRelease is the name of the event sent on the event stream when the latest release changes
+const EventLatestRelease = "latest_release"
@@ -16,10 +16,124 @@
 
 package cache
 
-import "fmt"
+import (
+	"github.com/loopholelabs/releaser/pkg/provider"
+	"strings"
+	"time"
+)
 
-type artifactKey string
+var (
+	// artifactSuffixes are the archive formats that GoReleaser produces
+	artifactSuffixes = []string{".tar.gz", ".zip"}
+)
 
-func toArtifactKey(releaseName string, os string, arch string) artifactKey {
-	return artifactKey(fmt.Sprintf("%s-%s-%s", releaseName, os, arch))
+// artifactKey identifies an artifact, it is a struct rather than a formatted
+// string so that lookups on the hot path do not allocate
+type artifactKey struct {
+	binaryName  string
+	releaseName string
+	os          string
+	arch        string
+}
+
+func (k artifactKey) String() string {
+	if k.binaryName == "" {
+		return k.releaseName + "-" + k.os + "-" + k.arch
+	}
+	return k.binaryName + "/" + k.releaseName + "-" + k.os + "-" + k.arch
+}
+
+// Artifact describes a single artifact of a release
+type Artifact struct {
+	// BinaryName is the binary this artifact contains, it is empty unless
+	// the server is running in multi-binary mode
+	BinaryName string
+
+	// OS is the operating system the artifact was built for
+	OS string
+
+	// Arch is the architecture the artifact was built for
+	Arch string
+
+	// Name is the name of the release asset
+	Name string
+
+	// Checksum is the sha256 checksum of the artifact, which is empty
+	// if the release has no matching entry in its checksums.txt
+	Checksum string
+
+	// Size is the size of the artifact in bytes
+	Size int
+
+	// URL is a public download URL for the artifact, it is empty if the
+	// artifact can only be downloaded through the provider
+	URL string
+
+	// UpdatedAt is when the artifact was uploaded, it is zero if the provider does not report it
+	UpdatedAt time.Time
+
+	asset *provider.Asset
+}
+
+// isArtifactName returns true if the given asset name is an archive produced by GoReleaser
+func isArtifactName(assetName string) bool {
+	for _, suffix := range artifactSuffixes {
+		if strings.HasSuffix(assetName, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isZipArtifactName returns true if the given asset name is a zip archive, which GoReleaser
+// produces for windows and winget requires, so zip archives are only served for windows
+func isZipArtifactName(assetName string) bool {
+	return strings.HasSuffix(assetName, ".zip")
+}
+
+// preferArtifact returns true if the candidate artifact should be served instead of the existing
+// artifact for the same binary, release, os, and arch, which is only the case for a zip archive
+// replacing a tar.gz archive so that the choice does not depend on the order of the release assets
+func preferArtifact(candidate string, existing string) bool {
+	return isZipArtifactName(candidate) && !isZipArtifactName(existing)
+}
+
+// toArtifactKey returns the key for the given artifact, the binaryName
+// is empty unless the server is running in multi-binary mode and the
+// releaseName is matched case-insensitively
+func toArtifactKey(binaryName string, releaseName string, os string, arch string) artifactKey {
+	return artifactKey{
+		binaryName:  binaryName,
+		releaseName: strings.ToLower(releaseName),
+		os:          os,
+		arch:        arch,
+	}
+}
+
+// parseArtifactName extracts the name, os, and arch from a GoReleaser artifact name of the
+// form <name>_<version>_<os>_<arch>.tar.gz (or .zip). If an asset prefix (see config.GetAssetPrefix)
+// is given and the artifact name starts with it, the prefix is the name, so that it may contain underscores.
+func parseArtifactName(artifactName string, assetPrefix string) (string, string, string, bool) {
+	trimmed := artifactName
+	for _, suffix := range artifactSuffixes {
+		trimmed = strings.TrimSuffix(trimmed, suffix)
+	}
+
+	name := ""
+	if assetPrefix != "" && len(trimmed) > len(assetPrefix) && strings.EqualFold(trimmed[:len(assetPrefix)], assetPrefix) {
+		name = trimmed[:len(assetPrefix)-1]
+		trimmed = trimmed[len(assetPrefix):]
+	} else {
+		var ok bool
+		name, trimmed, ok = strings.Cut(trimmed, "_")
+		if !ok {
+			return "", "", "", false
+		}
+	}
+
+	split := strings.Split(trimmed, "_")
+	if len(split) > 1 {
+		return name, split[1], strings.Join(split[2:], "_"), true
+	}
+	return "", "", "", false
 }
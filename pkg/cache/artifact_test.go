@@ -0,0 +1,56 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cache
+
+import (
+	"testing"
+
+	"github.com/loopholelabs/releaser/internal/config"
+)
+
+func TestParseArtifactName(t *testing.T) {
+	tests := []struct {
+		artifactName string
+		assetPrefix  string
+		name         string
+		os           string
+		arch         string
+		ok           bool
+	}{
+		{artifactName: "tool_1.0.0_linux_amd64.tar.gz", name: "tool", os: "linux", arch: "amd64", ok: true},
+		{artifactName: "tool_1.0.0_linux_arm_7.tar.gz", name: "tool", os: "linux", arch: "arm_7", ok: true},
+		{artifactName: "tool_1.0.0_windows_amd64.zip", name: "tool", os: "windows", arch: "amd64", ok: true},
+		{artifactName: "tool_1.0.0.tar.gz"},
+		{artifactName: "tool_1.0.0_linux_amd64.tar.gz", assetPrefix: "tool", name: "tool", os: "linux", arch: "amd64", ok: true},
+		{artifactName: "my_tool_1.0.0_linux_amd64.tar.gz", assetPrefix: "my_tool", name: "my_tool", os: "linux", arch: "amd64", ok: true},
+		{artifactName: "my_tool_1.0.0_darwin_arm64.tar.gz", assetPrefix: "my_tool_", name: "my_tool", os: "darwin", arch: "arm64", ok: true},
+		{artifactName: "My_Tool_1.0.0_linux_amd64.tar.gz", assetPrefix: "my_tool", name: "My_Tool", os: "linux", arch: "amd64", ok: true},
+		{artifactName: "my_tool_1.0.0.tar.gz", assetPrefix: "my_tool"},
+	}
+	for _, test := range tests {
+		t.Run(test.artifactName+"/"+test.assetPrefix, func(t *testing.T) {
+			c := &config.Config{AssetPrefix: test.assetPrefix}
+			if !c.MatchesAssetPrefix(test.artifactName) {
+				t.Fatalf("expected %s to match the asset prefix %q", test.artifactName, test.assetPrefix)
+			}
+			name, os, arch, ok := parseArtifactName(test.artifactName, c.GetAssetPrefix())
+			if ok != test.ok || name != test.name || os != test.os || arch != test.arch {
+				t.Fatalf("expected (%q, %q, %q, %t), got (%q, %q, %q, %t)", test.name, test.os, test.arch, test.ok, name, os, arch, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cache
+
+import (
+	"fmt"
+	"github.com/loopholelabs/releaser/pkg/provider"
+	"strings"
+	"sync/atomic"
+)
+
+// licenseNames are the names (without extension) of release assets that
+// contain the license or the notices of a release
+var licenseNames = []string{"license", "licence", "notice", "copying"}
+
+// licenseExtensions are the extensions a license or notice asset may have
+var licenseExtensions = []string{"", ".txt", ".md"}
+
+// signatureSuffixes are the suffixes of the minisign and cosign signatures attached to a release
+var signatureSuffixes = []string{".minisig", ".sig"}
+
+// maxSignatureSize limits the size of the signatures that are kept in memory
+const maxSignatureSize = 64 << 10
+
+// isSignatureName returns true if the given asset name is the name of a signature
+func isSignatureName(name string) bool {
+	for _, suffix := range signatureSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+type assetKey string
+
+// Verification is the result of verifying an asset against its checksum
+type Verification int32
+
+const (
+	// VerificationUnknown is the result of an asset that has not been downloaded in full yet
+	VerificationUnknown Verification = iota
+
+	// VerificationPassed is the result of an asset that matched its checksum
+	VerificationPassed
+
+	// VerificationFailed is the result of an asset that did not match its checksum
+	VerificationFailed
+)
+
+// Asset describes a release asset that is not a binary artifact, such as
+// a license, a configuration template, or a source tarball
+type Asset struct {
+	// Name is the name of the release asset
+	Name string
+
+	// Checksum is the sha256 checksum of the asset, which is empty
+	// if the release has no matching entry in its checksums.txt
+	Checksum string
+
+	// Size is the size of the asset in bytes
+	Size int
+
+	// URL is a public download URL for the asset, it is empty if the
+	// asset can only be downloaded through the provider
+	URL string
+
+	asset *provider.Asset
+
+	// verification is the Verification of the asset, it is carried over to the next
+	// snapshot unless the asset was replaced
+	verification atomic.Int32
+}
+
+// Verification returns the result of verifying the asset against its checksum
+func (a *Asset) Verification() Verification {
+	return Verification(a.verification.Load())
+}
+
+// SetVerification records the result of verifying the asset against its checksum, so that an asset
+// that did not match it is not downloaded again until it is replaced
+func (a *Asset) SetVerification(verification Verification) {
+	a.verification.Store(int32(verification))
+}
+
+func toAssetKey(releaseName string, assetName string) assetKey {
+	return assetKey(fmt.Sprintf("%s/%s", strings.ToLower(releaseName), assetName))
+}
+
+// licenseDownload is the download of the license and notice files of a release, requests
+// for the license wait for done to be closed
+type licenseDownload struct {
+	// revision identifies the license and notice files that were downloaded, see licenseRevision
+	revision string
+
+	done    chan struct{}
+	license []byte
+	err     error
+}
+
+// licenseRevision identifies the contents of the given license and notice files, it changes
+// when any of them is added, removed, or replaced
+func licenseRevision(licenseAssets []*Asset) string {
+	revisions := make([]string, 0, len(licenseAssets))
+	for _, asset := range licenseAssets {
+		revisions = append(revisions, asset.Name+"="+asset.asset.Revision())
+	}
+	return strings.Join(revisions, ",")
+}
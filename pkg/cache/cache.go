@@ -20,54 +20,111 @@ import (
 	"bufio"
 	"context"
 	"errors"
-	"github.com/google/go-github/v55/github"
+	"fmt"
 	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/releaser/internal/audit"
 	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/internal/httpclient"
+	"github.com/loopholelabs/releaser/internal/kubernetes"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/provider"
 	"io"
 	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// releaseNameRegex checks for anything but "v" + numerics, for example v0.1.7-dev12, v0.1.7-pre10, etc
+var releaseNameRegex = regexp.MustCompile(`^[^a-zA-Z]*[vV][^a-zA-Z]*$`)
+
+var (
+	ErrReleaseNotFound = errors.New("release not found")
+)
+
 type Cache struct {
-	mu sync.RWMutex
+	// snapshot is the state of the releases found during the last update, it is replaced
+	// atomically as a whole so readers never observe a partially applied update
+	snapshot atomic.Pointer[snapshot]
+
+	// licenses stores the downloads of the license and notice files of each release, they are
+	// fetched lazily since they are only needed by commercial distributions. licensesMu is only
+	// held to look up and replace downloads, never while downloading.
+	licensesMu sync.Mutex
+	licenses   map[string]*licenseDownload
+
+	// alertedIssues stores the integrity issues an alert was sent for, it is
+	// only accessed while updating
+	alertedIssues map[string]struct{}
+
+	// alertClient posts integrity alerts to the alert webhook, it is nil if no webhook is configured
+	alertClient *http.Client
+
+	// updateMu serializes updates, which run both periodically and when a release is pinned
+	updateMu sync.Mutex
 
-	// releases stores whether a release exists, given its name
-	releaseNames map[string]struct{}
+	// evictLatest makes the next update download the artifacts of the latest release again,
+	// even if it has not changed. It is guarded by updateMu.
+	evictLatest bool
 
-	// checksums stores the checksum of a given artifact across
-	// all releases
-	checksums map[artifactKey]string
+	// statusMu guards the outcome of the last updates, which is reported by GetStatus
+	statusMu   sync.RWMutex
+	lastUpdate time.Time
+	lastError  error
+	failures   int
 
-	// releaseArtifactNames stores the artifact names across all releases
-	releaseArtifactNames map[artifactKey]string
+	// latestChanged is closed and replaced whenever the latest release changes, see LatestReleaseChanged
+	latestChangedMu sync.Mutex
+	latestChanged   chan struct{}
 
-	// latestRelease is the name of the latest release
-	latestReleaseName string
+	// pinnedReleaseName overrides the latest release reported by the provider if it is set
+	pinnedMu          sync.RWMutex
+	pinnedReleaseName string
 
-	// latestReleaseArtifacts stores the artifacts for the latest release
-	latestReleaseArtifacts map[artifactKey][]byte
+	// events reports failed updates as events of the pod in Kubernetes mode, it is nil otherwise
+	events *kubernetes.Client
+
+	// audit records the rollouts and evictions of the cache, it is nil if no audit log is configured
+	audit *audit.Log
 
 	stop chan struct{}
 	wg   sync.WaitGroup
 
-	helper *cmdutils.Helper[*config.Config]
-	client *github.Client
+	helper   *cmdutils.Helper[*config.Config]
+	provider provider.Provider
 }
 
-func New(client *github.Client, helper *cmdutils.Helper[*config.Config]) (*Cache, error) {
+func New(provider provider.Provider, helper *cmdutils.Helper[*config.Config], auditLog *audit.Log) (*Cache, error) {
 	c := &Cache{
-		releaseNames:         make(map[string]struct{}),
-		checksums:            make(map[artifactKey]string),
-		releaseArtifactNames: make(map[artifactKey]string),
+		licenses:          make(map[string]*licenseDownload),
+		latestChanged:     make(chan struct{}),
+		pinnedReleaseName: helper.Config.PinnedRelease,
+		audit:             auditLog,
+
+		stop:     make(chan struct{}, 1),
+		helper:   helper,
+		provider: provider,
+	}
+	c.snapshot.Store(newSnapshot())
 
-		latestReleaseArtifacts: make(map[artifactKey][]byte),
+	if helper.Config.AlertWebhookURL != "" {
+		var err error
+		c.alertClient, err = httpclient.New(helper.Config.GetAPIOptions())
+		if err != nil {
+			return nil, err
+		}
+	}
 
-		stop:   make(chan struct{}, 1),
-		helper: helper,
-		client: client,
+	if helper.Config.Kubernetes {
+		var err error
+		c.events, err = kubernetes.InCluster()
+		if err != nil {
+			helper.Printer.Printf("warning: refresh failures will not be reported as kubernetes events: %s\n", err)
+		}
 	}
 
 	return c, c.init()
@@ -75,75 +132,371 @@ func New(client *github.Client, helper *cmdutils.Helper[*config.Config]) (*Cache
 
 // GetLatestReleaseName returns the name of the latest release
 func (c *Cache) GetLatestReleaseName() string {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	return c.latestReleaseName
+	return c.snapshot.Load().latestReleaseName
+}
+
+// LatestReleaseChanged returns a channel that is closed the next time the latest release changes
+func (c *Cache) LatestReleaseChanged() <-chan struct{} {
+	c.latestChangedMu.Lock()
+	defer c.latestChangedMu.Unlock()
+	return c.latestChanged
+}
+
+// notifyLatestReleaseChanged wakes up everyone waiting for the latest release to change
+func (c *Cache) notifyLatestReleaseChanged() {
+	c.latestChangedMu.Lock()
+	close(c.latestChanged)
+	c.latestChanged = make(chan struct{})
+	c.latestChangedMu.Unlock()
+}
+
+// GetUpstreamLatestReleaseName returns the name of the latest release reported by the provider,
+// which differs from GetLatestReleaseName while another release is pinned
+func (c *Cache) GetUpstreamLatestReleaseName() string {
+	return c.snapshot.Load().upstreamLatestReleaseName
+}
+
+// GetPinnedReleaseName returns the name of the release pinned as the latest release,
+// or an empty string if no release is pinned
+func (c *Cache) GetPinnedReleaseName() string {
+	c.pinnedMu.RLock()
+	defer c.pinnedMu.RUnlock()
+	return c.pinnedReleaseName
+}
+
+// PinRelease pins the given release as the latest release regardless of the releases reported by the
+// provider, an empty release name removes the pin. The cache is updated immediately to apply it.
+func (c *Cache) PinRelease(releaseName string) error {
+	if releaseName != "" {
+		canonicalName, ok := c.CanonicalReleaseName(releaseName)
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrReleaseNotFound, releaseName)
+		}
+		releaseName = canonicalName
+	}
+	c.setPinnedReleaseName(releaseName)
+	return c.update()
+}
+
+// setPinnedReleaseName sets the release pinned as the latest release
+func (c *Cache) setPinnedReleaseName(releaseName string) {
+	c.pinnedMu.Lock()
+	c.pinnedReleaseName = releaseName
+	c.pinnedMu.Unlock()
 }
 
-// GetAllReleaseNames returns an array of all the release names
+// GetAllReleaseNames returns an array of the canonical names of all the releases, newest version first
 func (c *Cache) GetAllReleaseNames() []string {
-	c.mu.RLock()
-	releaseNames := make([]string, 0, len(c.releaseNames))
-	for releaseName := range c.releaseNames {
+	snapshot := c.snapshot.Load()
+	releaseNames := make([]string, 0, len(snapshot.releaseNames))
+	for _, releaseName := range snapshot.releaseNames {
 		releaseNames = append(releaseNames, releaseName)
 	}
-	c.mu.RUnlock()
+	sort.Slice(releaseNames, func(i, j int) bool {
+		if order := utils.CompareVersions(releaseNames[i], releaseNames[j]); order != 0 {
+			return order > 0
+		}
+		return releaseNames[i] < releaseNames[j]
+	})
 	return releaseNames
 }
 
-// ReleaseNameExists returns true if the given release name exists
+// Generation returns the number of updates of the cache, anything rendered from its
+// releases should be discarded once it changes
+func (c *Cache) Generation() uint64 {
+	return c.snapshot.Load().generation
+}
+
+// ReleaseNameExists returns true if the given release name exists, ignoring case
 func (c *Cache) ReleaseNameExists(releaseName string) bool {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	_, ok := c.releaseNames[releaseName]
+	_, ok := c.CanonicalReleaseName(releaseName)
 	return ok
 }
 
-// GetChecksum returns the checksum for the given version, os, and arch
+// CanonicalReleaseName returns the name of the given release as reported by the provider,
+// matching the given release name case-insensitively
+func (c *Cache) CanonicalReleaseName(releaseName string) (string, bool) {
+	canonicalName, ok := c.snapshot.Load().releaseNames[strings.ToLower(releaseName)]
+	return canonicalName, ok
+}
+
+// GetReleaseTitle returns the display title of the given release, falling back to its name
+func (c *Cache) GetReleaseTitle(releaseName string) string {
+	if title, ok := c.snapshot.Load().releaseTitles[strings.ToLower(releaseName)]; ok && title != "" {
+		return title
+	}
+	return releaseName
+}
+
+// GetReleasePublishedAt returns when the given release was published, it is zero
+// if the release does not exist or its provider does not report it
+func (c *Cache) GetReleasePublishedAt(releaseName string) time.Time {
+	return c.snapshot.Load().releasePublishedAt[strings.ToLower(releaseName)]
+}
+
+// GetAllBinaryNames returns an array of all the binary names found when running in multi-binary mode
+func (c *Cache) GetAllBinaryNames() []string {
+	snapshot := c.snapshot.Load()
+	binaryNames := make([]string, 0, len(snapshot.binaryNames))
+	for binaryName := range snapshot.binaryNames {
+		binaryNames = append(binaryNames, binaryName)
+	}
+	sort.Strings(binaryNames)
+	return binaryNames
+}
+
+// BinaryNameExists returns true if the given binary name exists
+func (c *Cache) BinaryNameExists(binaryName string) bool {
+	_, ok := c.snapshot.Load().binaryNames[binaryName]
+	return ok
+}
+
+// GetChecksum returns the checksum for the given binary, version, os, and arch
 //
 // It will return an empty string if the checksum does not exist
-func (c *Cache) GetChecksum(releaseName string, os string, arch string) string {
-	if !c.ReleaseNameExists(releaseName) {
+func (c *Cache) GetChecksum(binaryName string, releaseName string, os string, arch string) string {
+	snapshot := c.snapshot.Load()
+	if _, ok := snapshot.releaseNames[strings.ToLower(releaseName)]; !ok {
 		return ""
 	}
 
-	key := toArtifactKey(releaseName, os, arch)
-
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	if checksum, ok := c.checksums[key]; !ok {
+	key := toArtifactKey(binaryName, releaseName, os, arch)
+	if checksum, ok := snapshot.checksums[key]; !ok {
 		return ""
 	} else {
 		return checksum
 	}
 }
 
-func (c *Cache) GetLatestReleaseArtifact(os string, arch string) []byte {
-	key := toArtifactKey(c.latestReleaseName, os, arch)
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	if artifactBytes, ok := c.latestReleaseArtifacts[key]; !ok {
+// GetReleaseChecksums returns the entries of the checksums.txt of the given release, mapping
+// the asset names to their checksums
+//
+// It will return nil if the release does not exist or has no checksums.txt, the returned
+// map is shared and must not be modified
+func (c *Cache) GetReleaseChecksums(releaseName string) map[string]string {
+	return c.snapshot.Load().releaseChecksums[strings.ToLower(releaseName)]
+}
+
+// GetLatestReleaseArtifact returns the contents of the cached artifact of the given release if it is the latest release
+//
+// It will return nil if the release is not the latest release, or if the artifact does not exist or is stored on disk,
+// see OpenLatestReleaseArtifact
+func (c *Cache) GetLatestReleaseArtifact(binaryName string, releaseName string, os string, arch string) []byte {
+	snapshot := c.snapshot.Load()
+	if snapshot.latestReleaseName != releaseName {
+		return nil
+	}
+	key := toArtifactKey(binaryName, releaseName, os, arch)
+	if artifactBytes, ok := snapshot.latestReleaseArtifacts[key]; !ok {
 		return nil
 	} else {
 		return artifactBytes
 	}
 }
 
-func (c *Cache) GetReleaseArtifactName(releaseName string, os string, arch string) string {
-	if !c.ReleaseNameExists(releaseName) {
+func (c *Cache) GetReleaseArtifactName(binaryName string, releaseName string, os string, arch string) string {
+	artifact := c.GetReleaseArtifact(binaryName, releaseName, os, arch)
+	if artifact == nil {
 		return ""
 	}
-	key := toArtifactKey(releaseName, os, arch)
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	if artifactName, ok := c.releaseArtifactNames[key]; !ok {
-		return ""
-	} else {
-		return artifactName
+	return artifact.Name
+}
+
+// GetReleaseArtifact returns the artifact for the given binary, version, os, and arch
+//
+// It will return nil if the artifact does not exist
+func (c *Cache) GetReleaseArtifact(binaryName string, releaseName string, os string, arch string) *Artifact {
+	snapshot := c.snapshot.Load()
+	if _, ok := snapshot.releaseNames[strings.ToLower(releaseName)]; !ok {
+		return nil
+	}
+	return snapshot.artifacts[toArtifactKey(binaryName, releaseName, os, arch)]
+}
+
+// DownloadArtifact returns a reader for the contents of the given artifact from the provider,
+// which must be closed by the caller
+func (c *Cache) DownloadArtifact(ctx context.Context, artifact *Artifact) (io.ReadCloser, error) {
+	return c.provider.DownloadAsset(ctx, artifact.asset)
+}
+
+// GetReleaseArtifacts returns the artifacts of the given release sorted by name
+//
+// The returned artifacts are shared and must not be modified
+func (c *Cache) GetReleaseArtifacts(releaseName string) []*Artifact {
+	return c.snapshot.Load().releaseArtifacts[strings.ToLower(releaseName)]
+}
+
+// GetReleaseAsset returns the non-artifact asset with the given name for the given release
+//
+// It will return nil if the asset does not exist
+func (c *Cache) GetReleaseAsset(releaseName string, assetName string) *Asset {
+	return c.snapshot.Load().assets[toAssetKey(releaseName, assetName)]
+}
+
+// DownloadAsset returns a reader for the contents of the given asset from the provider,
+// which must be closed by the caller
+func (c *Cache) DownloadAsset(ctx context.Context, asset *Asset) (io.ReadCloser, error) {
+	return c.provider.DownloadAsset(ctx, asset.asset)
+}
+
+// GetReleaseSignature returns the contents of the signature asset with the given name attached to the
+// given release, which are downloaded while updating the cache
+//
+// It will return nil if the release has no such signature or it could not be downloaded yet
+func (c *Cache) GetReleaseSignature(releaseName string, assetName string) []byte {
+	return c.snapshot.Load().signatures[toAssetKey(releaseName, assetName)]
+}
+
+// downloadSignature returns the contents of the given signature asset
+func (c *Cache) downloadSignature(ctx context.Context, asset *Asset) ([]byte, error) {
+	deadline, cancel := context.WithDeadline(ctx, time.Now().Add(time.Second*30))
+	defer cancel()
+	reader, err := c.provider.DownloadAsset(deadline, asset.asset)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	signature, err := io.ReadAll(io.LimitReader(reader, maxSignatureSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(signature) > maxSignatureSize {
+		return nil, fmt.Errorf("signature is larger than %d bytes", maxSignatureSize)
+	}
+	return signature, nil
+}
+
+// GetReleaseLicense returns the contents of the license and notice files (e.g. LICENSE and NOTICE)
+// attached to the given release, concatenated with licenses before notices. Concurrent requests
+// for the same release share a single download.
+//
+// It will return nil if the release has no license or notice files
+func (c *Cache) GetReleaseLicense(ctx context.Context, releaseName string) ([]byte, error) {
+	releaseName = strings.ToLower(releaseName)
+	licenseAssets := c.snapshot.Load().licenseAssets(releaseName)
+	if len(licenseAssets) == 0 {
+		return nil, nil
+	}
+	revision := licenseRevision(licenseAssets)
+
+	c.licensesMu.Lock()
+	download, ok := c.licenses[releaseName]
+	if ok && download.revision == revision {
+		c.licensesMu.Unlock()
+		select {
+		case <-download.done:
+			return download.license, download.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	download = &licenseDownload{
+		revision: revision,
+		done:     make(chan struct{}),
+	}
+	c.licenses[releaseName] = download
+	c.licensesMu.Unlock()
+
+	download.license, download.err = c.downloadLicense(ctx, licenseAssets)
+	close(download.done)
+	if download.err != nil {
+		// failed downloads are not kept, so that the next request tries again
+		c.licensesMu.Lock()
+		if c.licenses[releaseName] == download {
+			delete(c.licenses, releaseName)
+		}
+		c.licensesMu.Unlock()
+	}
+	return download.license, download.err
+}
+
+// HasReleaseLicense returns true if the given release has license or notice files
+func (c *Cache) HasReleaseLicense(releaseName string) bool {
+	return len(c.snapshot.Load().licenseAssets(strings.ToLower(releaseName))) > 0
+}
+
+// downloadLicense downloads the given license and notice files and concatenates them
+func (c *Cache) downloadLicense(ctx context.Context, licenseAssets []*Asset) ([]byte, error) {
+	var license []byte
+	for _, asset := range licenseAssets {
+		reader, err := c.DownloadAsset(ctx, asset)
+		if err != nil {
+			return nil, err
+		}
+		contents, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(license) > 0 {
+			license = append(license, '\n')
+		}
+		license = append(license, contents...)
+	}
+	return license, nil
+}
+
+// pruneLicenses drops the downloaded licenses of releases that no longer exist or whose
+// license or notice files were replaced in the given snapshot
+func (c *Cache) pruneLicenses(next *snapshot) {
+	c.licensesMu.Lock()
+	defer c.licensesMu.Unlock()
+	for releaseName, download := range c.licenses {
+		if licenseRevision(next.licenseAssets(releaseName)) != download.revision {
+			delete(c.licenses, releaseName)
+		}
+	}
+}
+
+// GetIntegrityIssues returns the mismatches between the checksums.txt files and the assets
+// of all releases found during the last update
+//
+// The returned slice is shared and must not be modified
+func (c *Cache) GetIntegrityIssues() []*IntegrityIssue {
+	return c.snapshot.Load().integrityIssues
+}
+
+// artifactBinaryName returns the binary name used to key an artifact with the given name,
+// which is always empty unless the server is running in multi-binary mode
+func (c *Cache) artifactBinaryName(name string) string {
+	if c.helper.Config.MultiBinary {
+		return strings.ToLower(name)
+	}
+	return ""
+}
+
+// withholdEmbargoed returns the given releases without the ones whose embargo has not ended yet
+func (c *Cache) withholdEmbargoed(releases []*provider.Release) []*provider.Release {
+	now := time.Now()
+	available := make([]*provider.Release, 0, len(releases))
+	for _, release := range releases {
+		if until, ok := c.helper.Config.GetEmbargo(release.Name); ok && now.Before(until) {
+			c.helper.Printer.Printf("withholding release %s until %s\n", release.Name, until.Format(time.RFC3339))
+			continue
+		}
+		available = append(available, release)
+	}
+	return available
+}
+
+// nextUpdate returns how long to wait before the next update, which is the given
+// interval unless an embargo ends before it
+func (c *Cache) nextUpdate(interval time.Duration) time.Duration {
+	if lift, ok := c.helper.Config.NextEmbargoLift(time.Now()); ok && time.Until(lift) < interval {
+		return time.Until(lift)
 	}
+	return interval
 }
 
 func (c *Cache) init() error {
+	err := c.initArtifactDirectory()
+	if err != nil {
+		return err
+	}
+
 	c.wg.Add(1)
 	go c.updateLoop()
 	return nil
@@ -155,51 +508,96 @@ func (c *Cache) doUpdate() error {
 
 	ctx := context.Background()
 	deadline, cancel := context.WithDeadline(ctx, time.Now().Add(time.Second*30))
-	releases, _, err := c.client.Repositories.ListReleases(deadline, c.helper.Config.RepositoryOwner, c.helper.Config.Repository, nil)
+	releases, err := c.provider.ListReleases(deadline)
 	if err != nil {
 		cancel()
 		return err
 	}
 	cancel()
 
-	releaseNames := make(map[string]struct{})
+	releases = c.withholdEmbargoed(releases)
+
+	releaseNames := make(map[string]string)
+	releaseTitles := make(map[string]string)
+	releasePublishedAt := make(map[string]time.Time)
 	checksums := make(map[artifactKey]string)
-	releaseArtifactNames := make(map[artifactKey]string)
+	releaseChecksums := make(map[string]map[string]string)
+	artifacts := make(map[artifactKey]*Artifact)
+	releaseArtifacts := make(map[string][]*Artifact)
+	binaryNames := make(map[string]struct{})
+	assets := make(map[assetKey]*Asset)
+	assetChecksums := make(map[assetKey]string)
+	var integrityIssues []*IntegrityIssue
 
 	if len(releases) < 1 {
 		c.helper.Printer.Printf("no releases available\n")
 		return nil
 	}
 
-	for _, release := range releases {
-		releaseName := strings.ToLower(release.GetName())
+	for i, release := range releases {
+		releaseName := release.Name
+		releaseKey := strings.ToLower(releaseName)
 
-		// checks for anything but "v" + numerics, for example v0.1.7-dev12, v0.1.7-pre10, etc
-		regex, err := regexp.Compile(`^[^a-zA-Z]*[vV][^a-zA-Z]*$`)
-		if err != nil {
-			return err
+		if !releaseNameRegex.MatchString(releaseKey) {
+			continue
 		}
+		if existingName, ok := releaseNames[releaseKey]; ok {
+			issue := &IntegrityIssue{
+				ReleaseName: existingName,
+				Problem:     IntegrityDuplicateName,
+				Tag:         release.Tag,
+			}
+			integrityIssues = append(integrityIssues, issue)
 
-		if !regex.MatchString(releaseName) {
-			continue
+			tagKey := strings.ToLower(release.Tag)
+			if _, taken := releaseNames[tagKey]; taken || !releaseNameRegex.MatchString(tagKey) {
+				c.helper.Printer.Printf("error: release %s (tag %s) has the same name as release %s, ignoring it\n", releaseName, release.Tag, existingName)
+				continue
+			}
+			c.helper.Printer.Printf("error: release %s (tag %s) has the same name as release %s, serving it as %s\n", releaseName, release.Tag, existingName, release.Tag)
+			issue.ServedAs = release.Tag
+
+			// the release is copied so that the release reported by the provider is left untouched
+			renamed := *release
+			renamed.Name = release.Tag
+			releases[i] = &renamed
+			release = &renamed
+			releaseName = release.Name
+			releaseKey = tagKey
+		}
+		releaseNames[releaseKey] = releaseName
+		releaseTitles[releaseKey] = release.Title
+		if !release.PublishedAt.IsZero() {
+			releasePublishedAt[releaseKey] = release.PublishedAt
 		}
-		releaseNames[releaseName] = struct{}{}
+		assetNames := make(map[string]struct{}, len(release.Assets))
+		var checksumNames []string
 		for _, asset := range release.Assets {
-			assetID := asset.GetID()
-			assetName := strings.ToLower(asset.GetName())
+			assetName := strings.ToLower(asset.Name)
+			assetNames[assetName] = struct{}{}
+			if !isArtifactName(assetName) {
+				assets[toAssetKey(releaseName, assetName)] = &Asset{
+					Name:  assetName,
+					Size:  asset.Size,
+					URL:   asset.URL,
+					asset: asset,
+				}
+			}
 			switch {
 			case assetName == "checksums.txt":
 				deadline, cancel = context.WithDeadline(ctx, time.Now().Add(time.Second*30))
-				assetReader, _, err := c.client.Repositories.DownloadReleaseAsset(deadline, c.helper.Config.RepositoryOwner, c.helper.Config.Repository, assetID, http.DefaultClient)
+				assetReader, err := c.provider.DownloadAsset(deadline, asset)
 				if err != nil {
 					cancel()
 					return err
 				}
 
+				releaseChecksums[releaseKey] = make(map[string]string)
 				reader := bufio.NewReader(assetReader)
 				for {
 					line, err := reader.ReadString(byte('\n'))
 					if err != nil {
+						_ = assetReader.Close()
 						cancel()
 						if !errors.Is(err, io.EOF) {
 							return err
@@ -207,103 +605,324 @@ func (c *Cache) doUpdate() error {
 						break
 					}
 					checksumLine := strings.Split(strings.TrimSpace(line), "  ")
-					if len(checksumLine) > 1 && strings.HasSuffix(checksumLine[1], ".tar.gz") {
-						trimmed := strings.TrimSuffix(checksumLine[1], ".tar.gz")
-						split := strings.Split(trimmed, "_")
-						if len(split) > 2 {
-							key := toArtifactKey(releaseName, split[2], strings.Join(split[3:], "_"))
-							checksums[key] = checksumLine[0]
-							c.helper.Printer.Printf("added checksum for asset with key %s (checksum %s)\n", key, checksumLine[0])
-						} else {
-							c.helper.Printer.Printf("error: malformed asset name %s for release %s\n", checksumLine[1], releaseName)
-						}
-					} else {
+					if len(checksumLine) < 2 {
 						c.helper.Printer.Printf("error: invalid checksum %s for release %s\n", checksumLine, releaseName)
+						continue
+					}
+					releaseChecksums[releaseKey][checksumLine[1]] = checksumLine[0]
+					assetChecksums[toAssetKey(releaseName, strings.ToLower(checksumLine[1]))] = checksumLine[0]
+					checksumNames = append(checksumNames, strings.ToLower(checksumLine[1]))
+					if !isArtifactName(checksumLine[1]) || !c.helper.Config.MatchesAssetPrefix(strings.ToLower(checksumLine[1])) {
+						continue
+					}
+					if _, _, _, ok := parseArtifactName(checksumLine[1], c.helper.Config.GetAssetPrefix()); !ok {
+						c.helper.Printer.Printf("error: malformed asset name %s for release %s\n", checksumLine[1], releaseName)
 					}
 				}
-			case strings.HasSuffix(assetName, ".tar.gz"):
-				trimmed := strings.TrimSuffix(assetName, ".tar.gz")
-				split := strings.Split(trimmed, "_")
-				if len(split) > 2 {
-					key := toArtifactKey(releaseName, split[2], strings.Join(split[3:], "_"))
-					releaseArtifactNames[key] = assetName
+			case isArtifactName(assetName):
+				if !c.helper.Config.MatchesAssetPrefix(assetName) {
+					continue
+				}
+				if name, os, arch, ok := parseArtifactName(assetName, c.helper.Config.GetAssetPrefix()); ok {
+					binaryName := c.artifactBinaryName(name)
+					if api.IsReservedName(binaryName) {
+						c.helper.Printer.Printf("error: binary name of asset %s for release %s is the path of a fixed route\n", assetName, releaseName)
+						continue
+					}
+					if binaryName != "" {
+						binaryNames[binaryName] = struct{}{}
+					}
+					if isZipArtifactName(assetName) && os != "windows" {
+						c.helper.Printer.Printf("ignoring zip artifact %s for release %s, zip artifacts are only served for windows\n", assetName, releaseName)
+						continue
+					}
+					key := toArtifactKey(binaryName, releaseName, os, arch)
+					existing, replace := artifacts[key]
+					if replace && !preferArtifact(assetName, existing.Name) {
+						c.helper.Printer.Printf("ignoring artifact %s for release %s in favor of %s\n", assetName, releaseName, existing.Name)
+						continue
+					}
+					artifact := &Artifact{
+						BinaryName: binaryName,
+						OS:         os,
+						Arch:       arch,
+						Name:       assetName,
+						Size:       asset.Size,
+						URL:        asset.URL,
+						UpdatedAt:  asset.UpdatedAt,
+						asset:      asset,
+					}
+					artifacts[key] = artifact
+					if replace {
+						c.helper.Printer.Printf("ignoring artifact %s for release %s in favor of %s\n", existing.Name, releaseName, assetName)
+						for j, releaseArtifact := range releaseArtifacts[releaseKey] {
+							if releaseArtifact == existing {
+								releaseArtifacts[releaseKey][j] = artifact
+							}
+						}
+					} else {
+						releaseArtifacts[releaseKey] = append(releaseArtifacts[releaseKey], artifact)
+					}
 					c.helper.Printer.Printf("saved release artifact name %s with key %s\n", assetName, key)
 				} else {
 					c.helper.Printer.Printf("error: malformed artifact name %s for release %s\n", assetName, releaseName)
 				}
 			}
 		}
+
+		for _, artifact := range releaseArtifacts[releaseKey] {
+			artifact.Checksum = assetChecksums[toAssetKey(releaseName, artifact.Name)]
+			if artifact.Checksum != "" {
+				checksums[toArtifactKey(artifact.BinaryName, releaseName, artifact.OS, artifact.Arch)] = artifact.Checksum
+			}
+		}
+		sort.Slice(releaseArtifacts[releaseKey], func(i, j int) bool {
+			return releaseArtifacts[releaseKey][i].Name < releaseArtifacts[releaseKey][j].Name
+		})
+
+		issues := checkIntegrity(releaseName, assetNames, checksumNames, releaseArtifacts[releaseKey])
+		for _, issue := range issues {
+			c.helper.Printer.Printf("error: integrity issue %s\n", issue)
+		}
+		integrityIssues = append(integrityIssues, issues...)
+	}
+
+	for key, asset := range assets {
+		asset.Checksum = assetChecksums[key]
 	}
 
-	c.mu.Lock()
-	c.releaseNames = releaseNames
-	c.checksums = checksums
-	c.releaseArtifactNames = releaseArtifactNames
-	c.mu.Unlock()
+	c.alertIntegrityIssues(integrityIssues)
 
 	latestRelease := releases[0]
-	latestReleaseName := strings.ToLower(latestRelease.GetName())
+	upstreamLatestReleaseName := latestRelease.Name
+	if pinnedReleaseName := c.GetPinnedReleaseName(); pinnedReleaseName != "" {
+		pinned := false
+		for _, release := range releases {
+			if strings.EqualFold(release.Name, pinnedReleaseName) {
+				latestRelease = release
+				pinned = true
+				c.setPinnedReleaseName(release.Name)
+				break
+			}
+		}
+		if !pinned {
+			c.helper.Printer.Printf("error: pinned release %s does not exist, using %s as the latest release\n", pinnedReleaseName, upstreamLatestReleaseName)
+		}
+	}
+	latestReleaseName := latestRelease.Name
+
+	previous := c.snapshot.Load()
+	signatures := make(map[assetKey][]byte)
+	for key, asset := range assets {
+		previousAsset, unchanged := previous.assets[key]
+		unchanged = unchanged && previousAsset.asset.Revision() == asset.asset.Revision()
+		if unchanged && previousAsset.Checksum == asset.Checksum {
+			asset.SetVerification(previousAsset.Verification())
+		}
+
+		if !isSignatureName(asset.Name) {
+			continue
+		}
+		if signature, ok := previous.signatures[key]; ok && unchanged {
+			signatures[key] = signature
+			continue
+		}
+		// signatures that can not be downloaded now are downloaded from the provider when they are requested
+		signature, err := c.downloadSignature(ctx, asset)
+		if err != nil {
+			c.helper.Printer.Printf("error: unable to download signature %s: %s\n", key, err)
+			continue
+		}
+		signatures[key] = signature
+	}
+	next := &snapshot{
+		releaseNames:              releaseNames,
+		releaseTitles:             releaseTitles,
+		releasePublishedAt:        releasePublishedAt,
+		checksums:                 checksums,
+		releaseChecksums:          releaseChecksums,
+		signatures:                signatures,
+		artifacts:                 artifacts,
+		releaseArtifacts:          releaseArtifacts,
+		binaryNames:               binaryNames,
+		assets:                    assets,
+		integrityIssues:           integrityIssues,
+		upstreamLatestReleaseName: upstreamLatestReleaseName,
+		latestReleaseName:         previous.latestReleaseName,
+		latestReleaseArtifacts:    previous.latestReleaseArtifacts,
+		latestReleaseFiles:        previous.latestReleaseFiles,
+		latestReleaseRevisions:    previous.latestReleaseRevisions,
+		previousReleaseName:       previous.previousReleaseName,
+		rolloutStart:              previous.rolloutStart,
+		generation:                previous.generation + 1,
+	}
 
-	if c.latestReleaseName != latestReleaseName {
+	evictLatest := c.evictLatest
+	c.evictLatest = false
+	latestReleaseChanged := previous.latestReleaseName != latestReleaseName
+	revisions := c.artifactRevisions(latestRelease, artifacts)
+	if latestReleaseChanged || evictLatest || !sameRevisions(revisions, previous.latestReleaseRevisions) {
+		// artifacts whose assets were not replaced are kept, unless they are evicted
+		reuse := !latestReleaseChanged && !evictLatest
 		latestReleaseArtifacts := make(map[artifactKey][]byte)
-		c.helper.Printer.Printf("updating cached assets for latest release to %s (was %s)\n", latestReleaseName, c.latestReleaseName)
+		latestReleaseFiles := make(map[artifactKey]string)
+		if latestReleaseChanged {
+			c.helper.Printer.Printf("updating cached assets for latest release to %s (was %s)\n", latestReleaseName, previous.latestReleaseName)
+		} else {
+			c.helper.Printer.Printf("refreshing cached assets for latest release %s\n", latestReleaseName)
+		}
 		for _, asset := range latestRelease.Assets {
-			assetID := asset.GetID()
-			assetName := strings.ToLower(asset.GetName())
-			if strings.HasSuffix(assetName, ".tar.gz") {
+			assetName := strings.ToLower(asset.Name)
+			if isArtifactName(assetName) && c.helper.Config.MatchesAssetPrefix(assetName) {
+				name, os, arch, ok := parseArtifactName(assetName, c.helper.Config.GetAssetPrefix())
+				if !ok {
+					c.helper.Printer.Printf("error: malformed artifact name %s for latest release %s\n", assetName, latestReleaseName)
+					continue
+				}
+				key := toArtifactKey(c.artifactBinaryName(name), latestReleaseName, os, arch)
+				if artifact, ok := artifacts[key]; !ok || artifact.Name != assetName {
+					continue
+				}
+
+				if reuse && previous.latestReleaseRevisions[key] == revisions[key] {
+					if artifactBytes, ok := previous.latestReleaseArtifacts[key]; ok {
+						latestReleaseArtifacts[key] = artifactBytes
+						continue
+					}
+					if path, ok := previous.latestReleaseFiles[key]; ok {
+						latestReleaseFiles[key] = path
+						continue
+					}
+				}
+				if before, ok := previous.latestReleaseRevisions[key]; reuse && ok {
+					c.helper.Printer.Printf("release asset %s of latest release %s was replaced, downloading it again\n", assetName, latestReleaseName)
+					c.record(&audit.Entry{
+						Action: "cache.replace_artifact",
+						Target: latestReleaseName + "/" + assetName,
+						Before: before,
+						After:  revisions[key],
+					})
+				}
+
 				deadline, cancel = context.WithDeadline(ctx, time.Now().Add(time.Second*30))
-				assetReader, _, err := c.client.Repositories.DownloadReleaseAsset(deadline, c.helper.Config.RepositoryOwner, c.helper.Config.Repository, assetID, http.DefaultClient)
+				assetReader, err := c.provider.DownloadAsset(deadline, asset)
 				if err != nil {
 					c.helper.Printer.Printf("error: unable to download release asset %s for latest release %s: %s\n", assetName, latestReleaseName, err)
 					cancel()
+					c.snapshot.Store(next)
 					return err
 				}
 
+				if c.helper.Config.ArtifactDirectory != "" {
+					path, size, err := c.storeArtifact(latestReleaseName, assetName, assetReader)
+					_ = assetReader.Close()
+					cancel()
+					if err != nil {
+						c.helper.Printer.Printf("error: unable to store release asset %s for latest release %s: %s\n", assetName, latestReleaseName, err)
+						c.snapshot.Store(next)
+						return err
+					}
+					latestReleaseFiles[key] = path
+					c.helper.Printer.Printf("stored release artifact %s with key %s at %s (%d bytes)\n", assetName, key, path, size)
+					continue
+				}
+
 				artifactBytes, err := io.ReadAll(assetReader)
+				_ = assetReader.Close()
+				cancel()
 				if err != nil {
-					cancel()
 					c.helper.Printer.Printf("error: unable to download release asset %s for latest release %s: %s\n", assetName, latestReleaseName, err)
+					c.snapshot.Store(next)
 					return err
 				}
-
-				trimmed := strings.TrimSuffix(assetName, ".tar.gz")
-				split := strings.Split(trimmed, "_")
-				if len(split) > 2 {
-					key := toArtifactKey(latestReleaseName, split[2], strings.Join(split[3:], "_"))
-					latestReleaseArtifacts[key] = artifactBytes
-					c.helper.Printer.Printf("downloaded release artifact %s with key %s (%d bytes)\n", assetName, key, len(artifactBytes))
-				} else {
-					c.helper.Printer.Printf("error: malformed artifact name %s for latest release %s\n", assetName, latestReleaseName)
-				}
-				cancel()
+				latestReleaseArtifacts[key] = artifactBytes
+				c.helper.Printer.Printf("downloaded release artifact %s with key %s (%d bytes)\n", assetName, key, len(artifactBytes))
 			}
 		}
 
-		c.mu.Lock()
-		c.latestReleaseName = latestReleaseName
-		c.latestReleaseArtifacts = latestReleaseArtifacts
-		c.mu.Unlock()
+		next.latestReleaseName = latestReleaseName
+		next.latestReleaseArtifacts = latestReleaseArtifacts
+		next.latestReleaseFiles = latestReleaseFiles
+		next.latestReleaseRevisions = revisions
+		if latestReleaseChanged {
+			c.startRollout(next, previous.latestReleaseName)
+		}
 	} else {
-		c.helper.Printer.Printf("latest release %s already cached\n", c.latestReleaseName)
+		c.helper.Printer.Printf("latest release %s already cached\n", latestReleaseName)
 	}
 
+	c.snapshot.Store(next)
+	c.pruneLicenses(next)
+	if previous.latestReleaseName != next.latestReleaseName {
+		c.record(&audit.Entry{
+			Action: "cache.latest_release",
+			Before: previous.latestReleaseName,
+			After:  next.latestReleaseName,
+		})
+		c.notifyLatestReleaseChanged()
+	}
+	for releaseKey, releaseName := range previous.releaseNames {
+		if _, ok := next.releaseNames[releaseKey]; !ok {
+			c.record(&audit.Entry{
+				Action: "cache.evict",
+				Target: releaseName,
+			})
+		}
+	}
+	if previous.latestReleaseName != "" && previous.latestReleaseName != next.latestReleaseName {
+		c.removeStoredArtifacts(previous.latestReleaseName)
+	}
 	c.helper.Printer.Printf("done updating cache in %s\n", time.Since(start))
 
 	return nil
 }
 
+// artifactRevisions returns the revisions of the artifact assets of the given release by their keys,
+// leaving out the assets that are not served since another asset was preferred for their key
+func (c *Cache) artifactRevisions(release *provider.Release, artifacts map[artifactKey]*Artifact) map[artifactKey]string {
+	revisions := make(map[artifactKey]string)
+	for _, asset := range release.Assets {
+		assetName := strings.ToLower(asset.Name)
+		if !isArtifactName(assetName) || !c.helper.Config.MatchesAssetPrefix(assetName) {
+			continue
+		}
+		if name, os, arch, ok := parseArtifactName(assetName, c.helper.Config.GetAssetPrefix()); ok {
+			key := toArtifactKey(c.artifactBinaryName(name), release.Name, os, arch)
+			if artifact, ok := artifacts[key]; ok && artifact.Name == assetName {
+				revisions[key] = asset.Revision()
+			}
+		}
+	}
+	return revisions
+}
+
+// sameRevisions returns true if the given revisions are the same for every artifact
+func sameRevisions(a map[artifactKey]string, b map[artifactKey]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, revision := range a {
+		if other, ok := b[key]; !ok || other != revision {
+			return false
+		}
+	}
+	return true
+}
+
 // updateLoop runs the update function every minute and updates the latest cache
 func (c *Cache) updateLoop() {
 	defer c.wg.Done()
 
 	c.helper.Printer.Printf("Doing initial update of cache\n")
-	err := c.doUpdate()
+	interval := updateInterval
+	err := c.update()
 	if err != nil {
-		c.helper.Printer.Printf("error: unable to do initial update of cache: %s\n", err)
-		panic(err)
+		c.helper.Printer.Printf("error: unable to do initial update of cache, retrying in %s: %s\n", retryInterval, err)
+		c.reportUpdateError(err)
+		interval = retryInterval
 	}
 
-	timer := time.NewTimer(time.Minute)
+	timer := time.NewTimer(c.nextUpdate(interval))
 	defer timer.Stop()
 
 	for {
@@ -312,11 +931,37 @@ func (c *Cache) updateLoop() {
 			return
 		case <-timer.C:
 			c.helper.Printer.Printf("updating cache\n")
-			err := c.doUpdate()
+			interval = updateInterval
+			err := c.update()
 			if err != nil {
-				c.helper.Printer.Printf("error: unable to update cache: %s\n", err)
+				if !c.GetStatus().Ready {
+					interval = retryInterval
+				}
+				c.helper.Printer.Printf("error: unable to update cache, serving the last known-good state: %s\n", err)
+				c.reportUpdateError(err)
 			}
-			timer.Reset(time.Minute)
+			timer.Reset(c.nextUpdate(interval))
 		}
 	}
 }
+
+// reportUpdateError reports a failed update as a warning event of the pod in Kubernetes mode
+func (c *Cache) reportUpdateError(err error) {
+	if c.events == nil {
+		return
+	}
+	deadline, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	if eventErr := c.events.Warning(deadline, "RefreshFailed", fmt.Sprintf("unable to update releases from %s: %s", c.provider.Name(), err)); eventErr != nil {
+		c.helper.Printer.Printf("error: unable to report refresh failure as kubernetes event: %s\n", eventErr)
+	}
+}
+
+// record appends the given entry to the audit log, the actions of the cache are always taken by the releaser itself
+func (c *Cache) record(entry *audit.Entry) {
+	entry.Actor = audit.ActorSystem
+	err := c.audit.Record(entry)
+	if err != nil {
+		c.helper.Printer.Printf("error: unable to record %s to the audit log: %s\n", entry.Action, err)
+	}
+}
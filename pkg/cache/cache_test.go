@@ -0,0 +1,206 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/printer"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/pkg/provider"
+)
+
+const (
+	benchmarkReleases = 50
+	benchmarkBinary   = "releaser"
+)
+
+var benchmarkPlatforms = [][2]string{{"linux", "amd64"}, {"linux", "arm64"}, {"darwin", "amd64"}, {"darwin", "arm64"}}
+
+// benchmarkProvider lists the same releases on every update, but swaps the two newest releases
+// each time so that every refresh changes the latest release and downloads its artifacts again
+type benchmarkProvider struct {
+	releases []*provider.Release
+	contents map[string][]byte
+	updates  atomic.Uint64
+}
+
+func newBenchmarkProvider() *benchmarkProvider {
+	p := &benchmarkProvider{
+		contents: make(map[string][]byte),
+	}
+	for i := benchmarkReleases; i > 0; i-- {
+		release := &provider.Release{
+			Name: fmt.Sprintf("v1.0.%d", i),
+		}
+		var checksums strings.Builder
+		for _, platform := range benchmarkPlatforms {
+			name := fmt.Sprintf("%s_%s_%s_%s.tar.gz", benchmarkBinary, release.Name, platform[0], platform[1])
+			release.Assets = append(release.Assets, &provider.Asset{
+				ID:   release.Name + "/" + name,
+				Name: name,
+				Size: 1024,
+			})
+			p.contents[release.Name+"/"+name] = bytes.Repeat([]byte{byte(i)}, 1024)
+			checksums.WriteString(fmt.Sprintf("%064x  %s\n", i, name))
+		}
+		release.Assets = append(release.Assets, &provider.Asset{
+			ID:   release.Name + "/checksums.txt",
+			Name: "checksums.txt",
+		})
+		p.contents[release.Name+"/checksums.txt"] = []byte(checksums.String())
+		p.releases = append(p.releases, release)
+	}
+	return p
+}
+
+func (p *benchmarkProvider) Name() string {
+	return "benchmark"
+}
+
+func (p *benchmarkProvider) ListReleases(context.Context) ([]*provider.Release, error) {
+	releases := make([]*provider.Release, len(p.releases))
+	copy(releases, p.releases)
+	if p.updates.Add(1)%2 == 0 {
+		releases[0], releases[1] = releases[1], releases[0]
+	}
+	return releases, nil
+}
+
+func (p *benchmarkProvider) DownloadAsset(_ context.Context, asset *provider.Asset) (io.ReadCloser, error) {
+	return io.NopCloser(bytes.NewReader(p.contents[asset.ID])), nil
+}
+
+// newBenchmarkCache returns a cache that was updated once from a benchmarkProvider, it is not
+// updated periodically so that the benchmarks control when it is refreshed
+func newBenchmarkCache(b *testing.B) *Cache {
+	format := printer.Human
+	helper := &cmdutils.Helper[*config.Config]{
+		Config:  config.New(),
+		Printer: printer.NewPrinter(&format),
+	}
+	helper.Printer.SetHumanOutput(io.Discard)
+
+	c := &Cache{
+		licenses:      make(map[string]*licenseDownload),
+		latestChanged: make(chan struct{}),
+		stop:          make(chan struct{}, 1),
+		helper:        helper,
+		provider:      newBenchmarkProvider(),
+	}
+	c.snapshot.Store(newSnapshot())
+	if err := c.Refresh(); err != nil {
+		b.Fatalf("unable to update cache: %s", err)
+	}
+	return c
+}
+
+// refreshDuring refreshes the given cache in a loop until the returned function is called
+func refreshDuring(b *testing.B, c *Cache) func() {
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if err := c.Refresh(); err != nil {
+				b.Errorf("unable to refresh cache: %s", err)
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+func benchmarkCacheGetReleaseArtifact(b *testing.B, refresh bool) {
+	c := newBenchmarkCache(b)
+	if refresh {
+		defer refreshDuring(b, c)()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			platform := benchmarkPlatforms[i%len(benchmarkPlatforms)]
+			releaseName := fmt.Sprintf("v1.0.%d", i%benchmarkReleases+1)
+			if c.GetReleaseArtifact("", releaseName, platform[0], platform[1]) == nil {
+				b.Errorf("artifact of release %s for %s/%s not found", releaseName, platform[0], platform[1])
+				return
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkCacheGetReleaseArtifact(b *testing.B) {
+	benchmarkCacheGetReleaseArtifact(b, false)
+}
+
+func BenchmarkCacheGetReleaseArtifactDuringRefresh(b *testing.B) {
+	benchmarkCacheGetReleaseArtifact(b, true)
+}
+
+func benchmarkCacheGetLatestReleaseArtifact(b *testing.B, refresh bool) {
+	c := newBenchmarkCache(b)
+	if refresh {
+		defer refreshDuring(b, c)()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			platform := benchmarkPlatforms[i%len(benchmarkPlatforms)]
+			// the latest release can change between both calls, but the artifact must never be read
+			// from a snapshot in which the given release is not the latest release
+			releaseName := c.GetLatestReleaseName()
+			artifactBytes := c.GetLatestReleaseArtifact("", releaseName, platform[0], platform[1])
+			if artifactBytes != nil && !strings.HasSuffix(releaseName, fmt.Sprintf(".%d", artifactBytes[0])) {
+				b.Errorf("artifact of release %s for %s/%s has the contents of another release", releaseName, platform[0], platform[1])
+				return
+			}
+			i++
+		}
+	})
+}
+
+func BenchmarkCacheGetLatestReleaseArtifact(b *testing.B) {
+	benchmarkCacheGetLatestReleaseArtifact(b, false)
+}
+
+func BenchmarkCacheGetLatestReleaseArtifactDuringRefresh(b *testing.B) {
+	benchmarkCacheGetLatestReleaseArtifact(b, true)
+}
@@ -0,0 +1,168 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/pkg/provider"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoReleases          = errors.New("no releases found")
+	ErrNoValidReleaseNames = errors.New("no release names match the expected format (e.g. v1.2.3)")
+	ErrNoArtifacts         = errors.New("no release has artifacts matching the expected format <name>_<version>_<os>_<arch>.tar.gz")
+	ErrNoChecksums         = errors.New("release does not have a checksums.txt asset")
+	ErrPinnedNotFound      = errors.New("pinned release does not exist")
+	ErrSkipped             = errors.New("skipped because an earlier check failed")
+)
+
+// CheckResult is the outcome of a single startup check of the configured provider
+type CheckResult struct {
+	// Name is a short description of the assumption that was checked
+	Name string
+
+	// Err describes why the assumption does not hold, it is nil if the check passed
+	Err error
+}
+
+// Check verifies the assumptions the cache makes about the given provider (that it is accessible,
+// has releases, and that the releases have artifacts the cache can parse) and reports the
+// result of every check, so misconfigurations are visible on startup instead of as empty responses
+func Check(ctx context.Context, p provider.Provider, c *config.Config) []*CheckResult {
+	accessible := &CheckResult{Name: fmt.Sprintf("%s is accessible", p.Name())}
+	if checker, ok := p.(provider.Checker); ok {
+		deadline, cancel := context.WithTimeout(ctx, time.Second*30)
+		accessible.Err = checker.Check(deadline)
+		cancel()
+	}
+
+	listed := &CheckResult{Name: "releases can be listed"}
+	hasReleases := &CheckResult{Name: "at least one release exists"}
+	validNames := &CheckResult{Name: "at least one release name is valid"}
+	hasArtifacts := &CheckResult{Name: "at least one release has artifacts"}
+	if c.AssetPrefix != "" {
+		hasArtifacts.Name = fmt.Sprintf("at least one release has artifacts with the prefix %s", c.AssetPrefix)
+	}
+	hasChecksums := &CheckResult{Name: "the latest release has checksums"}
+	results := []*CheckResult{accessible, listed, hasReleases, validNames, hasArtifacts, hasChecksums}
+
+	var pinned *CheckResult
+	if c.PinnedRelease != "" {
+		pinned = &CheckResult{Name: fmt.Sprintf("pinned release %s exists", c.PinnedRelease)}
+		results = append(results, pinned)
+	}
+
+	skip := func(from int) []*CheckResult {
+		for _, result := range results[from:] {
+			result.Err = ErrSkipped
+		}
+		return results
+	}
+
+	if accessible.Err != nil {
+		return skip(1)
+	}
+
+	deadline, cancel := context.WithTimeout(ctx, time.Second*30)
+	releases, err := p.ListReleases(deadline)
+	cancel()
+	if err != nil {
+		listed.Err = err
+		return skip(2)
+	}
+
+	if len(releases) == 0 {
+		hasReleases.Err = fmt.Errorf("%w in %s", ErrNoReleases, p.Name())
+		return skip(3)
+	}
+
+	var valid []*provider.Release
+	var invalidNames []string
+	for _, release := range releases {
+		if releaseNameRegex.MatchString(strings.ToLower(release.Name)) {
+			valid = append(valid, release)
+		} else {
+			invalidNames = append(invalidNames, release.Name)
+		}
+	}
+	if len(valid) == 0 {
+		validNames.Err = fmt.Errorf("%w, found %s", ErrNoValidReleaseNames, strings.Join(invalidNames, ", "))
+		return skip(4)
+	}
+
+	var ignored []string
+	found := false
+	for _, release := range valid {
+		for _, asset := range release.Assets {
+			assetName := strings.ToLower(asset.Name)
+			if !isArtifactName(assetName) {
+				continue
+			}
+			if _, _, _, ok := parseArtifactName(assetName, c.GetAssetPrefix()); ok && c.MatchesAssetPrefix(assetName) {
+				found = true
+				break
+			}
+			ignored = append(ignored, asset.Name)
+		}
+		if found {
+			break
+		}
+	}
+	if !found {
+		if len(ignored) > 0 {
+			hasArtifacts.Err = fmt.Errorf("%w, ignored %s", ErrNoArtifacts, strings.Join(ignored, ", "))
+		} else {
+			hasArtifacts.Err = ErrNoArtifacts
+		}
+	}
+
+	latest := valid[0]
+	hasChecksums.Err = fmt.Errorf("%w: %s", ErrNoChecksums, latest.Name)
+	for _, asset := range latest.Assets {
+		if strings.ToLower(asset.Name) == "checksums.txt" {
+			hasChecksums.Err = nil
+			break
+		}
+	}
+
+	if pinned != nil {
+		pinned.Err = ErrPinnedNotFound
+		for _, release := range releases {
+			if strings.EqualFold(release.Name, c.PinnedRelease) {
+				pinned.Err = nil
+				break
+			}
+		}
+	}
+
+	return results
+}
+
+// CheckFailed returns true if any of the given results did not pass
+func CheckFailed(results []*CheckResult) bool {
+	for _, result := range results {
+		if result.Err != nil {
+			return true
+		}
+	}
+	return false
+}
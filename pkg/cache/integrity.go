@@ -0,0 +1,150 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cache
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+const (
+	// IntegrityMissingChecksums is reported for releases with artifacts but without a checksums.txt
+	IntegrityMissingChecksums = "missing_checksums"
+
+	// IntegrityMissingAsset is reported for checksums.txt entries without a matching release asset
+	IntegrityMissingAsset = "missing_asset"
+
+	// IntegrityMissingChecksum is reported for artifacts without a matching checksums.txt entry
+	IntegrityMissingChecksum = "missing_checksum"
+
+	// IntegrityDuplicateName is reported for releases with the same name as a newer release,
+	// which are served under their tag instead or ignored if that is not possible
+	IntegrityDuplicateName = "duplicate_name"
+
+	// alertTimeout bounds posting an alert, so that a hanging webhook does not hold up the next update
+	alertTimeout = time.Second * 10
+)
+
+// IntegrityIssue describes a mismatch between the checksums.txt of a release and its assets
+type IntegrityIssue struct {
+	ReleaseName string `json:"release_name"`
+	AssetName   string `json:"asset_name,omitempty"`
+	Problem     string `json:"problem"`
+
+	// Tag is the tag of the release with a duplicate name
+	Tag string `json:"tag,omitempty"`
+
+	// ServedAs is the name a release with a duplicate name is served under,
+	// it is empty if the release is ignored
+	ServedAs string `json:"served_as,omitempty"`
+}
+
+func (i *IntegrityIssue) String() string {
+	if i.Problem == IntegrityDuplicateName {
+		return fmt.Sprintf("%s (tag %s): %s", i.ReleaseName, i.Tag, i.Problem)
+	}
+	return fmt.Sprintf("%s/%s: %s", i.ReleaseName, i.AssetName, i.Problem)
+}
+
+// checkIntegrity cross-checks the checksums.txt entries of a release against its assets
+func checkIntegrity(releaseName string, assetNames map[string]struct{}, checksumNames []string, artifacts []*Artifact) []*IntegrityIssue {
+	var issues []*IntegrityIssue
+	if _, ok := assetNames["checksums.txt"]; !ok {
+		if len(artifacts) > 0 {
+			issues = append(issues, &IntegrityIssue{
+				ReleaseName: releaseName,
+				Problem:     IntegrityMissingChecksums,
+			})
+		}
+		return issues
+	}
+
+	for _, checksumName := range checksumNames {
+		if _, ok := assetNames[checksumName]; !ok {
+			issues = append(issues, &IntegrityIssue{
+				ReleaseName: releaseName,
+				AssetName:   checksumName,
+				Problem:     IntegrityMissingAsset,
+			})
+		}
+	}
+
+	for _, artifact := range artifacts {
+		if artifact.Checksum == "" {
+			issues = append(issues, &IntegrityIssue{
+				ReleaseName: releaseName,
+				AssetName:   artifact.Name,
+				Problem:     IntegrityMissingChecksum,
+			})
+		}
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].AssetName < issues[j].AssetName
+	})
+	return issues
+}
+
+// alertIntegrityIssues posts the integrity issues that have not been reported yet
+// to the configured alert webhook
+func (c *Cache) alertIntegrityIssues(issues []*IntegrityIssue) {
+	alerted := make(map[string]struct{}, len(issues))
+	var newIssues []*IntegrityIssue
+	for _, issue := range issues {
+		alerted[issue.String()] = struct{}{}
+		if _, ok := c.alertedIssues[issue.String()]; !ok {
+			newIssues = append(newIssues, issue)
+		}
+	}
+	c.alertedIssues = alerted
+
+	if len(newIssues) == 0 || c.alertClient == nil {
+		return
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"event":  "integrity",
+		"issues": newIssues,
+	})
+	if err != nil {
+		c.helper.Printer.Printf("error: unable to encode integrity alert: %s\n", err)
+		return
+	}
+
+	deadline, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(deadline, http.MethodPost, c.helper.Config.AlertWebhookURL, bytes.NewReader(body))
+	if err != nil {
+		c.helper.Printer.Printf("error: unable to create integrity alert: %s\n", err)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.alertClient.Do(req)
+	if err != nil {
+		c.helper.Printer.Printf("error: unable to send integrity alert: %s\n", err)
+		return
+	}
+	_ = res.Body.Close()
+	if res.StatusCode >= http.StatusMultipleChoices {
+		c.helper.Printer.Printf("error: integrity alert webhook responded with %d\n", res.StatusCode)
+	}
+}
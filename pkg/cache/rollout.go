@@ -0,0 +1,111 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cache
+
+import (
+	"github.com/loopholelabs/releaser/internal/audit"
+	"time"
+)
+
+// Rollout describes the progressive rollout of the latest release
+type Rollout struct {
+	// PreviousReleaseName is the release served to clients outside the rollout,
+	// it is empty if no rollout is in progress
+	PreviousReleaseName string
+
+	// Percentage is the percentage of clients the latest release is served to
+	Percentage int
+}
+
+// GetRollout returns the progress of the rollout of the latest release, which ramps
+// from 0 to 100 percent over the configured rollout window once a new release is found
+func (c *Cache) GetRollout() Rollout {
+	snapshot := c.snapshot.Load()
+	previousReleaseName := snapshot.previousReleaseName
+	rolloutStart := snapshot.rolloutStart
+
+	window := c.helper.Config.RolloutWindow
+	if previousReleaseName == "" || window <= 0 {
+		return Rollout{Percentage: 100}
+	}
+
+	elapsed := time.Since(rolloutStart)
+	if elapsed >= window {
+		return Rollout{Percentage: 100}
+	}
+
+	return Rollout{
+		PreviousReleaseName: previousReleaseName,
+		Percentage:          int(elapsed * 100 / window),
+	}
+}
+
+// startRollout starts rolling out the latest release of the given snapshot, which has not been published yet,
+// replacing the previous latest release. Releases found on startup and pinned releases are served to all
+// clients immediately. If the previous latest release was still being rolled out, its rollout is superseded
+// and the new release is rolled out from the release that was served before it, so that clients outside the
+// earlier rollout do not jump to the superseded release.
+func (c *Cache) startRollout(next *snapshot, previousReleaseName string) {
+	if previousReleaseName == "" || c.GetPinnedReleaseName() != "" || c.helper.Config.RolloutWindow <= 0 {
+		next.previousReleaseName = ""
+		return
+	}
+	// next still carries the rollout of the previous snapshot
+	if next.previousReleaseName != "" && time.Since(next.rolloutStart) < c.helper.Config.RolloutWindow {
+		c.record(&audit.Entry{
+			Action: "rollout.supersede",
+			Target: previousReleaseName,
+			Before: next.previousReleaseName,
+			After:  next.latestReleaseName,
+		})
+		previousReleaseName = next.previousReleaseName
+		if previousReleaseName == next.latestReleaseName {
+			next.previousReleaseName = ""
+			return
+		}
+	}
+	next.previousReleaseName = previousReleaseName
+	next.rolloutStart = time.Now()
+	c.record(&audit.Entry{
+		Action: "rollout.start",
+		Target: next.latestReleaseName,
+		Before: previousReleaseName,
+		After:  c.helper.Config.RolloutWindow.String(),
+	})
+}
+
+// CompleteRollout serves the latest release to all clients immediately, ending the rollout in progress.
+// It returns false if no rollout is in progress.
+func (c *Cache) CompleteRollout() bool {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+
+	previous := c.snapshot.Load()
+	if previous.previousReleaseName == "" {
+		return false
+	}
+	next := *previous
+	next.previousReleaseName = ""
+	next.generation++
+	c.snapshot.Store(&next)
+	c.record(&audit.Entry{
+		Action: "rollout.complete",
+		Target: next.latestReleaseName,
+		Before: previous.previousReleaseName,
+	})
+	return true
+}
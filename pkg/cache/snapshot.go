@@ -0,0 +1,123 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+)
+
+// snapshot is the state of the releases found during an update. It is never modified once it
+// has been published, updates build a new snapshot and swap it in atomically instead.
+type snapshot struct {
+	// releaseNames stores the canonical (case-preserving) name
+	// of a release, given its lowercase name
+	releaseNames map[string]string
+
+	// releaseTitles stores the display title of a release, given its lowercase name
+	releaseTitles map[string]string
+
+	// releasePublishedAt stores when a release was published, given its lowercase name,
+	// releases whose provider does not report it are not stored
+	releasePublishedAt map[string]time.Time
+
+	// checksums stores the checksum of a given artifact across
+	// all releases
+	checksums map[artifactKey]string
+
+	// artifacts stores the artifacts across all releases
+	artifacts map[artifactKey]*Artifact
+
+	// latestRelease is the name of the latest release
+	latestReleaseName string
+
+	// latestReleaseArtifacts stores the artifacts for the latest release
+	latestReleaseArtifacts map[artifactKey][]byte
+
+	// latestReleaseFiles stores the paths of the artifacts for the latest
+	// release when they are stored on disk instead of in memory
+	latestReleaseFiles map[artifactKey]string
+
+	// latestReleaseRevisions stores the revisions of the assets the artifacts for the
+	// latest release were downloaded from, so that replaced assets are downloaded again
+	latestReleaseRevisions map[artifactKey]string
+
+	// releaseArtifacts stores the artifacts of each release
+	releaseArtifacts map[string][]*Artifact
+
+	// binaryNames stores the binary names found across all releases
+	// when running in multi-binary mode
+	binaryNames map[string]struct{}
+
+	// assets stores the assets that are not binary artifacts across all releases
+	assets map[assetKey]*Asset
+
+	// releaseChecksums stores the entries of the checksums.txt of each release,
+	// mapping the asset names to their checksums
+	releaseChecksums map[string]map[string]string
+
+	// signatures stores the contents of the signature assets across all releases, so that
+	// requests for a signature are not passed on to the provider
+	signatures map[assetKey][]byte
+
+	// integrityIssues stores the mismatches between the checksums.txt files and
+	// the assets of all releases
+	integrityIssues []*IntegrityIssue
+
+	// upstreamLatestReleaseName is the latest release reported by the provider
+	upstreamLatestReleaseName string
+
+	// previousReleaseName is served to the clients outside the rollout of the latest release,
+	// which started at rolloutStart
+	previousReleaseName string
+	rolloutStart        time.Time
+
+	// generation is incremented by every update, so that anything derived from
+	// a snapshot can tell when it has been replaced
+	generation uint64
+}
+
+// newSnapshot returns an empty snapshot, which is used until the first update completes
+func newSnapshot() *snapshot {
+	return &snapshot{
+		releaseNames:           make(map[string]string),
+		releaseTitles:          make(map[string]string),
+		releasePublishedAt:     make(map[string]time.Time),
+		checksums:              make(map[artifactKey]string),
+		releaseChecksums:       make(map[string]map[string]string),
+		artifacts:              make(map[artifactKey]*Artifact),
+		latestReleaseArtifacts: make(map[artifactKey][]byte),
+		latestReleaseFiles:     make(map[artifactKey]string),
+		latestReleaseRevisions: make(map[artifactKey]string),
+		releaseArtifacts:       make(map[string][]*Artifact),
+		binaryNames:            make(map[string]struct{}),
+		assets:                 make(map[assetKey]*Asset),
+		signatures:             make(map[assetKey][]byte),
+	}
+}
+
+// licenseAssets returns the license and notice files attached to the given release, licenses before notices
+func (s *snapshot) licenseAssets(releaseName string) []*Asset {
+	var licenseAssets []*Asset
+	for _, licenseName := range licenseNames {
+		for _, extension := range licenseExtensions {
+			if asset, ok := s.assets[toAssetKey(releaseName, licenseName+extension)]; ok {
+				licenseAssets = append(licenseAssets, asset)
+			}
+		}
+	}
+	return licenseAssets
+}
@@ -0,0 +1,95 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cache
+
+import (
+	"time"
+)
+
+const (
+	// updateInterval is how often the cache is updated
+	updateInterval = time.Minute
+
+	// retryInterval is how often the initial update is retried until it succeeds
+	retryInterval = time.Second * 10
+
+	// staleAfter is how long after the last successful update the cache is considered stale
+	staleAfter = updateInterval * 5
+)
+
+// Status describes the freshness of the cache
+type Status struct {
+	// Ready is true once the cache has been updated successfully at least once
+	Ready bool
+
+	// Stale is true if the last successful update is older than staleAfter, in which case
+	// the last known-good state is still served
+	Stale bool
+
+	// LastUpdate is the time of the last successful update
+	LastUpdate time.Time
+
+	// LastError is the error of the last update, if it failed
+	LastError error
+
+	// Failures is the total number of failed updates
+	Failures int
+}
+
+// GetStatus returns the freshness of the cache
+func (c *Cache) GetStatus() Status {
+	c.statusMu.RLock()
+	defer c.statusMu.RUnlock()
+	return Status{
+		Ready:      !c.lastUpdate.IsZero(),
+		Stale:      !c.lastUpdate.IsZero() && time.Since(c.lastUpdate) > staleAfter,
+		LastUpdate: c.lastUpdate,
+		LastError:  c.lastError,
+		Failures:   c.failures,
+	}
+}
+
+// update updates the cache once and records the outcome, keeping the last
+// known-good state if the update fails
+func (c *Cache) update() error {
+	c.updateMu.Lock()
+	defer c.updateMu.Unlock()
+	err := c.doUpdate()
+	c.statusMu.Lock()
+	c.lastError = err
+	if err != nil {
+		c.failures++
+	} else {
+		c.lastUpdate = time.Now()
+	}
+	c.statusMu.Unlock()
+	return err
+}
+
+// Refresh updates the cache immediately instead of waiting for the next periodic update
+func (c *Cache) Refresh() error {
+	return c.update()
+}
+
+// EvictLatestRelease discards the cached artifacts of the latest release and downloads them
+// again (e.g. after an asset was replaced upstream without publishing a new release)
+func (c *Cache) EvictLatestRelease() error {
+	c.updateMu.Lock()
+	c.evictLatest = true
+	c.updateMu.Unlock()
+	return c.update()
+}
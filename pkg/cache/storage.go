@@ -0,0 +1,138 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package cache
+
+import (
+	"github.com/loopholelabs/releaser/internal/audit"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// releaseDirectory returns the directory the artifacts of the given release are stored in
+func (c *Cache) releaseDirectory(releaseName string) string {
+	return filepath.Join(c.helper.Config.ArtifactDirectory, url.PathEscape(strings.ToLower(releaseName)))
+}
+
+// initArtifactDirectory creates the artifact directory and removes the artifacts
+// stored by previous runs, which may no longer belong to the latest release
+func (c *Cache) initArtifactDirectory() error {
+	if c.helper.Config.ArtifactDirectory == "" {
+		return nil
+	}
+
+	err := os.MkdirAll(c.helper.Config.ArtifactDirectory, 0755)
+	if err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(c.helper.Config.ArtifactDirectory)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		releaseName, err := url.PathUnescape(entry.Name())
+		if err != nil || !entry.IsDir() || !releaseNameRegex.MatchString(releaseName) {
+			continue
+		}
+		c.removeStoredArtifacts(releaseName)
+	}
+	return nil
+}
+
+// storeArtifact writes the given artifact of the given release to the artifact directory
+// and returns the path and the size of the stored file. The file is written under a temporary
+// name first, so that requests never observe a partially written artifact.
+func (c *Cache) storeArtifact(releaseName string, assetName string, reader io.Reader) (string, int64, error) {
+	directory := c.releaseDirectory(releaseName)
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		return "", 0, err
+	}
+
+	file, err := os.CreateTemp(directory, ".download-*")
+	if err != nil {
+		return "", 0, err
+	}
+
+	size, err := io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", 0, err
+	}
+
+	path := filepath.Join(directory, filepath.Base(assetName))
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", 0, err
+	}
+
+	return path, size, nil
+}
+
+// removeStoredArtifacts removes the stored artifacts of the given release, requests that
+// are still serving them keep reading from their open file handles
+func (c *Cache) removeStoredArtifacts(releaseName string) {
+	if c.helper.Config.ArtifactDirectory == "" {
+		return
+	}
+	err := os.RemoveAll(c.releaseDirectory(releaseName))
+	if err != nil {
+		c.helper.Printer.Printf("error: unable to remove stored artifacts for release %s: %s\n", releaseName, err)
+		return
+	}
+	c.record(&audit.Entry{
+		Action: "cache.evict_artifacts",
+		Target: releaseName,
+	})
+}
+
+// OpenLatestReleaseArtifact opens the artifact of the given release stored in the artifact directory if it is the
+// latest release, and returns it with its size, it must be closed by the caller. Since the returned reader is an
+// *os.File, the server can send it with sendfile instead of copying it through memory.
+//
+// It will return nil if the release is not the latest release, or if the artifact does not exist or is kept in
+// memory, see GetLatestReleaseArtifact
+func (c *Cache) OpenLatestReleaseArtifact(binaryName string, releaseName string, goos string, goarch string) (io.ReadCloser, int, error) {
+	snapshot := c.snapshot.Load()
+	if snapshot.latestReleaseName != releaseName {
+		return nil, 0, nil
+	}
+	path, ok := snapshot.latestReleaseFiles[toArtifactKey(binaryName, releaseName, goos, goarch)]
+	if !ok {
+		return nil, 0, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, err
+	}
+
+	return file, int(info.Size()), nil
+}
@@ -17,87 +17,423 @@
 package client
 
 import (
+	"context"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/go-resty/resty/v2"
-	"github.com/loopholelabs/releaser/internal/utils"
-	"github.com/loopholelabs/releaser/pkg/server"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 )
 
 var (
-	InvalidChecksumError = errors.New("error while verifying checksum")
+	InvalidChecksumError  = errors.New("error while verifying checksum")
+	RateLimitedError      = errors.New("rate limited by the server")
+	ChecksumNotFoundError = errors.New("checksum not found")
+)
+
+// DefaultUserAgent identifies the requests of the client, applications embedding it can
+// prepend their own name and version with SetUserAgent
+const DefaultUserAgent = "releaser-client"
+
+const (
+	// DefaultRetries is how many times a request is retried after the server responds with a 429
+	DefaultRetries = 3
+
+	// maxRetryAfter caps how long a rate limited request waits before it is retried
+	maxRetryAfter = time.Minute * 2
 )
 
 type Client struct {
 	base   string
 	client *resty.Client
+
+	// ctx bounds the requests of the client including the time spent waiting to retry them, see WithContext
+	ctx context.Context
+
+	// verifier verifies the signatures of downloaded artifacts, it is nil unless a public key is set
+	verifier verifier
+
+	// pins are the certificates and public keys the server is trusted by, see SetPinnedPublicKeys
+	pins pins
+
+	// hooks are called while requests are sent, they are nil unless set with SetHooks
+	hooks *Hooks
+
+	// maxConcurrentDownloads limits the number of artifacts DownloadAll downloads at the same time
+	maxConcurrentDownloads int
+
+	// pollInterval is how often Subscribe checks the latest release, see SetPollInterval
+	pollInterval time.Duration
+
+	clientID string
+	cohort   string
+}
+
+// LatestRelease describes the latest release served to this client
+type LatestRelease struct {
+	// Name is the name of the release
+	Name string
+
+	// Cohort is the cohort the release was selected for, it is empty
+	// if the client did not select a cohort or it is not configured
+	Cohort string
+
+	// RolloutPercentage is the percentage of clients the newest release is being served to,
+	// it is 100 unless a rollout is in progress
+	RolloutPercentage int
+
+	// PreviousReleaseName is the release served to the clients outside a rollout in progress
+	PreviousReleaseName string
 }
 
 func New(base string) *Client {
 	return &Client{
-		base:   base,
-		client: resty.New().SetBaseURL(base),
+		base:                   base,
+		maxConcurrentDownloads: DefaultConcurrentDownloads,
+		pollInterval:           DefaultPollInterval,
+		client: resty.New().
+			SetBaseURL(base).
+			SetHeader("User-Agent", DefaultUserAgent).
+			SetRetryCount(DefaultRetries).
+			SetRetryMaxWaitTime(maxRetryAfter).
+			SetRetryAfter(func(_ *resty.Client, res *resty.Response) (time.Duration, error) {
+				wait := retryAfter(res)
+				if deadline, ok := res.Request.Context().Deadline(); ok && time.Now().Add(wait).After(deadline) {
+					return 0, fmt.Errorf("retrying after %s would exceed the deadline: %w", wait, statusError(res))
+				}
+				if body := res.RawBody(); body != nil {
+					// the body of a response is not closed by resty if the request does not parse it
+					_ = body.Close()
+				}
+				reportRetry(res, wait)
+				return wait, nil
+			}).
+			AddRetryCondition(func(res *resty.Response, _ error) bool {
+				return res != nil && retryable(res)
+			}),
 	}
 }
 
-func (c *Client) ListReleaseNames() (*server.ListReleaseNamesResponse, error) {
+// WithContext returns a copy of the client whose requests use the given context. Requests are only
+// retried if the server allows retrying them before the deadline of the context.
+func (c *Client) WithContext(ctx context.Context) *Client {
+	clone := *c
+	clone.ctx = ctx
+	return &clone
+}
+
+// WithPublicKey returns a copy of the client that verifies every downloaded artifact against the signature
+// the server serves for it, which must have been made with the private key of the given public key. The key
+// is either a minisign public key or a cosign public key in PEM format. Downloads whose signature is missing
+// or invalid fail with a *SignatureError.
+func (c *Client) WithPublicKey(key string) (*Client, error) {
+	v, err := parsePublicKey(key)
+	if err != nil {
+		return nil, err
+	}
+	clone := *c
+	clone.verifier = v
+	return &clone, nil
+}
+
+// request returns a new request using the context of the client
+func (c *Client) request() *resty.Request {
 	req := c.client.NewRequest()
-	res, err := req.Get(server.ListReleaseNamesPath)
+	if c.ctx != nil {
+		req.SetContext(c.ctx)
+	}
+	return req
+}
+
+// SetRetries sets how many times a request is retried after the server rate limits it (429) or asks
+// to retry it later (503 with Retry-After), waiting for as long as the server asks. 0 disables retries.
+func (c *Client) SetRetries(retries int) *Client {
+	c.client.SetRetryCount(retries)
+	return c
+}
+
+// SetUserAgent identifies the application sending the requests of the client in their User-Agent
+// header (e.g. SetUserAgent("tool", "v1.2.3") sends "tool/v1.2.3 releaser-client")
+func (c *Client) SetUserAgent(name string, version string) *Client {
+	userAgent := name
+	if version != "" {
+		userAgent += "/" + version
+	}
+	c.client.SetHeader("User-Agent", userAgent+" "+DefaultUserAgent)
+	return c
+}
+
+// SetHeader sets a header that is sent with every request of the client (e.g. a token
+// required by a private deployment)
+func (c *Client) SetHeader(key string, value string) *Client {
+	c.client.SetHeader(key, value)
+	return c
+}
+
+// SetAuthToken sets the bearer token sent in the Authorization header of every request of the client
+func (c *Client) SetAuthToken(token string) *Client {
+	c.client.SetAuthToken(token)
+	return c
+}
+
+// SetClientID sets the stable ID used to place this client in rollouts (e.g. MachineID("tool")),
+// the server falls back to the IP address of the client if it is not set
+func (c *Client) SetClientID(clientID string) *Client {
+	c.clientID = clientID
+	return c
+}
+
+// SetCohort sets the cohort (e.g. canary) this client belongs to for staged upgrades
+func (c *Client) SetCohort(cohort string) *Client {
+	c.cohort = cohort
+	return c
+}
+
+// ListReleaseNames returns the names of all releases and when they were published, newest version first
+func (c *Client) ListReleaseNames() (*v1.ListReleaseNamesResponse, error) {
+	return c.listReleaseNames(api.OrderVersion)
+}
+
+// ListReleaseNamesByDate returns the names of all releases and when they were published, most recently
+// published first, releases whose publish date is unknown are listed last
+func (c *Client) ListReleaseNamesByDate() (*v1.ListReleaseNamesResponse, error) {
+	return c.listReleaseNames(api.OrderDate)
+}
+
+func (c *Client) listReleaseNames(order string) (*v1.ListReleaseNamesResponse, error) {
+	req := c.request().SetQueryParam(api.Order, order)
+	res, err := c.get(req, api.JoinPaths(api.V1Path, api.ListReleaseNamesPath))
 	if err != nil {
 		return nil, fmt.Errorf("error while getting available release names: %w", err)
 	}
 
 	if res.StatusCode() != 200 {
-		return nil, fmt.Errorf("invalid response status code: %d with body '%s'", res.StatusCode(), string(res.Body()))
+		return nil, statusError(res)
+	}
+
+	val := new(v1.ListReleaseNamesResponse)
+	return val, json.Unmarshal(res.Body(), val)
+}
+
+// GetReleaseMetadata returns the platforms of the given release and the size, checksum, and
+// download URL of each of its artifacts
+func (c *Client) GetReleaseMetadata(releaseName string) (*v1.ReleaseMetadata, error) {
+	req := c.request()
+	res, err := c.get(req, api.JoinPaths(api.V1Path, api.APIPath, api.ListReleasesPath, releaseName))
+	if err != nil {
+		return nil, fmt.Errorf("error while getting release metadata: %w", err)
+	}
+
+	if res.StatusCode() != 200 {
+		return nil, statusError(res)
 	}
 
-	val := new(server.ListReleaseNamesResponse)
+	val := new(v1.ReleaseMetadata)
 	return val, json.Unmarshal(res.Body(), val)
 }
 
 func (c *Client) GetLatestReleaseName() (string, error) {
-	req := c.client.NewRequest()
-	res, err := req.Get(server.LatestReleaseNamePath)
+	latestRelease, err := c.GetLatestRelease()
+	if err != nil {
+		return "", err
+	}
+	return latestRelease.Name, nil
+}
+
+// GetLatestRelease returns the latest release for this client, taking its cohort
+// and any rollout in progress into account
+func (c *Client) GetLatestRelease() (*LatestRelease, error) {
+	req := c.request()
+	if c.clientID != "" {
+		req.SetQueryParam(api.ClientID, c.clientID)
+	}
+	if c.cohort != "" {
+		req.SetQueryParam(api.Cohort, c.cohort)
+	}
+	res, err := c.get(req, api.JoinPaths(api.V1Path, api.LatestReleaseNamePath))
 	if err != nil {
-		return "", fmt.Errorf("error while getting latest release name: %w", err)
+		return nil, fmt.Errorf("error while getting latest release name: %w", err)
 	}
 
 	if res.StatusCode() != 200 {
-		return "", fmt.Errorf("invalid response status code: %d with body '%s'", res.StatusCode(), string(res.Body()))
+		return nil, statusError(res)
 	}
 
-	return string(res.Body()), nil
+	latestRelease := &LatestRelease{
+		Name:                string(res.Body()),
+		Cohort:              res.Header().Get(api.CohortHeader),
+		RolloutPercentage:   100,
+		PreviousReleaseName: res.Header().Get(api.RolloutPreviousReleaseHeader),
+	}
+	if percentage := res.Header().Get(api.RolloutPercentageHeader); percentage != "" {
+		latestRelease.RolloutPercentage, err = strconv.Atoi(percentage)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rollout percentage '%s': %w", percentage, err)
+		}
+	}
+
+	return latestRelease, nil
+}
+
+// CheckForUpdate returns the latest release for this client and whether it differs from the given
+// release the client is currently running, taking its cohort and any rollout in progress into account
+func (c *Client) CheckForUpdate(currentReleaseName string) (*v1.UpdateInfo, error) {
+	req := c.request().SetQueryParam(api.Current, currentReleaseName)
+	if c.clientID != "" {
+		req.SetQueryParam(api.ClientID, c.clientID)
+	}
+	if c.cohort != "" {
+		req.SetQueryParam(api.Cohort, c.cohort)
+	}
+	res, err := c.get(req, api.JoinPaths(api.V1Path, api.APIPath, api.UpdatePath))
+	if err != nil {
+		return nil, fmt.Errorf("error while checking for updates: %w", err)
+	}
+
+	if res.StatusCode() != 200 {
+		return nil, statusError(res)
+	}
+
+	val := new(v1.UpdateInfo)
+	return val, json.Unmarshal(res.Body(), val)
 }
 
 func (c *Client) GetChecksum(releaseName string) (string, error) {
-	req := c.client.NewRequest()
-	res, err := req.Get(utils.JoinPaths(server.ChecksumPath, releaseName, runtime.GOOS, runtime.GOARCH))
+	req := c.request()
+	res, err := c.get(req, api.JoinPaths(api.V1Path, api.ChecksumPath, releaseName, runtime.GOOS, runtime.GOARCH))
 	if err != nil {
 		return "", fmt.Errorf("error while getting checksum: %w", err)
 	}
 
 	if res.StatusCode() != 200 {
-		return "", fmt.Errorf("invalid response status code: %d with body '%s'", res.StatusCode(), string(res.Body()))
+		return "", statusError(res)
 	}
 
 	return string(res.Body()), nil
 }
 
+// GetChecksums returns the checksums of all assets of the given release listed in its checksums.txt,
+// keyed by asset name
+func (c *Client) GetChecksums(releaseName string) (map[string]string, error) {
+	req := c.request()
+	res, err := c.get(req, api.JoinPaths(api.V1Path, api.ChecksumsPath, releaseName))
+	if err != nil {
+		return nil, fmt.Errorf("error while getting checksums: %w", err)
+	}
+
+	if res.StatusCode() != 200 {
+		return nil, statusError(res)
+	}
+
+	checksums := make(map[string]string)
+	for _, line := range strings.Split(string(res.Body()), "\n") {
+		checksum, assetName, ok := strings.Cut(strings.TrimSpace(line), "  ")
+		if !ok {
+			continue
+		}
+		checksums[assetName] = checksum
+	}
+	return checksums, nil
+}
+
+// Verify verifies the contents of the given reader against the checksum of the asset with the given
+// name in the checksums.txt of the given release
+func (c *Client) Verify(releaseName string, assetName string, reader io.Reader) error {
+	checksums, err := c.GetChecksums(releaseName)
+	if err != nil {
+		return err
+	}
+
+	var checksum string
+	for name, sum := range checksums {
+		if strings.EqualFold(name, assetName) {
+			checksum = sum
+			break
+		}
+	}
+	if checksum == "" {
+		return fmt.Errorf("%w: %s", ChecksumNotFoundError, assetName)
+	}
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, reader); err != nil {
+		return fmt.Errorf("error while hashing %s: %w", assetName, err)
+	}
+	if !strings.EqualFold(checksum, hex.EncodeToString(hash.Sum(nil))) {
+		return InvalidChecksumError
+	}
+	return nil
+}
+
+// VerifyFile verifies the file at the given path against the checksum of the asset with the same name in
+// the checksums.txt of the given release, for artifacts obtained without this client (e.g. from a mirror
+// or a previous CI stage)
+func (c *Client) VerifyFile(releaseName string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return c.Verify(releaseName, filepath.Base(path), file)
+}
+
 func (c *Client) GetReleaseArtifact(releaseName string) ([]byte, error) {
-	req := c.client.NewRequest()
-	res, err := req.Get(utils.JoinPaths(releaseName, runtime.GOOS, runtime.GOARCH))
+	return c.getArtifact(releaseName, api.JoinPaths(releaseName, runtime.GOOS, runtime.GOARCH))
+}
+
+// getArtifact downloads the artifact of the given release served at the given path (e.g. /v1.2.3/linux/amd64),
+// verifying its signature if the client has a public key
+func (c *Client) getArtifact(releaseName string, artifactPath string) ([]byte, error) {
+	req := c.request()
+	body, res, err := c.download(req, artifactPath)
 	if err != nil {
 		return nil, fmt.Errorf("error while getting release artifact: %w", err)
 	}
 
 	if res.StatusCode() != 200 {
-		return nil, fmt.Errorf("invalid response status code: %d with body '%s'", res.StatusCode(), string(res.Body()))
+		return nil, statusError(res)
+	}
+
+	if c.verifier != nil {
+		err = c.verifySignature(releaseName, artifactPath, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+// verifySignature verifies the given artifact of the given release served at the given path
+// against the signature served for it
+func (c *Client) verifySignature(releaseName string, artifactPath string, artifact []byte) error {
+	req := c.request()
+	req.SetQueryParam(api.SignatureFormat, c.verifier.format())
+	res, err := c.get(req, api.JoinPaths(api.V1Path, api.SignaturePath, artifactPath))
+	if err != nil {
+		return &SignatureError{ReleaseName: releaseName, Err: fmt.Errorf("error while getting signature: %w", err)}
+	}
+
+	if res.StatusCode() != 200 {
+		return &SignatureError{ReleaseName: releaseName, Err: statusError(res)}
 	}
 
-	return res.Body(), nil
+	err = c.verifier.verify(artifact, res.Body())
+	if err != nil {
+		return &SignatureError{ReleaseName: releaseName, Err: err}
+	}
+	return nil
 }
 
 func (c *Client) DownloadReleaseArtifactAndVerify(releaseName string) ([]byte, error) {
@@ -117,3 +453,53 @@ func (c *Client) DownloadReleaseArtifactAndVerify(releaseName string) ([]byte, e
 
 	return body, nil
 }
+
+// statusError returns the error for a response with an unexpected status code
+func statusError(res *resty.Response) error {
+	if res.StatusCode() == http.StatusTooManyRequests {
+		return fmt.Errorf("%w: retry after %s", RateLimitedError, retryAfter(res))
+	}
+	envelope := new(v1.ErrorResponse)
+	if json.Unmarshal(res.Body(), envelope) == nil && envelope.Error != "" {
+		return fmt.Errorf("invalid response status code: %d with error '%s'", res.StatusCode(), envelope.Error)
+	}
+	return fmt.Errorf("invalid response status code: %d with body '%s'", res.StatusCode(), string(res.Body()))
+}
+
+// retryable returns whether the server asked to retry the request of the given response later, which
+// it does when it rate limits a client (429) and when it is temporarily unavailable (503 with Retry-After)
+func retryable(res *resty.Response) bool {
+	switch res.StatusCode() {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusServiceUnavailable:
+		return res.Header().Get("Retry-After") != ""
+	default:
+		return false
+	}
+}
+
+// retryAfter returns how long the server asked to wait before retrying a request, which is read from
+// the body of a rate limit response, the Retry-After header (in seconds or as a date), or the rate limit
+// reset header, in that order. It returns 0 if the server did not say, so that the request is retried
+// with a backoff instead.
+func retryAfter(res *resty.Response) time.Duration {
+	envelope := new(v1.ErrorResponse)
+	if json.Unmarshal(res.Body(), envelope) == nil && envelope.RetryAfter > 0 {
+		return time.Duration(envelope.RetryAfter) * time.Second
+	}
+
+	if header := res.Header().Get("Retry-After"); header != "" {
+		if seconds, err := strconv.Atoi(header); err == nil {
+			return time.Duration(seconds) * time.Second
+		}
+		if date, err := http.ParseTime(header); err == nil && time.Now().Before(date) {
+			return time.Until(date)
+		}
+	}
+
+	if seconds, err := strconv.Atoi(res.Header().Get(api.RateLimitResetHeader)); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	return 0
+}
@@ -0,0 +1,182 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// DefaultConcurrentDownloads is the number of artifacts DownloadAll downloads at the same time by default
+const DefaultConcurrentDownloads = 4
+
+var (
+	UnsupportedPlatformError = errors.New("platform is not supported by the release")
+)
+
+// SetMaxConcurrentDownloads sets the number of artifacts DownloadAll downloads at the same time
+func (c *Client) SetMaxConcurrentDownloads(maxConcurrentDownloads int) *Client {
+	if maxConcurrentDownloads < 1 {
+		maxConcurrentDownloads = 1
+	}
+	c.maxConcurrentDownloads = maxConcurrentDownloads
+	return c
+}
+
+// DownloadAll downloads the artifacts of the given release for the given platforms (e.g. linux/amd64) into
+// the given directory, or all of its artifacts if no platforms are given. The artifacts are downloaded
+// concurrently, verified against their checksums (and signatures if the client has a public key), and stored
+// under their asset names. It returns the paths of the downloaded artifacts, or the first error once all
+// downloads stopped, since the remaining downloads are canceled after one fails.
+func (c *Client) DownloadAll(ctx context.Context, releaseName string, platforms []string, destDir string) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	client := c.WithContext(ctx)
+
+	metadata, err := client.GetReleaseMetadata(releaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	artifacts, err := selectArtifacts(metadata, platforms)
+	if err != nil {
+		return nil, err
+	}
+
+	err = os.MkdirAll(destDir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	paths := make([]string, len(artifacts))
+	slots := make(chan struct{}, c.maxConcurrentDownloads)
+	for i, artifact := range artifacts {
+		wg.Add(1)
+		go func(i int, artifact *v1.ReleaseArtifact) {
+			defer wg.Done()
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			path, err := client.downloadArtifact(metadata.Name, artifact, destDir)
+			if err != nil {
+				once.Do(func() {
+					firstErr = fmt.Errorf("error while downloading %s: %w", artifact.Name, err)
+					cancel()
+				})
+				return
+			}
+			paths[i] = path
+		}(i, artifact)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// selectArtifacts returns the artifacts of the given release for the given platforms, or all of its
+// artifacts if no platforms are given
+func selectArtifacts(metadata *v1.ReleaseMetadata, platforms []string) ([]*v1.ReleaseArtifact, error) {
+	if len(platforms) == 0 {
+		return metadata.Artifacts, nil
+	}
+
+	var artifacts []*v1.ReleaseArtifact
+	for _, platform := range platforms {
+		var found bool
+		for _, artifact := range metadata.Artifacts {
+			if strings.EqualFold(artifact.OS+"/"+artifact.Arch, platform) {
+				artifacts = append(artifacts, artifact)
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s (available: %s)", UnsupportedPlatformError, platform, strings.Join(metadata.Platforms, ", "))
+		}
+	}
+	return artifacts, nil
+}
+
+// downloadArtifact downloads the given artifact of the given release into the given directory after
+// verifying it against its checksum, and returns its path. The artifact is written under a temporary
+// name first, so that a partially written artifact is never left under its asset name.
+func (c *Client) downloadArtifact(releaseName string, artifact *v1.ReleaseArtifact, destDir string) (string, error) {
+	if artifact.Checksum == "" {
+		return "", fmt.Errorf("%w: %s", ChecksumNotFoundError, artifact.Name)
+	}
+
+	artifactPath := api.JoinPaths(releaseName, artifact.OS, artifact.Arch)
+	if artifact.Binary != "" {
+		artifactPath = api.JoinPaths(artifact.Binary, releaseName, artifact.OS, artifact.Arch)
+	}
+	body, err := c.getArtifact(releaseName, artifactPath)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(body)
+	if !strings.EqualFold(hex.EncodeToString(sum[:]), artifact.Checksum) {
+		return "", InvalidChecksumError
+	}
+
+	file, err := os.CreateTemp(destDir, "."+artifact.Name+"-*")
+	if err != nil {
+		return "", err
+	}
+	_, err = file.Write(body)
+	if err == nil {
+		err = file.Chmod(0644)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+
+	path := filepath.Join(destDir, filepath.Base(artifact.Name))
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", err
+	}
+	return path, nil
+}
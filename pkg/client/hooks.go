@@ -0,0 +1,201 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"github.com/go-resty/resty/v2"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Hooks are called while the client sends requests, so that applications embedding it can report
+// downloads to their own metrics or tracing. Any of the hooks may be nil.
+type Hooks struct {
+	// OnRequest is called before a request is sent for the first time
+	OnRequest func(request *RequestInfo)
+
+	// OnRetry is called before a request is retried with the number of the attempt that
+	// failed and how long the client waits before retrying it
+	OnRetry func(request *RequestInfo, attempt int, wait time.Duration)
+
+	// OnProgress is called while an artifact is downloaded with the number of bytes received so far
+	// and the size of the artifact, which is -1 if the server did not report it
+	OnProgress func(request *RequestInfo, received int64, total int64)
+
+	// OnComplete is called once a request completed or failed
+	OnComplete func(request *RequestInfo, result *Result)
+}
+
+// RequestInfo describes a request sent by the client
+type RequestInfo struct {
+	// Method is the HTTP method of the request
+	Method string
+
+	// URL is the URL the request is sent to
+	URL string
+}
+
+// Result describes the outcome of a request sent by the client
+type Result struct {
+	// StatusCode is the status code of the last response, it is 0 if no response was received
+	StatusCode int
+
+	// Bytes is the size of the body of the last response
+	Bytes int64
+
+	// Duration is the time from sending the request to receiving the whole body, including retries
+	Duration time.Duration
+
+	// Attempts is the number of times the request was sent
+	Attempts int
+
+	// Err is the error the request failed with, it is nil if a response was received
+	Err error
+}
+
+// hooksContextKey is the key of the request being reported to the hooks in the context of a request
+type hooksContextKey struct{}
+
+// SetHooks sets the hooks that are called while the client sends requests
+func (c *Client) SetHooks(hooks *Hooks) *Client {
+	c.hooks = hooks
+	return c
+}
+
+// get sends a GET request for the given path, reporting it to the hooks of the client
+func (c *Client) get(req *resty.Request, path string) (*resty.Response, error) {
+	if c.hooks == nil {
+		return req.Get(path)
+	}
+
+	info, start := c.startRequest(req, path)
+	res, err := req.Get(path)
+	result := &Result{
+		Duration: time.Since(start),
+		Attempts: req.Attempt,
+		Err:      err,
+	}
+	if res != nil {
+		result.StatusCode = res.StatusCode()
+		result.Bytes = int64(len(res.Body()))
+	}
+	c.completeRequest(info, result)
+	return res, err
+}
+
+// download sends a GET request for the given path like get and returns the body of the response,
+// additionally reporting the progress of receiving the body to the OnProgress hook
+func (c *Client) download(req *resty.Request, path string) ([]byte, *resty.Response, error) {
+	if c.hooks == nil || c.hooks.OnProgress == nil {
+		res, err := c.get(req, path)
+		if err != nil {
+			return nil, res, err
+		}
+		return res.Body(), res, nil
+	}
+
+	info, start := c.startRequest(req, path)
+	result := &Result{}
+	defer func() {
+		result.Duration = time.Since(start)
+		result.Attempts = req.Attempt
+		c.completeRequest(info, result)
+	}()
+
+	res, err := req.SetDoNotParseResponse(true).Get(path)
+	if res != nil && res.RawBody() != nil {
+		defer res.RawBody().Close()
+		result.StatusCode = res.StatusCode()
+	}
+	if err != nil {
+		result.Err = err
+		return nil, res, err
+	}
+
+	var body bytes.Buffer
+	reader := io.Reader(res.RawBody())
+	if res.StatusCode() == http.StatusOK {
+		reader = &progressReader{
+			reader: reader,
+			total:  res.RawResponse.ContentLength,
+			report: func(received int64, total int64) {
+				c.hooks.OnProgress(info, received, total)
+			},
+		}
+	}
+	result.Bytes, err = io.Copy(&body, reader)
+	if err != nil {
+		result.Err = err
+		return nil, res, err
+	}
+	// the body is set on the response as if it had been parsed, so that errors can report it
+	res.SetBody(body.Bytes())
+	return body.Bytes(), res, nil
+}
+
+// startRequest reports the given request to the OnRequest hook and returns the time it started at
+func (c *Client) startRequest(req *resty.Request, path string) (*RequestInfo, time.Time) {
+	info := &RequestInfo{
+		Method: http.MethodGet,
+		URL:    strings.TrimSuffix(c.base, "/") + path,
+	}
+	req.SetContext(context.WithValue(req.Context(), hooksContextKey{}, c.hooks))
+	if c.hooks.OnRequest != nil {
+		c.hooks.OnRequest(info)
+	}
+	return info, time.Now()
+}
+
+// completeRequest reports the given result to the OnComplete hook
+func (c *Client) completeRequest(info *RequestInfo, result *Result) {
+	if c.hooks.OnComplete != nil {
+		c.hooks.OnComplete(info, result)
+	}
+}
+
+// reportRetry reports the retry of the request of the given response to the OnRetry hook, if it has one
+func reportRetry(res *resty.Response, wait time.Duration) {
+	hooks, ok := res.Request.Context().Value(hooksContextKey{}).(*Hooks)
+	if !ok || hooks.OnRetry == nil {
+		return
+	}
+	hooks.OnRetry(&RequestInfo{
+		Method: res.Request.Method,
+		URL:    res.Request.URL,
+	}, res.Request.Attempt, wait)
+}
+
+// progressReader reports the number of bytes read from the underlying reader
+type progressReader struct {
+	reader   io.Reader
+	received int64
+	total    int64
+	report   func(received int64, total int64)
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.received += int64(n)
+		r.report(r.received, r.total)
+	}
+	return n, err
+}
@@ -0,0 +1,46 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// machineIDPaths are the files that contain a stable machine ID on linux and the BSDs
+var machineIDPaths = []string{"/etc/machine-id", "/var/lib/dbus/machine-id", "/etc/hostid"}
+
+// MachineID returns a stable, anonymized identifier for the current machine, which is derived
+// from the machine ID of the operating system (or the hostname if there is none) and the given
+// application name so that it can not be correlated across applications
+func MachineID(application string) string {
+	var id string
+	for _, path := range machineIDPaths {
+		if contents, err := os.ReadFile(path); err == nil {
+			id = strings.TrimSpace(string(contents))
+			if id != "" {
+				break
+			}
+		}
+	}
+	if id == "" {
+		id, _ = os.Hostname()
+	}
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(application+":"+id)))[:32]
+}
@@ -0,0 +1,173 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var (
+	InvalidPinError  = errors.New("invalid pin")
+	PinMismatchError = errors.New("server certificate does not match any pin")
+)
+
+// pins are the certificates and public keys the server is trusted by instead of the CA store of the host
+type pins struct {
+	// host is the hostname of the server, which is verified instead of the server name
+	// of the connection when connecting to an IP address (which is not sent as the server name)
+	host string
+
+	// publicKeys stores the SHA-256 hashes of the pinned SubjectPublicKeyInfos
+	publicKeys map[[sha256.Size]byte]struct{}
+
+	// certificates stores the raw pinned certificates
+	certificates [][]byte
+}
+
+// SetPinnedPublicKeys trusts the server only if its leaf certificate has one of the given public keys, or its
+// certificate chain verifies up to a presented certificate with one of them, instead of trusting the CA store of the host. The keys are base64 encoded SHA-256 hashes of their
+// SubjectPublicKeyInfo, optionally prefixed with sha256// as accepted by curl --pinnedpubkey, e.g. the output of:
+//
+//	openssl x509 -in cert.pem -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+//
+// The hostname of the server is still verified against its certificate.
+func (c *Client) SetPinnedPublicKeys(hashes ...string) (*Client, error) {
+	for _, hash := range hashes {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(hash, "sha256//"))
+		if err != nil || len(raw) != sha256.Size {
+			return nil, fmt.Errorf("%w: %s is not a base64 encoded sha256 hash", InvalidPinError, hash)
+		}
+		if c.pins.publicKeys == nil {
+			c.pins.publicKeys = make(map[[sha256.Size]byte]struct{})
+		}
+		c.pins.publicKeys[[sha256.Size]byte(raw)] = struct{}{}
+	}
+	c.pins.host = c.host()
+	c.client.SetTLSClientConfig(c.pins.tlsConfig())
+	return c, nil
+}
+
+// SetPinnedCertificates trusts the server only if it presents one of the given PEM encoded certificates
+// as its leaf certificate, instead of trusting the CA store of the host. Self-signed certificates may be pinned.
+// The hostname of the server is still verified against its certificate.
+func (c *Client) SetPinnedCertificates(certificates []byte) (*Client, error) {
+	var found bool
+	for {
+		var block *pem.Block
+		block, certificates = pem.Decode(certificates)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return nil, fmt.Errorf("%w: %s", InvalidPinError, err)
+		}
+		c.pins.certificates = append(c.pins.certificates, block.Bytes)
+		found = true
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: no PEM encoded certificates found", InvalidPinError)
+	}
+	c.pins.host = c.host()
+	c.client.SetTLSClientConfig(c.pins.tlsConfig())
+	return c, nil
+}
+
+// tlsConfig returns the TLS configuration that verifies the server against the pins instead of the CA store
+func (p *pins) tlsConfig() *tls.Config {
+	return &tls.Config{
+		// the chain is not verified against the CA store, VerifyConnection verifies it against the pins instead
+		InsecureSkipVerify: true,
+		VerifyConnection:   p.verify,
+	}
+}
+
+// verify accepts the connection if the leaf certificate is valid for the server name and either the leaf
+// certificate or its public key is pinned, or the leaf certificate verifies up to a presented certificate
+// with a pinned public key. Presented certificates are only trusted as issuers if their key is pinned,
+// so a forged leaf certificate can not borrow the pin of a genuine intermediate sent along with it.
+func (p *pins) verify(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return PinMismatchError
+	}
+	leaf := state.PeerCertificates[0]
+	serverName := state.ServerName
+	if serverName == "" {
+		serverName = p.host
+	}
+	if err := leaf.VerifyHostname(serverName); err != nil {
+		return err
+	}
+
+	for _, certificate := range p.certificates {
+		if bytes.Equal(certificate, leaf.Raw) {
+			return nil
+		}
+	}
+	if p.pinnedPublicKey(leaf) {
+		return nil
+	}
+
+	roots := x509.NewCertPool()
+	intermediates := x509.NewCertPool()
+	var pinned bool
+	for _, certificate := range state.PeerCertificates[1:] {
+		if p.pinnedPublicKey(certificate) {
+			roots.AddCert(certificate)
+			pinned = true
+		} else {
+			intermediates.AddCert(certificate)
+		}
+	}
+	if !pinned {
+		return PinMismatchError
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{
+		DNSName:       serverName,
+		Roots:         roots,
+		Intermediates: intermediates,
+	}); err != nil {
+		return fmt.Errorf("%w: %s", PinMismatchError, err)
+	}
+	return nil
+}
+
+// pinnedPublicKey returns true if the public key of the given certificate is pinned
+func (p *pins) pinnedPublicKey(certificate *x509.Certificate) bool {
+	_, ok := p.publicKeys[sha256.Sum256(certificate.RawSubjectPublicKeyInfo)]
+	return ok
+}
+
+// host returns the hostname of the server
+func (c *Client) host() string {
+	base, err := url.Parse(c.base)
+	if err != nil {
+		return ""
+	}
+	return base.Hostname()
+}
@@ -0,0 +1,117 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package client
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+const pinnedHost = "releases.example.com"
+
+// issueCertificate issues a certificate for the given template, signed by the given parent, or
+// self-signed if the parent is nil
+func issueCertificate(t *testing.T, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificate, key
+}
+
+func caTemplate(serial int64, name string) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber:          big.NewInt(serial),
+		Subject:               pkix.Name{CommonName: name},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+}
+
+func leafTemplate(serial int64) *x509.Certificate {
+	return &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: pinnedHost},
+		DNSNames:     []string{pinnedHost},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+}
+
+func TestPinnedPublicKeys(t *testing.T) {
+	root, rootKey := issueCertificate(t, caTemplate(1, "root"), nil, nil)
+	intermediate, intermediateKey := issueCertificate(t, caTemplate(2, "intermediate"), root, rootKey)
+	leaf, _ := issueCertificate(t, leafTemplate(3), intermediate, intermediateKey)
+	forged, _ := issueCertificate(t, leafTemplate(4), nil, nil)
+
+	pin := func(certificates ...*x509.Certificate) *pins {
+		p := &pins{host: pinnedHost, publicKeys: make(map[[sha256.Size]byte]struct{})}
+		for _, certificate := range certificates {
+			p.publicKeys[sha256.Sum256(certificate.RawSubjectPublicKeyInfo)] = struct{}{}
+		}
+		return p
+	}
+
+	tests := []struct {
+		name  string
+		pins  *pins
+		chain []*x509.Certificate
+		valid bool
+	}{
+		{name: "pinned intermediate", pins: pin(intermediate), chain: []*x509.Certificate{leaf, intermediate}, valid: true},
+		{name: "pinned root", pins: pin(root), chain: []*x509.Certificate{leaf, intermediate, root}, valid: true},
+		{name: "pinned leaf", pins: pin(leaf), chain: []*x509.Certificate{leaf}, valid: true},
+		{name: "unpinned chain", pins: pin(forged), chain: []*x509.Certificate{leaf, intermediate, root}},
+		{name: "forged leaf with pinned intermediate", pins: pin(intermediate), chain: []*x509.Certificate{forged, intermediate}},
+		{name: "forged leaf with pinned root", pins: pin(root), chain: []*x509.Certificate{forged, intermediate, root}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.pins.verify(tls.ConnectionState{PeerCertificates: test.chain})
+			if test.valid && err != nil {
+				t.Fatalf("expected the chain to be accepted, got %v", err)
+			}
+			if !test.valid && !errors.Is(err, PinMismatchError) {
+				t.Fatalf("expected %v, got %v", PinMismatchError, err)
+			}
+		})
+	}
+}
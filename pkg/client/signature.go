@@ -0,0 +1,184 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package client
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"golang.org/x/crypto/blake2b"
+	"strings"
+)
+
+var (
+	UnsupportedPublicKeyError = errors.New("unsupported public key")
+	InvalidSignatureError     = errors.New("invalid signature")
+)
+
+// SignatureError is returned when a downloaded artifact does not carry a valid signature
+// made with the private key of the public key the client was configured with
+type SignatureError struct {
+	// ReleaseName is the release the artifact was downloaded for
+	ReleaseName string
+
+	// Err is the reason the signature could not be verified
+	Err error
+}
+
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("error while verifying signature for release %s: %s", e.ReleaseName, e.Err)
+}
+
+func (e *SignatureError) Unwrap() error {
+	return e.Err
+}
+
+// verifier verifies signatures made with the private key of a public key
+type verifier interface {
+	// format returns the format of the signatures the verifier accepts (e.g. minisign)
+	format() string
+
+	// verify returns an error if the given signature of the given message is not valid
+	verify(message []byte, signature []byte) error
+}
+
+// parsePublicKey parses a cosign public key in PEM format or a minisign public key,
+// which may be given either as the contents of its .pub file or its base64 encoded key alone
+func parsePublicKey(key string) (verifier, error) {
+	key = strings.TrimSpace(key)
+	if strings.HasPrefix(key, "-----BEGIN") {
+		return parseCosignKey(key)
+	}
+	return parseMinisignKey(key)
+}
+
+// minisignKey verifies minisign signatures, see https://jedisct1.github.io/minisign/
+type minisignKey struct {
+	keyID     []byte
+	publicKey ed25519.PublicKey
+}
+
+func parseMinisignKey(key string) (*minisignKey, error) {
+	lines := strings.Split(key, "\n")
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(lines[len(lines)-1]))
+	if err != nil || len(raw) != 2+8+ed25519.PublicKeySize || string(raw[:2]) != "Ed" {
+		return nil, fmt.Errorf("%w: not a cosign or minisign public key", UnsupportedPublicKeyError)
+	}
+	return &minisignKey{
+		keyID:     raw[2:10],
+		publicKey: raw[10:],
+	}, nil
+}
+
+func (k *minisignKey) format() string {
+	return api.SignatureFormatMinisign
+}
+
+func (k *minisignKey) verify(message []byte, signature []byte) error {
+	lines := strings.Split(strings.ReplaceAll(string(signature), "\r", ""), "\n")
+	if len(lines) < 4 {
+		return fmt.Errorf("%w: malformed minisign signature", InvalidSignatureError)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(lines[1])
+	if err != nil || len(raw) != 2+8+ed25519.SignatureSize {
+		return fmt.Errorf("%w: malformed minisign signature", InvalidSignatureError)
+	}
+	algorithm, keyID, sig := string(raw[:2]), raw[2:10], raw[10:]
+	if !bytes.Equal(keyID, k.keyID) {
+		return fmt.Errorf("%w: signed with another key (%X)", InvalidSignatureError, keyID)
+	}
+
+	switch algorithm {
+	case "Ed":
+	case "ED":
+		// prehashed signatures sign the BLAKE2b-512 hash of the message instead of the message itself
+		hash := blake2b.Sum512(message)
+		message = hash[:]
+	default:
+		return fmt.Errorf("%w: unsupported minisign algorithm %q", InvalidSignatureError, algorithm)
+	}
+	if !ed25519.Verify(k.publicKey, message, sig) {
+		return InvalidSignatureError
+	}
+
+	// the global signature covers the trusted comment, which would otherwise be unauthenticated
+	trustedComment, ok := strings.CutPrefix(lines[2], "trusted comment: ")
+	if !ok {
+		return fmt.Errorf("%w: malformed minisign signature", InvalidSignatureError)
+	}
+	globalSig, err := base64.StdEncoding.DecodeString(lines[3])
+	if err != nil || !ed25519.Verify(k.publicKey, append(sig, trustedComment...), globalSig) {
+		return fmt.Errorf("%w: trusted comment does not match", InvalidSignatureError)
+	}
+	return nil
+}
+
+// cosignKey verifies the signatures made by cosign sign-blob, which are base64 encoded
+type cosignKey struct {
+	publicKey interface{}
+}
+
+func parseCosignKey(key string) (*cosignKey, error) {
+	block, _ := pem.Decode([]byte(key))
+	if block == nil {
+		return nil, fmt.Errorf("%w: malformed PEM block", UnsupportedPublicKeyError)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", UnsupportedPublicKeyError, err)
+	}
+	switch publicKey.(type) {
+	case *ecdsa.PublicKey, ed25519.PublicKey:
+		return &cosignKey{
+			publicKey: publicKey,
+		}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", UnsupportedPublicKeyError, publicKey)
+	}
+}
+
+func (k *cosignKey) format() string {
+	return api.SignatureFormatCosign
+}
+
+func (k *cosignKey) verify(message []byte, signature []byte) error {
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("%w: malformed cosign signature", InvalidSignatureError)
+	}
+
+	var valid bool
+	switch publicKey := k.publicKey.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(message)
+		valid = ecdsa.VerifyASN1(publicKey, digest[:], sig)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(publicKey, message, sig)
+	}
+	if !valid {
+		return InvalidSignatureError
+	}
+	return nil
+}
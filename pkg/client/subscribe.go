@@ -0,0 +1,187 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// errEventsUnsupported is returned by readEvents if the server does not have an event stream
+var errEventsUnsupported = errors.New("event stream is not supported by the server")
+
+const (
+	// DefaultPollInterval is how often Subscribe checks the latest release by default
+	DefaultPollInterval = time.Minute
+
+	// defaultReconnectDelay is how long Subscribe waits before reconnecting to the event stream,
+	// unless the server asks for a different delay
+	defaultReconnectDelay = time.Second * 5
+)
+
+// ReleasePublished notifies a subscriber that a different release is now the latest release for this client
+type ReleasePublished struct {
+	// Release is the latest release for this client
+	Release *LatestRelease
+
+	// Replaces is the name of the release the subscriber was notified about before,
+	// it is empty in the first notification
+	Replaces string
+}
+
+// SetPollInterval sets how often Subscribe checks the latest release
+func (c *Client) SetPollInterval(pollInterval time.Duration) *Client {
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+	c.pollInterval = pollInterval
+	return c
+}
+
+// Subscribe returns a channel that receives a notification with the latest release for this client once
+// it is known, and again whenever it changes, until the given context is canceled and the channel is closed.
+//
+// The latest release is checked as soon as the event stream of the server announces a new release, and
+// every poll interval regardless, which catches up on rollouts progressing and on events missed while the
+// stream reconnects. Servers without an event stream are only polled. Requests that fail are tried again
+// at the next poll interval.
+func (c *Client) Subscribe(ctx context.Context) <-chan *ReleasePublished {
+	notifications := make(chan *ReleasePublished)
+	client := c.WithContext(ctx)
+	changed := make(chan struct{}, 1)
+	go client.watchEvents(ctx, changed)
+	go client.pollLatestRelease(ctx, changed, notifications)
+	return notifications
+}
+
+// pollLatestRelease sends a notification to the given channel whenever the latest release for this client
+// changes, checking it every poll interval and whenever the changed channel receives, until the given
+// context is canceled
+func (c *Client) pollLatestRelease(ctx context.Context, changed <-chan struct{}, notifications chan<- *ReleasePublished) {
+	defer close(notifications)
+
+	poll := time.NewTicker(c.pollInterval)
+	defer poll.Stop()
+
+	var current string
+	for {
+		latestRelease, err := c.GetLatestRelease()
+		if err == nil && latestRelease.Name != current {
+			select {
+			case notifications <- &ReleasePublished{Release: latestRelease, Replaces: current}:
+				current = latestRelease.Name
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		select {
+		case <-changed:
+		case <-poll.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// watchEvents signals the given channel whenever the event stream of the server announces a new latest
+// release, reconnecting to the stream after it ends until the given context is canceled. It returns
+// early if the server does not have an event stream.
+func (c *Client) watchEvents(ctx context.Context, changed chan<- struct{}) {
+	var lastEventID string
+	for {
+		reconnectDelay, err := c.readEvents(&lastEventID, changed)
+		if errors.Is(err, errEventsUnsupported) {
+			return
+		}
+
+		timer := time.NewTimer(reconnectDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// readEvents reads the event stream of the server until it ends, signaling the given channel for every
+// latest release event and recording the ID of the last event received so that the stream resumes after
+// it when reconnecting. It returns how long to wait before reconnecting.
+func (c *Client) readEvents(lastEventID *string, changed chan<- struct{}) (time.Duration, error) {
+	reconnectDelay := defaultReconnectDelay
+
+	req := c.request().
+		SetDoNotParseResponse(true).
+		SetHeader("Accept", "text/event-stream")
+	if *lastEventID != "" {
+		req.SetHeader("Last-Event-ID", *lastEventID)
+	}
+	res, err := req.Get(api.JoinPaths(api.V1Path, api.EventsPath))
+	if err != nil {
+		return reconnectDelay, fmt.Errorf("error while subscribing to events: %w", err)
+	}
+	body := res.RawBody()
+	defer body.Close()
+
+	switch res.StatusCode() {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		return reconnectDelay, errEventsUnsupported
+	default:
+		return reconnectDelay, fmt.Errorf("invalid response status code: %d", res.StatusCode())
+	}
+
+	var event, id string
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		field, value, _ := strings.Cut(scanner.Text(), ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "":
+			// a blank line dispatches the event, lines starting with a colon are comments
+			if scanner.Text() != "" {
+				continue
+			}
+			if event == v1.EventLatestRelease {
+				*lastEventID = id
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+			}
+			event, id = "", ""
+		case "event":
+			event = value
+		case "id":
+			id = value
+		case "retry":
+			if milliseconds, err := strconv.Atoi(value); err == nil && milliseconds > 0 {
+				reconnectDelay = time.Duration(milliseconds) * time.Millisecond
+			}
+		}
+	}
+	return reconnectDelay, scanner.Err()
+}
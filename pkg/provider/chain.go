@@ -0,0 +1,178 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var _ Provider = (*Chain)(nil)
+var _ Checker = (*Chain)(nil)
+
+var (
+	ErrNoProviders = errors.New("no providers configured")
+)
+
+// Chain is a Provider that combines an ordered list of providers, releases and assets
+// missing from (or erroring in) a provider are taken from the next one in the list
+type Chain struct {
+	providers []Provider
+
+	mu sync.RWMutex
+
+	// fallbacks stores the alternative copies of every asset returned
+	// by the last call to ListReleases, in provider order
+	fallbacks map[*Asset][]*Asset
+}
+
+func NewChain(providers ...Provider) *Chain {
+	return &Chain{
+		providers: providers,
+		fallbacks: make(map[*Asset][]*Asset),
+	}
+}
+
+func (c *Chain) Name() string {
+	names := make([]string, 0, len(c.providers))
+	for _, provider := range c.providers {
+		names = append(names, provider.Name())
+	}
+	return strings.Join(names, ", ")
+}
+
+// ListReleases returns the merged releases of all providers that did not error, where
+// earlier providers take precedence over later ones
+func (c *Chain) ListReleases(ctx context.Context) ([]*Release, error) {
+	if len(c.providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	var merged []*Release
+	var errs []error
+	releaseIndex := make(map[string]*Release)
+	assetIndex := make(map[string]*Asset)
+	fallbacks := make(map[*Asset][]*Asset)
+	appended := false
+
+	for i, provider := range c.providers {
+		releases, err := provider.ListReleases(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			continue
+		}
+
+		listed := make(map[string]struct{}, len(releases))
+		for _, release := range releases {
+			for _, asset := range release.Assets {
+				asset.source = provider
+			}
+
+			// releases with the same name in a single provider are different releases (e.g. two
+			// tags with the same title), they are passed on as they are for the cache to resolve
+			releaseName := strings.ToLower(release.Name)
+			if _, ok := listed[releaseName]; ok {
+				merged = append(merged, release)
+				continue
+			}
+			listed[releaseName] = struct{}{}
+
+			existing, ok := releaseIndex[releaseName]
+			if !ok {
+				releaseIndex[releaseName] = release
+				merged = append(merged, release)
+				for _, asset := range release.Assets {
+					assetIndex[releaseName+"/"+strings.ToLower(asset.Name)] = asset
+				}
+				appended = appended || i > 0
+				continue
+			}
+
+			for _, asset := range release.Assets {
+				key := releaseName + "/" + strings.ToLower(asset.Name)
+				if primary, ok := assetIndex[key]; ok {
+					fallbacks[primary] = append(fallbacks[primary], asset)
+					continue
+				}
+				assetIndex[key] = asset
+				existing.Assets = append(existing.Assets, asset)
+			}
+		}
+	}
+
+	if merged == nil && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	// releases that only exist in a fallback provider have no well-defined
+	// position, so the merged list is ordered by version instead
+	if appended {
+		sort.SliceStable(merged, func(i, j int) bool {
+			return utils.CompareVersions(merged[i].Name, merged[j].Name) > 0
+		})
+	}
+
+	c.mu.Lock()
+	c.fallbacks = fallbacks
+	c.mu.Unlock()
+
+	return merged, nil
+}
+
+// DownloadAsset downloads the given asset from the provider it was listed by, falling back
+// to the copies of the asset in later providers if the download fails
+func (c *Chain) DownloadAsset(ctx context.Context, asset *Asset) (io.ReadCloser, error) {
+	c.mu.RLock()
+	candidates := append([]*Asset{asset}, c.fallbacks[asset]...)
+	c.mu.RUnlock()
+
+	var errs []error
+	for _, candidate := range candidates {
+		if candidate.source == nil {
+			continue
+		}
+		reader, err := candidate.source.DownloadAsset(ctx, candidate)
+		if err == nil {
+			return reader, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", candidate.source.Name(), err))
+	}
+
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("asset %s was not listed by any provider", asset.Name)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// Check checks every provider in the chain that implements Checker
+func (c *Chain) Check(ctx context.Context) error {
+	var errs []error
+	for _, provider := range c.providers {
+		if checker, ok := provider.(Checker); ok {
+			if err := checker.Check(ctx); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
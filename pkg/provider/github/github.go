@@ -0,0 +1,224 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/go-github/v55/github"
+	"github.com/loopholelabs/releaser/pkg/provider"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var _ provider.Provider = (*GitHub)(nil)
+var _ provider.Checker = (*GitHub)(nil)
+
+var (
+	ErrRepositoryNotFound = errors.New("repository does not exist or the token does not have access to it")
+	ErrInvalidToken       = errors.New("github token is invalid or expired")
+	ErrRateLimited        = errors.New("github rate limit exceeded, configure a token to raise it")
+)
+
+// GitHub is a provider.Provider backed by GitHub Releases
+type GitHub struct {
+	client *github.Client
+	owner  string
+	repo   string
+
+	keyOnTitle bool
+
+	// downloadClient follows the redirects of asset downloads to the storage backend
+	downloadClient *http.Client
+
+	// downloads limits the number of concurrent asset downloads, it is nil if they are not limited
+	downloads chan struct{}
+}
+
+func New(client *github.Client, owner string, repo string) *GitHub {
+	return &GitHub{
+		client: client,
+		owner:  owner,
+		repo:   repo,
+
+		downloadClient: http.DefaultClient,
+	}
+}
+
+// SetKeyOnTitle names releases after their title instead of their tag name,
+// releases without a title are still named after their tag name
+func (g *GitHub) SetKeyOnTitle(keyOnTitle bool) *GitHub {
+	g.keyOnTitle = keyOnTitle
+	return g
+}
+
+// SetDownloadClient sets the HTTP client used to download assets after they have been
+// redirected from the GitHub API to the storage backend
+func (g *GitHub) SetDownloadClient(downloadClient *http.Client) *GitHub {
+	g.downloadClient = downloadClient
+	return g
+}
+
+// SetMaxConcurrentDownloads limits the number of assets downloaded at the same time, including
+// downloads that are being proxied to clients, to avoid GitHub's secondary rate limits.
+// A limit of 0 or less removes the limit.
+func (g *GitHub) SetMaxConcurrentDownloads(maxConcurrentDownloads int) *GitHub {
+	g.downloads = nil
+	if maxConcurrentDownloads > 0 {
+		g.downloads = make(chan struct{}, maxConcurrentDownloads)
+	}
+	return g
+}
+
+func (g *GitHub) Name() string {
+	return fmt.Sprintf("github.com/%s/%s", g.owner, g.repo)
+}
+
+func (g *GitHub) ListReleases(ctx context.Context) ([]*provider.Release, error) {
+	releases, _, err := g.client.Repositories.ListReleases(ctx, g.owner, g.repo, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	providerReleases := make([]*provider.Release, 0, len(releases))
+	for _, release := range releases {
+		providerRelease := &provider.Release{
+			Name:        release.GetTagName(),
+			Title:       release.GetName(),
+			Tag:         release.GetTagName(),
+			PublishedAt: release.GetPublishedAt().Time,
+			Assets:      make([]*provider.Asset, 0, len(release.Assets)),
+		}
+		if g.keyOnTitle && providerRelease.Title != "" {
+			providerRelease.Name = providerRelease.Title
+		}
+		for _, asset := range release.Assets {
+			providerRelease.Assets = append(providerRelease.Assets, &provider.Asset{
+				ID:        strconv.FormatInt(asset.GetID(), 10),
+				Name:      asset.GetName(),
+				Size:      asset.GetSize(),
+				URL:       asset.GetBrowserDownloadURL(),
+				UpdatedAt: asset.GetUpdatedAt().Time,
+			})
+		}
+		providerReleases = append(providerReleases, providerRelease)
+	}
+
+	return providerReleases, nil
+}
+
+func (g *GitHub) DownloadAsset(ctx context.Context, asset *provider.Asset) (io.ReadCloser, error) {
+	assetID, err := strconv.ParseInt(asset.ID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid asset id %s: %w", asset.ID, err)
+	}
+
+	if g.downloads == nil {
+		assetReader, _, err := g.client.Repositories.DownloadReleaseAsset(ctx, g.owner, g.repo, assetID, g.downloadClient)
+		if err != nil {
+			return nil, rateLimitError(err)
+		}
+		return assetReader, nil
+	}
+
+	select {
+	case g.downloads <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	assetReader, _, err := g.client.Repositories.DownloadReleaseAsset(ctx, g.owner, g.repo, assetID, g.downloadClient)
+	if err != nil {
+		<-g.downloads
+		return nil, rateLimitError(err)
+	}
+
+	return &limitedReader{ReadCloser: assetReader, downloads: g.downloads}, nil
+}
+
+// limitedReader releases its download slot once it is closed
+type limitedReader struct {
+	io.ReadCloser
+	downloads chan struct{}
+	once      sync.Once
+}
+
+func (r *limitedReader) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		<-r.downloads
+	})
+	return err
+}
+
+// rateLimitError converts the rate limit errors of the GitHub API into a *provider.RateLimitError
+// that reports when the request may be retried, other errors are returned unchanged
+func rateLimitError(err error) error {
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return &provider.RateLimitError{RetryAfter: time.Until(rateLimitErr.Rate.Reset.Time), Err: err}
+	}
+
+	var abuseRateLimitErr *github.AbuseRateLimitError
+	if errors.As(err, &abuseRateLimitErr) {
+		retryAfter := provider.DefaultRetryAfter
+		if abuseRateLimitErr.RetryAfter != nil {
+			retryAfter = *abuseRateLimitErr.RetryAfter
+		}
+		return &provider.RateLimitError{RetryAfter: retryAfter, Err: err}
+	}
+
+	var responseErr *github.ErrorResponse
+	if errors.As(err, &responseErr) && responseErr.Response != nil && responseErr.Response.StatusCode == http.StatusTooManyRequests {
+		retryAfter := provider.DefaultRetryAfter
+		if seconds, err := strconv.Atoi(responseErr.Response.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+		return &provider.RateLimitError{RetryAfter: retryAfter, Err: err}
+	}
+
+	return err
+}
+
+// Check verifies that the repository exists and that the configured token can access it
+func (g *GitHub) Check(ctx context.Context) error {
+	_, _, err := g.client.Repositories.Get(ctx, g.owner, g.repo)
+	if err == nil {
+		return nil
+	}
+
+	var rateLimitErr *github.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return fmt.Errorf("%w: %s", ErrRateLimited, err)
+	}
+
+	var responseErr *github.ErrorResponse
+	if errors.As(err, &responseErr) && responseErr.Response != nil {
+		switch responseErr.Response.StatusCode {
+		case http.StatusNotFound:
+			return fmt.Errorf("%w: %s/%s", ErrRepositoryNotFound, g.owner, g.repo)
+		case http.StatusUnauthorized:
+			return ErrInvalidToken
+		}
+	}
+
+	return err
+}
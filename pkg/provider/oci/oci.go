@@ -0,0 +1,97 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/provider"
+	"github.com/loopholelabs/releaser/pkg/reference"
+	"github.com/loopholelabs/releaser/pkg/registry"
+	"io"
+	"sort"
+	"time"
+)
+
+var _ provider.Provider = (*OCI)(nil)
+
+// OCI is a provider.Provider backed by OCI artifacts pushed to a registry with ORAS,
+// where every tag is a release and every titled layer is a release asset
+type OCI struct {
+	client     *registry.Client
+	repository *reference.Repository
+}
+
+func New(client *registry.Client, repository *reference.Repository) *OCI {
+	return &OCI{
+		client:     client,
+		repository: repository,
+	}
+}
+
+func (o *OCI) Name() string {
+	return o.repository.String()
+}
+
+func (o *OCI) ListReleases(ctx context.Context) ([]*provider.Release, error) {
+	tags, err := o.client.ListTags(ctx, o.repository)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing tags: %w", err)
+	}
+
+	// registries do not record when a tag was pushed, so releases are ordered by version
+	sort.Slice(tags, func(i, j int) bool {
+		return utils.CompareVersions(tags[i], tags[j]) > 0
+	})
+
+	releases := make([]*provider.Release, 0, len(tags))
+	for _, tag := range tags {
+		manifest, err := o.client.GetManifest(ctx, o.repository, tag)
+		if err != nil {
+			return nil, fmt.Errorf("error while getting manifest for tag %s: %w", tag, err)
+		}
+
+		release := &provider.Release{
+			Name:   tag,
+			Title:  tag,
+			Tag:    tag,
+			Assets: make([]*provider.Asset, 0, len(manifest.Layers)),
+		}
+		if created, err := time.Parse(time.RFC3339, manifest.Annotations[registry.CreatedAnnotation]); err == nil {
+			release.PublishedAt = created
+		}
+		for _, layer := range manifest.Layers {
+			title := layer.Annotations[registry.TitleAnnotation]
+			if title == "" {
+				continue
+			}
+			release.Assets = append(release.Assets, &provider.Asset{
+				ID:   layer.Digest,
+				Name: title,
+				Size: layer.Size,
+			})
+		}
+		releases = append(releases, release)
+	}
+
+	return releases, nil
+}
+
+func (o *OCI) DownloadAsset(ctx context.Context, asset *provider.Asset) (io.ReadCloser, error) {
+	return o.client.GetBlob(ctx, o.repository, asset.ID)
+}
@@ -0,0 +1,115 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultRetryAfter is how long clients are asked to wait when a provider is throttled
+// without reporting when its requests may be retried
+const DefaultRetryAfter = time.Minute
+
+// RateLimitError is returned when the upstream of a provider throttles its requests
+type RateLimitError struct {
+	// RetryAfter is how long to wait before the request may be retried
+	RetryAfter time.Duration
+
+	// Err is the error reported by the upstream of the provider
+	Err error
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited for %s: %s", e.RetryAfter, e.Err)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return e.Err
+}
+
+// Asset is a single file attached to a release
+type Asset struct {
+	// ID identifies the asset within its provider
+	ID string
+
+	// Name is the file name of the asset
+	Name string
+
+	// Size is the size of the asset in bytes
+	Size int
+
+	// URL is a public download URL for the asset, it is empty if the
+	// asset can only be downloaded through the provider
+	URL string
+
+	// UpdatedAt is when the asset was last uploaded, it is zero if the provider does not report it
+	UpdatedAt time.Time
+
+	// source is the provider that listed the asset when it was listed through a Chain
+	source Provider
+}
+
+// Revision identifies the contents of the asset, it changes when the asset is replaced
+// (e.g. deleted and uploaded again under the same name) without publishing a new release
+func (a *Asset) Revision() string {
+	if a.UpdatedAt.IsZero() {
+		return a.ID
+	}
+	return a.ID + "@" + a.UpdatedAt.UTC().Format(time.RFC3339Nano)
+}
+
+// Release is a single release and its assets
+type Release struct {
+	// Name is the name of the release (e.g. v1.2.3)
+	Name string
+
+	// Title is the human-readable title of the release, it is only used for display
+	Title string
+
+	// Tag is the tag the release was published under, it differs from Name when
+	// releases are named after their title and is empty if the provider has no tags
+	Tag string
+
+	// PublishedAt is when the release was published, it is zero if the provider does not report it
+	PublishedAt time.Time
+
+	// Assets are the files attached to the release
+	Assets []*Asset
+}
+
+// Provider is a source of releases and their assets
+type Provider interface {
+	// Name returns a human-readable name for the provider
+	Name() string
+
+	// ListReleases returns all releases, newest first
+	ListReleases(ctx context.Context) ([]*Release, error)
+
+	// DownloadAsset returns a reader for the contents of the given asset,
+	// which must be closed by the caller
+	DownloadAsset(ctx context.Context, asset *Asset) (io.ReadCloser, error)
+}
+
+// Checker is implemented by providers that can verify their configuration (e.g. that a
+// repository exists and is accessible) and report a specific error when it is not
+type Checker interface {
+	// Check returns an error describing exactly which assumption about the provider does not hold
+	Check(ctx context.Context) error
+}
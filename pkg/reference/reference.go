@@ -0,0 +1,82 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+// Package reference parses image repository references. It only depends on the standard library so
+// that both the config and the registry client can use it.
+package reference
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidRepository = errors.New("invalid image repository")
+)
+
+const (
+	dockerHubRegistry = "registry-1.docker.io"
+	dockerHubDomain   = "docker.io"
+	dockerHubLibrary  = "library"
+)
+
+// Repository is a parsed image repository reference
+type Repository struct {
+	// Domain is the registry domain as users refer to it (e.g. ghcr.io or docker.io)
+	Domain string
+
+	// Registry is the host the registry API is served from
+	Registry string
+
+	// Name is the repository name within the registry (e.g. owner/tool)
+	Name string
+}
+
+// String returns the fully qualified repository reference
+func (r *Repository) String() string {
+	return r.Domain + "/" + r.Name
+}
+
+// ParseRepository parses an image repository such as ghcr.io/owner/tool or owner/tool
+func ParseRepository(repository string) (*Repository, error) {
+	if repository == "" || strings.ContainsAny(repository, "@ ") {
+		return nil, ErrInvalidRepository
+	}
+
+	domain, name, found := strings.Cut(repository, "/")
+	if !found || (!strings.ContainsAny(domain, ".:") && domain != "localhost") {
+		domain, name = dockerHubDomain, repository
+	}
+
+	// the repository must not include a tag
+	if name == "" || strings.Contains(name, ":") {
+		return nil, ErrInvalidRepository
+	}
+
+	registry := domain
+	if domain == dockerHubDomain {
+		registry = dockerHubRegistry
+		if !strings.Contains(name, "/") {
+			name = dockerHubLibrary + "/" + name
+		}
+	}
+
+	return &Repository{
+		Domain:   domain,
+		Registry: registry,
+		Name:     name,
+	}, nil
+}
@@ -0,0 +1,312 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/loopholelabs/releaser/pkg/reference"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrNotFound      = errors.New("image not found")
+	ErrMissingDigest = errors.New("registry did not return a digest")
+)
+
+const (
+	digestHeader       = "Docker-Content-Digest"
+	authenticateHeader = "WWW-Authenticate"
+
+	// digestTTL is how long a resolved digest is cached for
+	digestTTL = time.Minute
+)
+
+const ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+
+// manifestMediaTypes are the manifest types accepted when resolving a digest
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	ociManifestMediaType,
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// TitleAnnotation is the annotation ORAS uses to store the file name of a layer
+const TitleAnnotation = "org.opencontainers.image.title"
+
+// CreatedAnnotation is the annotation ORAS uses to store when a manifest was pushed, in RFC 3339 format
+const CreatedAnnotation = "org.opencontainers.image.created"
+
+// Descriptor describes a blob referenced by a manifest
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int               `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is an OCI image manifest
+type Manifest struct {
+	MediaType    string            `json:"mediaType"`
+	ArtifactType string            `json:"artifactType,omitempty"`
+	Layers       []*Descriptor     `json:"layers"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+}
+
+type cachedDigest struct {
+	digest  string
+	expires time.Time
+}
+
+// Client resolves image tags to digests using the OCI distribution API
+type Client struct {
+	mu             sync.Mutex
+	digests        map[string]cachedDigest
+	authorizations map[string]string
+
+	username string
+	password string
+
+	client *http.Client
+}
+
+func New(client *http.Client) *Client {
+	return &Client{
+		digests:        make(map[string]cachedDigest),
+		authorizations: make(map[string]string),
+		client:         client,
+	}
+}
+
+// SetCredentials sets the credentials used to authenticate with the registry,
+// without them only anonymous access is possible
+func (c *Client) SetCredentials(username string, password string) {
+	c.username = username
+	c.password = password
+}
+
+// ResolveDigest returns the digest of the manifest for the given tag
+func (c *Client) ResolveDigest(ctx context.Context, repository *reference.Repository, tag string) (string, error) {
+	key := repository.String() + ":" + tag
+	c.mu.Lock()
+	cached, ok := c.digests[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.digest, nil
+	}
+
+	res, err := c.do(ctx, http.MethodHead, repository, "manifests/"+url.PathEscape(tag), manifestMediaTypes)
+	if err != nil {
+		return "", err
+	}
+	_ = res.Body.Close()
+
+	digest := res.Header.Get(digestHeader)
+	if digest == "" {
+		return "", ErrMissingDigest
+	}
+
+	c.mu.Lock()
+	c.digests[key] = cachedDigest{digest: digest, expires: time.Now().Add(digestTTL)}
+	c.mu.Unlock()
+
+	return digest, nil
+}
+
+// ListTags returns all tags of the given repository
+func (c *Client) ListTags(ctx context.Context, repository *reference.Repository) ([]string, error) {
+	res, err := c.do(ctx, http.MethodGet, repository, "tags/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&tags); err != nil {
+		return nil, fmt.Errorf("error while decoding tags: %w", err)
+	}
+
+	return tags.Tags, nil
+}
+
+// GetManifest returns the image manifest for the given tag or digest
+func (c *Client) GetManifest(ctx context.Context, repository *reference.Repository, reference string) (*Manifest, error) {
+	res, err := c.do(ctx, http.MethodGet, repository, "manifests/"+url.PathEscape(reference), []string{ociManifestMediaType})
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	manifest := new(Manifest)
+	if err = json.NewDecoder(res.Body).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("error while decoding manifest: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// GetBlob returns a reader for the blob with the given digest, which must be closed by the caller
+func (c *Client) GetBlob(ctx context.Context, repository *reference.Repository, digest string) (io.ReadCloser, error) {
+	res, err := c.do(ctx, http.MethodGet, repository, "blobs/"+digest, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.Body, nil
+}
+
+// do performs a request against the registry API of the given repository, authenticating
+// if the registry requests it, and returns the response if it was successful
+func (c *Client) do(ctx context.Context, method string, repository *reference.Repository, path string, accept []string) (*http.Response, error) {
+	requestURL := fmt.Sprintf("https://%s/v2/%s/%s", repository.Registry, repository.Name, path)
+
+	c.mu.Lock()
+	authorization := c.authorizations[repository.String()]
+	c.mu.Unlock()
+
+	res, err := c.request(ctx, method, requestURL, accept, authorization)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized {
+		_ = res.Body.Close()
+		authorization, err = c.authorize(ctx, res.Header.Get(authenticateHeader))
+		if err != nil {
+			return nil, err
+		}
+
+		c.mu.Lock()
+		c.authorizations[repository.String()] = authorization
+		c.mu.Unlock()
+
+		res, err = c.request(ctx, method, requestURL, accept, authorization)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch res.StatusCode {
+	case http.StatusOK:
+		return res, nil
+	case http.StatusNotFound:
+		_ = res.Body.Close()
+		return nil, ErrNotFound
+	default:
+		_ = res.Body.Close()
+		return nil, fmt.Errorf("invalid response status code from registry: %d", res.StatusCode)
+	}
+}
+
+func (c *Client) request(ctx context.Context, method string, requestURL string, accept []string, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(accept) > 0 {
+		req.Header.Set("Accept", strings.Join(accept, ", "))
+	}
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error while requesting %s: %w", requestURL, err)
+	}
+	return res, nil
+}
+
+// authorize answers the given WWW-Authenticate challenge and returns the value for the Authorization header
+func (c *Client) authorize(ctx context.Context, challenge string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	if strings.EqualFold(scheme, "basic") && c.username != "" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.username+":"+c.password)), nil
+	}
+
+	if !strings.EqualFold(scheme, "bearer") {
+		return "", fmt.Errorf("unsupported registry authentication scheme %q", scheme)
+	}
+
+	values := make(map[string]string)
+	for _, param := range strings.Split(params, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if found {
+			values[key] = strings.Trim(value, `"`)
+		}
+	}
+
+	realm, err := url.Parse(values["realm"])
+	if err != nil || realm.Host == "" {
+		return "", fmt.Errorf("invalid registry authentication realm %q", values["realm"])
+	}
+
+	query := realm.Query()
+	for _, key := range []string{"service", "scope"} {
+		if values[key] != "" {
+			query.Set(key, values[key])
+		}
+	}
+	realm.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error while requesting registry token: %w", err)
+	}
+	defer func() {
+		_ = res.Body.Close()
+	}()
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("invalid response status code from registry token endpoint: %d", res.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("error while decoding registry token: %w", err)
+	}
+
+	if token.Token != "" {
+		return "Bearer " + token.Token, nil
+	}
+	return "Bearer " + token.AccessToken, nil
+}
@@ -0,0 +1,116 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package selfupdate
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultMaxStarts = 3
+	DefaultWindow    = time.Minute * 5
+)
+
+// CrashLoop detects an executable that keeps crashing after an update. Every start is recorded in a
+// state file, and if MaxStarts starts happen within Window without a call to Healthy, OnCrashLoop is called.
+type CrashLoop struct {
+	// StatePath is the file the recent starts are recorded in
+	StatePath string
+
+	// MaxStarts is the number of unhealthy starts within Window that is considered a crash loop
+	MaxStarts int
+
+	// Window is the time within which MaxStarts unhealthy starts are considered a crash loop
+	Window time.Duration
+
+	// OnCrashLoop is called when a crash loop is detected, RollbackOnCrashLoop can be used
+	// to automatically revert to the previous binary
+	OnCrashLoop func() error
+}
+
+// NewCrashLoop returns a CrashLoop with the default limits that records starts in the given file
+func NewCrashLoop(statePath string, onCrashLoop func() error) *CrashLoop {
+	return &CrashLoop{
+		StatePath:   statePath,
+		MaxStarts:   DefaultMaxStarts,
+		Window:      DefaultWindow,
+		OnCrashLoop: onCrashLoop,
+	}
+}
+
+// RollbackOnCrashLoop is an OnCrashLoop hook that rolls back the running executable
+func RollbackOnCrashLoop() error {
+	return Rollback()
+}
+
+// Started records a start of the executable and calls OnCrashLoop if it is crash looping,
+// it should be called as early as possible after the executable starts
+func (c *CrashLoop) Started() error {
+	now := time.Now()
+	starts := c.recentStarts(now)
+	starts = append(starts, now)
+	if len(starts) >= c.MaxStarts {
+		if err := c.Healthy(); err != nil {
+			return err
+		}
+		if c.OnCrashLoop != nil {
+			return c.OnCrashLoop()
+		}
+		return nil
+	}
+	return c.write(starts)
+}
+
+// Healthy clears the recorded starts, it should be called once the executable is known to work
+func (c *CrashLoop) Healthy() error {
+	err := os.Remove(c.StatePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// recentStarts returns the recorded starts within the window
+func (c *CrashLoop) recentStarts(now time.Time) []time.Time {
+	contents, err := os.ReadFile(c.StatePath)
+	if err != nil {
+		return nil
+	}
+	var starts []time.Time
+	for _, line := range strings.Split(strings.TrimSpace(string(contents)), "\n") {
+		unix, err := strconv.ParseInt(line, 10, 64)
+		if err != nil {
+			continue
+		}
+		if start := time.Unix(unix, 0); now.Sub(start) <= c.Window {
+			starts = append(starts, start)
+		}
+	}
+	return starts
+}
+
+func (c *CrashLoop) write(starts []time.Time) error {
+	lines := make([]string, 0, len(starts))
+	for _, start := range starts {
+		lines = append(lines, strconv.FormatInt(start.Unix(), 10))
+	}
+	return os.WriteFile(c.StatePath, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+}
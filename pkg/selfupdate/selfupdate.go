@@ -0,0 +1,116 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+// Package selfupdate replaces the running executable with a new release and
+// keeps the previous executable around so that a broken release can be rolled back
+package selfupdate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// OldSuffix is appended to the path of the executable to keep the previous binary
+	OldSuffix = ".old"
+
+	// newSuffix is appended to the path of the executable while the new binary is written
+	newSuffix = ".new"
+)
+
+var (
+	ErrNoPreviousBinary = errors.New("no previous binary to roll back to")
+)
+
+// Executable returns the resolved path of the running executable
+func Executable() (string, error) {
+	path, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(path)
+}
+
+// Apply replaces the binary at the given path with the given binary, keeping the replaced
+// binary as path.old. The new binary is written next to the old one before it is swapped in,
+// so a failed write never leaves a partially written binary at the given path.
+func Apply(path string, binary io.Reader) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	newPath := path + newSuffix
+	f, err := os.OpenFile(newPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, binary)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(newPath)
+		return fmt.Errorf("unable to write new binary: %w", err)
+	}
+
+	oldPath := path + OldSuffix
+	_ = os.Remove(oldPath)
+	if err = os.Rename(path, oldPath); err != nil {
+		_ = os.Remove(newPath)
+		return fmt.Errorf("unable to keep previous binary: %w", err)
+	}
+	if err = os.Rename(newPath, path); err != nil {
+		_ = os.Rename(oldPath, path)
+		return fmt.Errorf("unable to replace binary: %w", err)
+	}
+	return nil
+}
+
+// Rollback restores the previous binary of the running executable, kept by the last call to Apply
+func Rollback() error {
+	path, err := Executable()
+	if err != nil {
+		return err
+	}
+	return RollbackPath(path)
+}
+
+// RollbackPath restores the binary kept as path.old by the last call to Apply
+func RollbackPath(path string) error {
+	oldPath := path + OldSuffix
+	if _, err := os.Stat(oldPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNoPreviousBinary
+		}
+		return err
+	}
+
+	brokenPath := path + newSuffix
+	_ = os.Remove(brokenPath)
+	if err := os.Rename(path, brokenPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to move broken binary: %w", err)
+	}
+	if err := os.Rename(oldPath, path); err != nil {
+		_ = os.Rename(brokenPath, path)
+		return fmt.Errorf("unable to restore previous binary: %w", err)
+	}
+	_ = os.Remove(brokenPath)
+	return nil
+}
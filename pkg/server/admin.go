@@ -0,0 +1,191 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/pprof"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/loopholelabs/releaser/internal/audit"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/cache"
+	"net"
+	"sort"
+	"strings"
+	"time"
+)
+
+// pprofPath is the path the profiling routes are served under, below the admin path
+const pprofPath = "/debug/pprof"
+
+// newAdminApp returns the app serving the admin routes on the admin listen address
+func (s *Server) newAdminApp() *fiber.App {
+	app := fiber.New(fiber.Config{
+		ServerHeader:          s.helper.Config.Hostname,
+		BodyLimit:             adminBodyLimit,
+		ReadBufferSize:        s.helper.Config.MaxHeaderSize,
+		ReadTimeout:           time.Second * 30,
+		WriteTimeout:          time.Minute,
+		IdleTimeout:           time.Second * 30,
+		DisableStartupMessage: true,
+	})
+	app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: s.reportPanic,
+	}))
+	app.Use(newRequestID())
+	app.Use(s.LimitURI)
+	return app
+}
+
+// initAdmin registers the admin routes on the given app, the profiling routes
+// are only registered if it is the app of the separate admin listener
+func (s *Server) initAdmin(app *fiber.App, compressed fiber.Handler) {
+	if s.helper.Config.OIDC.Issuer != "" {
+		s.initOIDC(app)
+	}
+	s.initDashboard(app)
+	if s.helper.Config.AdminAuthEnabled() {
+		app.Use(api.AdminPath, s.AdminAuth)
+	}
+	read := requireScope(config.AdminScopeRead)
+	app.Use(api.AdminPath, limitBody(adminBodyLimit), s.AuditAdmin)
+	app.Get(utils.JoinStrings(api.AdminPath, api.IntegrityPath), read, compressed, s.GetIntegrity)
+	app.Get(utils.JoinStrings(api.AdminPath, api.MetricsPath), read, s.GetMetrics)
+	app.Get(utils.JoinStrings(api.AdminPath, api.MaintenancePath), read, s.GetMaintenance)
+	app.Get(utils.JoinStrings(api.AdminPath, api.OverviewPath), read, compressed, s.GetOverview)
+	if app == s.adminApp || !s.helper.Config.GETOnly {
+		cacheScope := requireScope(config.AdminScopeCache)
+		rolloutScope := requireScope(config.AdminScopeRollout)
+		app.Post(utils.JoinStrings(api.AdminPath, api.MaintenancePath), cacheScope, s.SetMaintenance)
+		app.Post(utils.JoinStrings(api.AdminPath, api.RefreshPath), cacheScope, s.RefreshCache)
+		app.Post(utils.JoinStrings(api.AdminPath, api.EvictPath), cacheScope, s.EvictLatestRelease)
+		app.Post(utils.JoinStrings(api.AdminPath, api.PinPath), rolloutScope, s.PinRelease)
+		app.Post(utils.JoinStrings(api.AdminPath, api.RolloutPath), rolloutScope, s.CompleteRollout)
+	}
+	if app == s.adminApp {
+		app.Use(utils.JoinStrings(api.AdminPath, pprofPath), read)
+		app.Use(pprof.New(pprof.Config{Prefix: api.AdminPath}))
+	}
+}
+
+// startAdmin serves the admin app on the admin listen address until it is shut down
+func (s *Server) startAdmin(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+
+	s.helper.Printer.Printf("Starting admin server on http://%s\n", address)
+	go func() {
+		err := s.adminApp.Listener(listener)
+		if err != nil {
+			s.helper.Printer.Printf("error: admin server stopped: %s\n", err)
+		}
+	}()
+	return nil
+}
+
+// SetAuditLog sets the audit log admin requests and the changes they make are recorded to,
+// it must be called before the server is started
+func (s *Server) SetAuditLog(log *audit.Log) *Server {
+	s.audit = log
+	return s
+}
+
+// AuditAdmin records every request to the admin routes to the audit log
+func (s *Server) AuditAdmin(ctx *fiber.Ctx) error {
+	s.record(ctx, &audit.Entry{
+		Action: "admin.request",
+		Target: ctx.Method() + " " + ctx.Path(),
+	})
+	return ctx.Next()
+}
+
+// record appends the given entry to the audit log on behalf of the client that sent the given request,
+// which is identified by the name of its admin token if it was authenticated with one
+func (s *Server) record(ctx *fiber.Ctx, entry *audit.Entry) {
+	entry.Actor = ctx.IP()
+	if token, ok := ctx.Locals(adminTokenKey).(*config.AdminToken); ok {
+		entry.Actor = token.Name + "@" + entry.Actor
+	}
+	err := s.audit.Record(entry)
+	if err != nil {
+		s.helper.Printer.Printf("error: unable to record %s to the audit log (request %s): %s\n", entry.Action, requestID(ctx), err)
+	}
+}
+
+// GetIntegrity returns the mismatches between the checksums.txt files and the assets of all releases
+func (s *Server) GetIntegrity(ctx *fiber.Ctx) error {
+	issues := s.cache.GetIntegrityIssues()
+	if issues == nil {
+		issues = []*cache.IntegrityIssue{}
+	}
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
+	return ctx.JSON(&IntegrityResponse{
+		Healthy: len(issues) == 0,
+		Issues:  issues,
+	})
+}
+
+// GetMetrics returns the state of the cache in the Prometheus text exposition format
+func (s *Server) GetMetrics(ctx *fiber.Ctx) error {
+	problems := make(map[string]int)
+	for _, issue := range s.cache.GetIntegrityIssues() {
+		problems[issue.Problem]++
+	}
+	problemNames := make([]string, 0, len(problems))
+	for problem := range problems {
+		problemNames = append(problemNames, problem)
+	}
+	sort.Strings(problemNames)
+
+	status := s.cache.GetStatus()
+	var cacheAge float64
+	if status.Ready {
+		cacheAge = time.Since(status.LastUpdate).Seconds()
+	}
+	var stale int
+	if status.Stale {
+		stale = 1
+	}
+
+	var b strings.Builder
+	b.WriteString("# HELP releaser_cache_age_seconds The time since the last successful cache update.\n")
+	b.WriteString("# TYPE releaser_cache_age_seconds gauge\n")
+	b.WriteString(fmt.Sprintf("releaser_cache_age_seconds %f\n", cacheAge))
+	b.WriteString("# HELP releaser_cache_stale Whether the last known-good cache is being served because updates are failing.\n")
+	b.WriteString("# TYPE releaser_cache_stale gauge\n")
+	b.WriteString(fmt.Sprintf("releaser_cache_stale %d\n", stale))
+	b.WriteString("# HELP releaser_cache_update_failures_total The number of failed cache updates.\n")
+	b.WriteString("# TYPE releaser_cache_update_failures_total counter\n")
+	b.WriteString(fmt.Sprintf("releaser_cache_update_failures_total %d\n", status.Failures))
+	b.WriteString("# HELP releaser_releases The number of releases being served.\n")
+	b.WriteString("# TYPE releaser_releases gauge\n")
+	b.WriteString(fmt.Sprintf("releaser_releases %d\n", len(s.cache.GetAllReleaseNames())))
+	b.WriteString("# HELP releaser_integrity_issues The number of mismatches between checksums.txt files and release assets.\n")
+	b.WriteString("# TYPE releaser_integrity_issues gauge\n")
+	for _, problem := range problemNames {
+		b.WriteString(fmt.Sprintf("releaser_integrity_issues{problem=%q} %d\n", problem, problems[problem]))
+	}
+
+	ctx.Response().Header.SetContentType("text/plain; version=0.0.4; charset=utf-8")
+	return ctx.SendString(b.String())
+}
@@ -0,0 +1,119 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// adminTokenKey stores the admin token a request was authenticated with in the locals of its context
+	adminTokenKey = "admin_token"
+
+	// legacyAdminTokenName is the name the admin token is recorded as in the audit log
+	legacyAdminTokenName = "admin"
+)
+
+// adminToken is an admin token with the digest it is compared by, comparing the digests
+// of the tokens instead of the tokens themselves does not reveal the length of the tokens
+type adminToken struct {
+	digest [sha256.Size]byte
+	token  *config.AdminToken
+}
+
+// newAdminTokens returns the tokens accepted by the admin routes, the admin token is granted every scope
+func newAdminTokens(c *config.Config) []adminToken {
+	tokens := make([]adminToken, 0, len(c.AdminTokens)+1)
+	if c.AdminToken != "" {
+		tokens = append(tokens, adminToken{
+			digest: sha256.Sum256([]byte(c.AdminToken)),
+			token: &config.AdminToken{
+				Name:   legacyAdminTokenName,
+				Token:  c.AdminToken,
+				Scopes: config.AdminScopes,
+			},
+		})
+	}
+	for i := range c.AdminTokens {
+		tokens = append(tokens, adminToken{
+			digest: sha256.Sum256([]byte(c.AdminTokens[i].Token)),
+			token:  &c.AdminTokens[i],
+		})
+	}
+	return tokens
+}
+
+// AdminAuth rejects requests to the admin routes that do not carry one of the unexpired admin tokens,
+// or the ID token of a user allowed to sign in with OIDC either as the bearer token or in the session
+// cookie. The token is stored in the locals of the request so that the admin routes can check its scopes.
+func (s *Server) AdminAuth(ctx *fiber.Ctx) error {
+	bearer, ok := strings.CutPrefix(ctx.Get(fiber.HeaderAuthorization), "Bearer ")
+	if !ok && s.oidc != nil {
+		bearer = ctx.Cookies(oidcSessionCookie)
+		ok = bearer != ""
+	}
+	if !ok {
+		if s.oidc != nil && ctx.Method() == fiber.MethodGet && strings.Contains(ctx.Get(fiber.HeaderAccept), fiber.MIMETextHTML) {
+			return ctx.Redirect(utils.JoinStrings(api.AdminPath, oidcLoginPath, "?", oidcNext, "=", url.QueryEscape(ctx.Path())), fiber.StatusFound)
+		}
+		return ctx.Status(fiber.StatusUnauthorized).SendString("unauthorized")
+	}
+
+	// every token is compared, so that the time taken does not reveal which one matched
+	digest := sha256.Sum256([]byte(bearer))
+	var token *config.AdminToken
+	for _, candidate := range s.adminTokens {
+		if subtle.ConstantTimeCompare(digest[:], candidate.digest[:]) == 1 {
+			token = candidate.token
+		}
+	}
+	if token == nil && s.oidc != nil {
+		var err error
+		token, _, err = s.oidc.authenticate(ctx.UserContext(), bearer, "")
+		if errors.Is(err, errOIDCGroupNotAllowed) {
+			return ctx.Status(fiber.StatusForbidden).SendString("forbidden")
+		}
+	}
+	if token == nil || token.Expired(time.Now()) {
+		return ctx.Status(fiber.StatusUnauthorized).SendString("unauthorized")
+	}
+
+	ctx.Locals(adminTokenKey, token)
+	return ctx.Next()
+}
+
+// requireScope rejects requests authenticated with an admin token that was not granted the given scope,
+// requests to the separate admin listener are not authenticated if no admin tokens are configured
+func requireScope(scope string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		token, ok := ctx.Locals(adminTokenKey).(*config.AdminToken)
+		if ok && !token.HasScope(scope) {
+			return ctx.Status(fiber.StatusForbidden).SendString(fmt.Sprintf("admin token %s is not granted the %s scope", token.Name, scope))
+		}
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,180 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/cache"
+	"github.com/loopholelabs/releaser/pkg/provider"
+	"hash"
+	"io"
+	"mime"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	errAssetChecksumMismatch = errors.New("asset checksum mismatch")
+)
+
+// GetReleaseAsset returns a non-binary asset (e.g. a license, a configuration template, or a source tarball)
+// of the given release, verifying it against the release's checksums.txt when it has an entry there. Assets are
+// streamed from the provider through the same transfer slots and bandwidth limits as artifacts, and are hashed
+// while they are sent. An asset that did not match its checksum is not served again until it is replaced.
+func (s *Server) GetReleaseAsset(ctx *fiber.Ctx) error {
+	releaseName := s.releaseNameParam(ctx)
+	assetName := strings.ToLower(param(ctx, "asset_name"))
+
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	asset := s.cache.GetReleaseAsset(releaseName, assetName)
+	if asset == nil {
+		return ctx.Status(fiber.StatusNotFound).SendString("asset not found")
+	}
+
+	if asset.Verification() == cache.VerificationFailed {
+		return ctx.Status(fiber.StatusBadGateway).SendString("asset checksum verification failed")
+	}
+
+	release, ok := s.acquireTransfer()
+	if !ok {
+		return s.transfersExhausted(ctx)
+	}
+
+	body, err := s.openUpstream(ctx, func(downloadCtx context.Context) (io.ReadCloser, error) {
+		return s.cache.DownloadAsset(downloadCtx, asset)
+	})
+	if err != nil {
+		release()
+		s.helper.Printer.Printf("error: unable to download asset %s for release %s: %s\n", assetName, releaseName, err)
+		return s.upstreamError(ctx, err, "unable to download asset")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetReleaseAsset from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "release_asset", map[string]string{
+			"release_name": releaseName,
+			"asset_name":   assetName,
+		})
+	}
+
+	size := asset.Size
+	if size == 0 {
+		size = -1
+	}
+
+	var reader io.Reader = body
+	if asset.Checksum != "" && asset.Verification() != cache.VerificationPassed {
+		reader = &verifiedBody{
+			body:  body,
+			hash:  sha256.New(),
+			size:  int64(asset.Size),
+			asset: asset,
+			mismatch: func() {
+				s.helper.Printer.Printf("error: checksum mismatch for asset %s of release %s\n", assetName, releaseName)
+			},
+		}
+	}
+
+	contentType := mime.TypeByExtension(filepath.Ext(assetName))
+	if contentType == "" {
+		contentType = fiber.MIMEOctetStream
+	}
+	setAttachment(ctx, assetName)
+	ctx.Response().Header.SetContentType(contentType)
+	s.sendArtifact(ctx, reader, size, release)
+	return nil
+}
+
+// verifiedBody hashes an asset while it is sent and records whether it matched its checksum. The read completing
+// the asset fails without returning its bytes on a mismatch, so the response is cut short instead of completed
+// and clients notice that the body does not match its Content-Length.
+type verifiedBody struct {
+	body  io.ReadCloser
+	hash  hash.Hash
+	size  int64
+	read  int64
+	done  bool
+	asset *cache.Asset
+
+	// mismatch is called when the asset did not match its checksum
+	mismatch func()
+}
+
+func (b *verifiedBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	if b.done {
+		return n, err
+	}
+	b.hash.Write(p[:n])
+	b.read += int64(n)
+	if errors.Is(err, io.EOF) || (b.size > 0 && b.read >= b.size) {
+		b.done = true
+		if hex.EncodeToString(b.hash.Sum(nil)) != strings.ToLower(b.asset.Checksum) {
+			b.mismatch()
+			b.asset.SetVerification(cache.VerificationFailed)
+			return 0, errAssetChecksumMismatch
+		}
+		b.asset.SetVerification(cache.VerificationPassed)
+	}
+	return n, err
+}
+
+// Close closes the download of the asset, fasthttp closes the body stream once the response is sent
+func (b *verifiedBody) Close() error {
+	return b.body.Close()
+}
+
+// GetReleaseLicense returns the license and notice files attached to the given release as plain text
+func (s *Server) GetReleaseLicense(ctx *fiber.Ctx) error {
+	releaseName := s.releaseNameParam(ctx)
+
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	deadline, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	license, err := s.cache.GetReleaseLicense(deadline, releaseName)
+	if err != nil {
+		s.helper.Printer.Printf("error: unable to download license for release %s: %s\n", releaseName, err)
+		var rateLimitErr *provider.RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			return s.tooManyRequests(ctx, "upstream rate limit exceeded", rateLimitErr.RetryAfter)
+		}
+		return ctx.Status(fiber.StatusBadGateway).SendString("unable to download license")
+	}
+	if license == nil {
+		return ctx.Status(fiber.StatusNotFound).SendString("license not found")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetReleaseLicense from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "release_license", map[string]string{"release_name": releaseName})
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.Send(license)
+}
@@ -0,0 +1,135 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/time/rate"
+	"io"
+	"net"
+	"time"
+)
+
+const (
+	// writeTimeout is how long a response may take to be written, throttled artifact
+	// responses extend it for as long as they keep sending
+	writeTimeout = time.Second * 30
+
+	// throttleChunkSize is the most a throttled artifact response sends at once
+	throttleChunkSize = 32 * 1024
+)
+
+// newBandwidthLimiter returns a limiter allowing the given number of bytes per second,
+// or nil if the bandwidth is unlimited
+func newBandwidthLimiter(limit int) *rate.Limiter {
+	if limit <= 0 {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(limit), limit)
+}
+
+// throttledReader limits the rate an artifact response body is read at, and so the rate it is sent at
+type throttledReader struct {
+	reader   io.Reader
+	limiters []*rate.Limiter
+
+	// chunkSize is no larger than the burst of any of the limiters, so that every read can be waited for
+	chunkSize int
+
+	// conn is the connection the response is sent on, its write deadline is extended after every read
+	conn net.Conn
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > r.chunkSize {
+		p = p[:r.chunkSize]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		for _, limiter := range r.limiters {
+			_ = limiter.WaitN(context.Background(), n)
+		}
+		if r.conn != nil {
+			_ = r.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		}
+	}
+	return n, err
+}
+
+// Close closes the underlying reader, fasthttp closes the body stream once the response is sent
+func (r *throttledReader) Close() error {
+	if closer, ok := r.reader.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// bandwidthLimiters returns the limiters an artifact response is throttled by, which are the global limiter
+// and a new limiter for the connection of the response, leaving out the ones whose bandwidth is unlimited
+func (s *Server) bandwidthLimiters() []*rate.Limiter {
+	var limiters []*rate.Limiter
+	if s.bandwidth != nil {
+		limiters = append(limiters, s.bandwidth)
+	}
+	if limiter := newBandwidthLimiter(s.helper.Config.ConnectionBandwidthLimit); limiter != nil {
+		limiters = append(limiters, limiter)
+	}
+	return limiters
+}
+
+// sendArtifact streams the given artifact as the body of the response, throttled to the global and
+// per-connection bandwidth limits. The size is -1 if it is unknown, and release is called to release
+// the transfer slot of the response once the body has been sent.
+func (s *Server) sendArtifact(ctx *fiber.Ctx, body io.Reader, size int, release func()) {
+	s.streamArtifact(ctx, body, size, release, s.bandwidthLimiters())
+}
+
+// streamArtifact streams the given artifact as the body of the response, it is only wrapped in a
+// throttledReader if there are limiters so that unthrottled files can still be sent with sendfile
+func (s *Server) streamArtifact(ctx *fiber.Ctx, body io.Reader, size int, release func(), limiters []*rate.Limiter) {
+	if len(limiters) > 0 {
+		reader := &throttledReader{
+			reader:    body,
+			limiters:  limiters,
+			chunkSize: throttleChunkSize,
+			conn:      ctx.Context().Conn(),
+		}
+		for _, limiter := range limiters {
+			if limiter.Burst() < reader.chunkSize {
+				reader.chunkSize = limiter.Burst()
+			}
+		}
+		body = reader
+	}
+	if s.transfers != nil {
+		body = &transferBody{reader: body, release: release}
+	}
+	ctx.Response().SetBodyStream(body, size)
+}
+
+// sendArtifactBytes sets the given artifact as the body of the response, it is only streamed if it is
+// throttled or its transfer slot has to be released once it has been sent
+func (s *Server) sendArtifactBytes(ctx *fiber.Ctx, artifact []byte, release func()) {
+	limiters := s.bandwidthLimiters()
+	if len(limiters) == 0 && s.transfers == nil {
+		ctx.Response().SetBodyRaw(artifact)
+		return
+	}
+	s.streamArtifact(ctx, bytes.NewReader(artifact), len(artifact), release, limiters)
+}
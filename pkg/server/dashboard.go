@@ -0,0 +1,129 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/embed"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"strings"
+	"time"
+)
+
+const (
+	// dashboardScriptPath is the path of the script of the admin dashboard, below the dashboard path
+	dashboardScriptPath = "/dashboard.js"
+
+	// dashboardSecurityPolicy only allows the dashboard to load its own script and call the admin routes
+	dashboardSecurityPolicy = "default-src 'none'; script-src 'self'; style-src 'unsafe-inline'; connect-src 'self'; form-action 'none'; frame-ancestors 'none'; base-uri 'none'"
+
+	// overviewPeriod is how far back the installs shown by the admin dashboard go
+	overviewPeriod = time.Hour * 24 * 30
+)
+
+// initDashboard registers the admin dashboard on the given app, which is served without authentication
+// since it contains no data and signs in to the admin routes itself
+func (s *Server) initDashboard(app *fiber.App) {
+	app.Get(utils.JoinStrings(api.AdminPath, api.DashboardPath), s.GetDashboard)
+	app.Get(utils.JoinStrings(api.AdminPath, api.DashboardPath, dashboardScriptPath), s.GetDashboardScript)
+}
+
+// GetDashboard returns the admin dashboard, which shows the overview of the releaser
+// and calls the admin routes to refresh the cache, evict the latest release, pin a
+// release, complete a rollout, or toggle maintenance mode
+func (s *Server) GetDashboard(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderContentSecurityPolicy, dashboardSecurityPolicy)
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Response().Header.SetContentType(fiber.MIMETextHTMLCharsetUTF8)
+	_, err := s.dashboardTemplate.Execute(ctx.Response().BodyWriter(), map[string]interface{}{
+		"script":     utils.JoinStrings(api.AdminPath, api.DashboardPath, dashboardScriptPath),
+		"oidc_login": utils.JoinStrings(api.AdminPath, oidcLoginPath, "?", oidcNext, "=", api.AdminPath, api.DashboardPath),
+		"oidc_class": s.dashboardOIDCClass(),
+	})
+	return err
+}
+
+// dashboardOIDCClass hides the OIDC sign in link of the dashboard if no OIDC provider is configured
+func (s *Server) dashboardOIDCClass() string {
+	if s.helper.Config.OIDC.Issuer == "" {
+		return "hidden"
+	}
+	return ""
+}
+
+// GetDashboardScript returns the script of the admin dashboard
+func (s *Server) GetDashboardScript(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJavaScriptCharsetUTF8)
+	return ctx.SendString(embed.DashboardScript)
+}
+
+// GetOverview returns the state of the releaser shown by the admin dashboard: the status of the
+// cache, the releases sorted from newest to oldest, and the installs per version and day
+func (s *Server) GetOverview(ctx *fiber.Ctx) error {
+	latestReleaseName := s.cache.GetLatestReleaseName()
+	pinnedReleaseName := s.cache.GetPinnedReleaseName()
+	releaseNames := s.cache.GetAllReleaseNames()
+
+	res := &v1.OverviewResponse{
+		Status:       s.statusResponse(),
+		Cache:        s.cacheInfo(),
+		Releases:     make([]*v1.OverviewRelease, 0, len(releaseNames)),
+		StatsEnabled: s.stats != nil,
+	}
+	for _, releaseName := range releaseNames {
+		res.Releases = append(res.Releases, &v1.OverviewRelease{
+			Name:      releaseName,
+			Title:     s.cache.GetReleaseTitle(releaseName),
+			Latest:    releaseName == latestReleaseName,
+			Pinned:    strings.EqualFold(releaseName, pinnedReleaseName),
+			Artifacts: len(s.cache.GetReleaseArtifacts(releaseName)),
+		})
+	}
+
+	if s.stats != nil {
+		truncate, _ := intervalTruncate(IntervalDay)
+		rollups, err := s.stats.Rollups("release_artifact", truncate(time.Now().UTC().Add(-overviewPeriod)), truncate)
+		if err != nil {
+			s.helper.Printer.Printf("error: unable to summarize stats for the overview (request %s): %s\n", requestID(ctx), err)
+			return ctx.Status(fiber.StatusInternalServerError).SendString("unable to summarize stats")
+		}
+
+		// the dashboard graphs the installs per version, so the platforms and sources are merged
+		installs := make(map[[2]string]*v1.StatsRollupResponse)
+		for _, rollup := range rollups {
+			start := rollup.Start.Format(time.DateOnly)
+			key := [2]string{start, rollup.Version}
+			if install, ok := installs[key]; ok {
+				install.Installs += rollup.Count
+				continue
+			}
+			install := &v1.StatsRollupResponse{
+				Start:    start,
+				Version:  rollup.Version,
+				Installs: rollup.Count,
+			}
+			installs[key] = install
+			res.Installs = append(res.Installs, install)
+		}
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
+	return ctx.JSON(res)
+}
@@ -0,0 +1,87 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/analytics"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/pkg/api"
+)
+
+// geoHeaders are the headers CDNs report the country of the client in, in order of preference
+var geoHeaders = []string{"CF-IPCountry", "CloudFront-Viewer-Country", "X-Vercel-IP-Country", "X-Country-Code"}
+
+// requestContextKey is the key the request of an analytics event is stored under in its context
+type requestContextKey struct{}
+
+// withRequest returns a context carrying the given request for the enrichers of its analytics events
+func withRequest(ctx *fiber.Ctx) context.Context {
+	return context.WithValue(ctx.UserContext(), requestContextKey{}, ctx)
+}
+
+// requestFromContext returns the request an analytics event belongs to, if any
+func requestFromContext(ctx context.Context) (*fiber.Ctx, bool) {
+	request, ok := ctx.Value(requestContextKey{}).(*fiber.Ctx)
+	return request, ok
+}
+
+// enricher returns the analytics enricher with the given name
+func (s *Server) enricher(name string) analytics.Enricher {
+	switch name {
+	case config.EnricherRegion:
+		region := s.helper.Config.AnalyticsRegion
+		return func(_ context.Context, _ string, properties map[string]string) {
+			if region != "" {
+				properties["region"] = region
+			}
+		}
+	case config.EnricherGeo:
+		return requestEnricher(func(request *fiber.Ctx, properties map[string]string) {
+			for _, header := range geoHeaders {
+				if country := request.Get(header); country != "" {
+					properties["country"] = country
+					return
+				}
+			}
+		})
+	case config.EnricherChannel:
+		return requestEnricher(func(request *fiber.Ctx, properties map[string]string) {
+			if cohort := request.Query(api.Cohort); cohort != "" {
+				properties["channel"] = cohort
+			}
+		})
+	case config.EnricherReferrer:
+		return requestEnricher(func(request *fiber.Ctx, properties map[string]string) {
+			if referrer := request.Get(fiber.HeaderReferer); referrer != "" {
+				properties["referrer"] = referrer
+			}
+		})
+	default:
+		return nil
+	}
+}
+
+// requestEnricher returns an enricher that calls enrich for events sent while handling a request
+func requestEnricher(enrich func(request *fiber.Ctx, properties map[string]string)) analytics.Enricher {
+	return func(ctx context.Context, _ string, properties map[string]string) {
+		if request, ok := requestFromContext(ctx); ok {
+			enrich(request, properties)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"net"
+	"time"
+)
+
+const (
+	// eventKeepAlive is how often a comment is sent on an idle event stream so that proxies keep it open,
+	// and how long a buffered event request waits for the latest release to change
+	eventKeepAlive = time.Second * 20
+
+	// eventRetry is how long EventSource clients wait before reconnecting to a closed event stream, in milliseconds
+	eventRetry = 5000
+
+	// lastEventIDHeader is sent by EventSource clients when they reconnect, events are identified by the
+	// name of the latest release so that a client is not told about the release it already knows again
+	lastEventIDHeader = "Last-Event-ID"
+)
+
+// GetEvents streams Server-Sent Events to the client, sending an event with the latest release when the
+// client subscribes and whenever it changes.
+//
+// Responses are buffered when serving HTTP/2, so the response ends after the next event or once the
+// keep-alive interval has passed, and clients reconnect with the ID of the last event they received.
+func (s *Server) GetEvents(ctx *fiber.Ctx) error {
+	if limit := int64(s.helper.Config.MaxEventSubscribers); s.eventSubscribers.Add(1) > limit && limit > 0 {
+		s.eventSubscribers.Add(-1)
+		s.helper.Printer.Printf("Rejected event stream from %s, too many subscribers (request %s)\n", ctx.IP(), requestID(ctx))
+		return s.retryLater(ctx, fiber.StatusServiceUnavailable, "too many event subscribers", eventKeepAlive)
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetEvents from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "subscribe_events")
+	}
+
+	lastEventID := ctx.Get(lastEventIDHeader)
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+	// nginx buffers responses unless told otherwise, which would hold back every event
+	ctx.Set("X-Accel-Buffering", "no")
+
+	if s.httpServer != nil {
+		w := bufio.NewWriter(ctx.Response().BodyWriter())
+		s.writeEvents(w, nil, lastEventID, false)
+		return nil
+	}
+
+	conn := ctx.Context().Conn()
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		s.writeEvents(w, conn, lastEventID, true)
+	})
+	return nil
+}
+
+// writeEvents writes an event to the given writer whenever the latest release differs from the release
+// named by the last event ID. It returns after the first event unless streaming, and otherwise once the
+// client disconnects or the server stops. The write deadline of the given connection is extended before
+// every write, it is nil if the response is buffered.
+func (s *Server) writeEvents(w *bufio.Writer, conn net.Conn, lastEventID string, stream bool) {
+	defer s.eventSubscribers.Add(-1)
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	_, _ = fmt.Fprintf(w, "retry: %d\n\n", eventRetry)
+	for {
+		changed := s.cache.LatestReleaseChanged()
+		if latestReleaseName := s.cache.GetLatestReleaseName(); latestReleaseName != "" && latestReleaseName != lastEventID {
+			err := s.writeReleaseEvent(w, latestReleaseName, lastEventID)
+			if err != nil {
+				s.helper.Printer.Printf("error: unable to encode release event: %s\n", err)
+				return
+			}
+			lastEventID = latestReleaseName
+			if !stream {
+				_ = w.Flush()
+				return
+			}
+		}
+
+		if conn != nil {
+			_ = conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		}
+		if w.Flush() != nil {
+			return
+		}
+
+		select {
+		case <-changed:
+		case <-keepAlive.C:
+			if !stream {
+				_ = w.Flush()
+				return
+			}
+			_, _ = w.WriteString(": keep-alive\n\n")
+		case <-s.stopEvents:
+			return
+		}
+	}
+}
+
+// writeReleaseEvent writes the event announcing the given latest release to the given writer
+func (s *Server) writeReleaseEvent(w *bufio.Writer, latestReleaseName string, previousReleaseName string) error {
+	event := &v1.ReleaseEvent{
+		Name:                latestReleaseName,
+		PreviousReleaseName: previousReleaseName,
+		PublishedAt:         unixTime(s.cache.GetReleasePublishedAt(latestReleaseName)),
+	}
+	if rollout := s.cache.GetRollout(); rollout.PreviousReleaseName != "" {
+		event.Rollout = &v1.RolloutResponse{
+			PreviousReleaseName: rollout.PreviousReleaseName,
+			Percentage:          rollout.Percentage,
+		}
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", latestReleaseName, v1.EventLatestRelease, data)
+	return err
+}
+
+// endEventStreams ends the event streams of all subscribers, it is safe to call more than once
+func (s *Server) endEventStreams() {
+	select {
+	case <-s.stopEvents:
+	default:
+		close(s.stopEvents)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+)
+
+// GetHealth returns the freshness of the cache. It responds with a 503 until the cache has been
+// updated once, a stale cache is still reported as healthy since its last known-good state is served.
+func (s *Server) GetHealth(ctx *fiber.Ctx) error {
+	status := s.cache.GetStatus()
+	res := &v1.HealthResponse{
+		Ready:             status.Ready,
+		Stale:             status.Stale,
+		LatestReleaseName: s.cache.GetLatestReleaseName(),
+	}
+	if status.Ready {
+		res.LastUpdate = status.LastUpdate.Unix()
+	}
+	if status.LastError != nil {
+		res.Error = status.LastError.Error()
+	}
+
+	if !status.Ready {
+		ctx.Status(fiber.StatusServiceUnavailable)
+	}
+	return ctx.JSON(res)
+}
+
+// GetReady reports whether the server should receive traffic, for readiness probes. It responds
+// with a 503 until the cache has been updated once, and while the server is draining before a shutdown.
+func (s *Server) GetReady(ctx *fiber.Ctx) error {
+	if s.draining.Load() {
+		return ctx.Status(fiber.StatusServiceUnavailable).SendString("draining")
+	}
+	if !s.cache.GetStatus().Ready {
+		return ctx.Status(fiber.StatusServiceUnavailable).SendString("warming up")
+	}
+	return ctx.SendString("ready")
+}
+
+// Drain makes the server report that it is not ready while it keeps serving requests,
+// so that it is removed from load balancers before it is stopped
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
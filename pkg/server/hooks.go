@@ -0,0 +1,44 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/internal/config"
+	"strings"
+)
+
+// GetHook returns the shell snippet configured for the given install phase (pre-install or post-install)
+func (s *Server) GetHook(ctx *fiber.Ctx) error {
+	hook, ok := s.helper.Config.Hooks.GetHook(strings.ToLower(param(ctx, "phase")))
+	if !ok || hook == "" {
+		return ctx.Status(fiber.StatusNotFound).SendString("hook not found")
+	}
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.SendString(hook)
+}
+
+// hookPhases returns the install phases that have a hook configured, separated by spaces
+func (s *Server) hookPhases() string {
+	var phases []string
+	for _, phase := range []string{config.HookPreInstall, config.HookPostInstall} {
+		if hook, _ := s.helper.Config.Hooks.GetHook(phase); hook != "" {
+			phases = append(phases, phase)
+		}
+	}
+	return strings.Join(phases, " ")
+}
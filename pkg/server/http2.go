@@ -0,0 +1,61 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"net"
+	"net/http"
+	"time"
+)
+
+// http2NextProtos are the ALPN protocols advertised by TLS listeners when HTTP/2 is enabled
+var http2NextProtos = []string{http2.NextProtoTLS, "http/1.1"}
+
+// serveHTTP2 serves the app with net/http instead of fasthttp (which only supports HTTP/1.1),
+// negotiating HTTP/2 over TLS listeners and accepting h2c on plaintext listeners if enabled.
+//
+// Response bodies are buffered by the adaptor, so proxied artifacts are not streamed in this mode.
+func (s *Server) serveHTTP2(listener net.Listener, tls bool) error {
+	handler := http.MaxBytesHandler(adaptor.FiberApp(s.app), int64(s.helper.Config.MaxBodySize))
+	h2Server := new(http2.Server)
+	if !tls && s.helper.Config.H2C {
+		handler = h2c.NewHandler(handler, h2Server)
+	}
+
+	s.httpServer = &http.Server{
+		Handler:        handler,
+		ReadTimeout:    time.Minute * 3,
+		WriteTimeout:   writeTimeout,
+		IdleTimeout:    time.Second * 30,
+		MaxHeaderBytes: s.helper.Config.MaxHeaderSize,
+	}
+	if tls {
+		if err := http2.ConfigureServer(s.httpServer, h2Server); err != nil {
+			return err
+		}
+	}
+
+	err := s.httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"time"
+)
+
+// anonymizer derives the distinct IDs of analytics events from client IPs without storing them,
+// by hashing them with a secret salt and the current day so that IDs can not be linked across days
+type anonymizer struct {
+	salt []byte
+}
+
+// newAnonymizer returns an anonymizer for the given salt, a random salt is used if it is empty,
+// in which case the IDs of the same client differ between replicas and restarts
+func newAnonymizer(salt string) *anonymizer {
+	if salt != "" {
+		return &anonymizer{salt: []byte(salt)}
+	}
+	randomSalt := make([]byte, 32)
+	_, _ = rand.Read(randomSalt)
+	return &anonymizer{salt: randomSalt}
+}
+
+// id returns the anonymized ID of the given IP on the day of the given time
+func (a *anonymizer) id(ip string, now time.Time) string {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(now.UTC().Format(time.DateOnly)))
+	mac.Write([]byte{0})
+	mac.Write([]byte(ip))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// distinctID returns the ID analytics events of the given request are attributed to, which is the
+// install ID passed by the install script if it is valid and the anonymized client IP otherwise
+func (s *Server) distinctID(ctx *fiber.Ctx) string {
+	if installID, err := uuid.Parse(ctx.Query(api.InstallID)); err == nil {
+		return installID.String()
+	}
+	return s.anonymizer.id(ctx.IP(), time.Now())
+}
@@ -0,0 +1,123 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/registry"
+	"strings"
+	"time"
+)
+
+// GetLatestImageReference returns the digest-pinned image reference for the latest release
+func (s *Server) GetLatestImageReference(ctx *fiber.Ctx) error {
+	latestReleaseName := s.cache.GetLatestReleaseName()
+	if len(latestReleaseName) == 0 {
+		return ctx.Status(fiber.StatusInternalServerError).SendString("no releases available")
+	}
+	return s.getImageReference(ctx, latestReleaseName)
+}
+
+// GetImageReference returns the fully qualified, digest-pinned image reference for the given release
+func (s *Server) GetImageReference(ctx *fiber.Ctx) error {
+	return s.getImageReference(ctx, s.releaseNameParam(ctx))
+}
+
+func (s *Server) getImageReference(ctx *fiber.Ctx, releaseName string) error {
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	reference, err := s.imageReference(ctx.UserContext(), releaseName)
+	if err != nil {
+		if errors.Is(err, registry.ErrNotFound) {
+			return ctx.Status(fiber.StatusNotFound).SendString("image not found")
+		}
+		s.helper.Printer.Printf("error: unable to resolve image digest for release %s: %s\n", releaseName, err)
+		return ctx.Status(fiber.StatusBadGateway).SendString("unable to resolve image digest")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetImageReference from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "image_reference", map[string]string{"release_name": releaseName})
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.SendString(reference)
+}
+
+// GetLatestDockerRun returns a docker run snippet for the image of the latest release
+func (s *Server) GetLatestDockerRun(ctx *fiber.Ctx) error {
+	latestReleaseName := s.latestReleaseName(ctx)
+	if len(latestReleaseName) == 0 {
+		return ctx.Status(fiber.StatusInternalServerError).SendString("no releases available")
+	}
+	return s.getDockerRun(ctx, latestReleaseName)
+}
+
+// GetDockerRun returns a docker run snippet for the image of the given release
+func (s *Server) GetDockerRun(ctx *fiber.Ctx) error {
+	return s.getDockerRun(ctx, s.releaseNameParam(ctx))
+}
+
+func (s *Server) getDockerRun(ctx *fiber.Ctx, releaseName string) error {
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetDockerRun from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "docker_run", map[string]string{"release_name": releaseName})
+	}
+
+	snippet := []string{"docker", "run", "--rm"}
+	if s.helper.Config.DockerRunArgs != "" {
+		snippet = append(snippet, s.helper.Config.DockerRunArgs)
+	}
+	snippet = append(snippet, s.imageRepository.String()+":"+s.imageTag(releaseName))
+	if s.helper.Config.DockerCommand != "" {
+		snippet = append(snippet, s.helper.Config.DockerCommand)
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.SendString(strings.Join(snippet, " ") + "\n")
+}
+
+// imageTag returns the image tag of the given release
+func (s *Server) imageTag(releaseName string) string {
+	return s.imageTagTemplate.ExecuteString(map[string]interface{}{
+		"release_name": releaseName,
+		"version":      strings.TrimPrefix(releaseName, "v"),
+	})
+}
+
+// imageReference resolves the fully qualified, digest-pinned image reference for the given release
+func (s *Server) imageReference(ctx context.Context, releaseName string) (string, error) {
+	tag := s.imageTag(releaseName)
+
+	deadline, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	digest, err := s.registry.ResolveDigest(deadline, s.imageRepository, tag)
+	if err != nil {
+		return "", err
+	}
+
+	return s.imageRepository.String() + ":" + tag + "@" + digest, nil
+}
@@ -0,0 +1,48 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	// telemetryBodyLimit is the body limit of the install telemetry route, whose reports are a few hundred bytes
+	telemetryBodyLimit = 4 * 1024
+
+	// adminBodyLimit is the body limit of the admin routes, which take their arguments as query parameters
+	adminBodyLimit = 1024
+)
+
+// LimitURI rejects requests whose URI is longer than the configured maximum URI length with a 414
+func (s *Server) LimitURI(ctx *fiber.Ctx) error {
+	if len(ctx.Request().RequestURI()) > s.helper.Config.MaxURILength {
+		return ctx.Status(fiber.StatusRequestURITooLong).SendString("uri too long")
+	}
+	return ctx.Next()
+}
+
+// limitBody returns a handler that rejects requests whose body is larger than the given limit with a 413,
+// for routes that accept less than the configured maximum body size
+func limitBody(limit int) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if len(ctx.Request().Body()) > limit {
+			return ctx.Status(fiber.StatusRequestEntityTooLarge).SendString("request body too large")
+		}
+		return ctx.Next()
+	}
+}
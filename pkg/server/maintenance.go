@@ -0,0 +1,62 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/internal/audit"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"strconv"
+	"strings"
+)
+
+// Maintenance answers every request except health checks (/ping, /healthz, and /readyz) and admin requests with a 503 while
+// the server is in maintenance mode, for migrations where serving stale binaries is worse than refusing
+func (s *Server) Maintenance(ctx *fiber.Ctx) error {
+	if !s.maintenance.Load() || ctx.Path() == api.PingPath || ctx.Path() == api.HealthPath || ctx.Path() == api.ReadyPath || strings.HasPrefix(ctx.Path(), api.AdminPath+"/") {
+		return ctx.Next()
+	}
+
+	retryAfter := int(s.helper.Config.MaintenanceRetryAfter.Seconds())
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(retryAfter))
+	return ctx.Status(fiber.StatusServiceUnavailable).SendString(fmt.Sprintf("%s is undergoing maintenance, please try again in %s", s.helper.Config.Domain, s.helper.Config.MaintenanceRetryAfter))
+}
+
+// SetMaintenance enables or disables maintenance mode (?enabled=true or ?enabled=false)
+func (s *Server) SetMaintenance(ctx *fiber.Ctx) error {
+	enabled, err := strconv.ParseBool(ctx.Query("enabled"))
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).SendString("enabled must be true or false")
+	}
+	before := s.maintenance.Swap(enabled)
+	s.record(ctx, &audit.Entry{
+		Action: "admin.maintenance",
+		Before: before,
+		After:  enabled,
+	})
+	s.helper.Printer.Printf("Maintenance mode set to %t from %s (request %s)\n", enabled, ctx.IP(), requestID(ctx))
+	return s.GetMaintenance(ctx)
+}
+
+// GetMaintenance returns whether the server is in maintenance mode
+func (s *Server) GetMaintenance(ctx *fiber.Ctx) error {
+	return ctx.JSON(&v1.MaintenanceResponse{
+		Enabled: s.maintenance.Load(),
+	})
+}
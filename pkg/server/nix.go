@@ -0,0 +1,103 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"strings"
+)
+
+// nixSystems maps GOOS/GOARCH pairs to nix system doubles
+var nixSystems = map[string]string{
+	"linux/amd64":   "x86_64-linux",
+	"linux/386":     "i686-linux",
+	"linux/arm64":   "aarch64-linux",
+	"linux/armv6":   "armv6l-linux",
+	"linux/armv7":   "armv7l-linux",
+	"linux/riscv64": "riscv64-linux",
+	"darwin/amd64":  "x86_64-darwin",
+	"darwin/arm64":  "aarch64-darwin",
+}
+
+// GetLatestNixSources returns the nix sources for the latest release
+func (s *Server) GetLatestNixSources(ctx *fiber.Ctx) error {
+	latestReleaseName := s.cache.GetLatestReleaseName()
+	if len(latestReleaseName) == 0 {
+		return ctx.Status(fiber.StatusInternalServerError).SendString("no releases available")
+	}
+	return s.getNixSources(ctx, latestReleaseName)
+}
+
+// GetNixSources returns a fetchurl-compatible url and SRI hash for every platform
+// of the given release, keyed by nix system
+func (s *Server) GetNixSources(ctx *fiber.Ctx) error {
+	return s.getNixSources(ctx, s.releaseNameParam(ctx))
+}
+
+func (s *Server) getNixSources(ctx *fiber.Ctx, releaseName string) error {
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetNixSources from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "nix_sources", map[string]string{"release_name": releaseName})
+	}
+
+	res := getNixSourcesResponse()
+	defer putNixSourcesResponse(res)
+	res.Version = strings.TrimPrefix(releaseName, "v")
+
+	binaryName := s.defaultBinaryName()
+	for _, artifact := range s.cache.GetReleaseArtifacts(releaseName) {
+		if artifact.BinaryName != binaryName || artifact.Checksum == "" {
+			continue
+		}
+
+		system, ok := nixSystems[artifact.OS+"/"+artifact.Arch]
+		if !ok {
+			continue
+		}
+
+		hash, err := sriHash(artifact.Checksum)
+		if err != nil {
+			s.helper.Printer.Printf("error: invalid checksum %s for artifact %s: %s\n", artifact.Checksum, artifact.Name, err)
+			continue
+		}
+
+		res.Sources[system] = &v1.NixSource{
+			URL:  s.artifactURL(releaseName, artifact),
+			Hash: hash,
+		}
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
+	return ctx.JSON(res)
+}
+
+// sriHash converts a hex encoded sha256 checksum into a subresource integrity hash
+func sriHash(checksum string) (string, error) {
+	sum, err := hex.DecodeString(checksum)
+	if err != nil {
+		return "", err
+	}
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum), nil
+}
@@ -0,0 +1,242 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/internal/httpclient"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"golang.org/x/oauth2"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	oidcLoginPath    = "/oidc/login"
+	oidcCallbackPath = "/oidc/callback"
+
+	// oidcNext is the query parameter of the login route selecting the admin route redirected to after signing in
+	oidcNext = "next"
+
+	// oidcStateCookie stores the state and the admin route redirected to while signing in,
+	// oidcSessionCookie stores the ID token of a signed in user
+	oidcStateCookie   = "releaser_oidc_state"
+	oidcSessionCookie = "releaser_oidc_session"
+
+	// oidcStateTimeout is how long a user has to sign in with the provider
+	oidcStateTimeout = time.Minute * 10
+
+	// oidcNamePrefix marks the users that signed in with OIDC in the audit log
+	oidcNamePrefix = "oidc:"
+)
+
+var (
+	errOIDCGroupNotAllowed = errors.New("user is not a member of an allowed group")
+)
+
+// oidcAuth signs users in to the admin routes with an OpenID Connect provider
+type oidcAuth struct {
+	config   *config.OIDC
+	oauth2   *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+
+	// client is used for requests to the provider
+	client *http.Client
+
+	allowedGroups map[string]struct{}
+}
+
+// newOIDCAuth discovers the configured provider, it returns nil if no provider is configured
+func newOIDCAuth(ctx context.Context, c *config.Config) (*oidcAuth, error) {
+	if c.OIDC.Issuer == "" {
+		return nil, nil
+	}
+
+	client, err := httpclient.New(c.GetAPIOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, client), c.OIDC.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover oidc provider %s: %w", c.OIDC.Issuer, err)
+	}
+
+	allowedGroups := make(map[string]struct{}, len(c.OIDC.AllowedGroups))
+	for _, group := range c.OIDC.AllowedGroups {
+		allowedGroups[group] = struct{}{}
+	}
+
+	return &oidcAuth{
+		config: &c.OIDC,
+		oauth2: &oauth2.Config{
+			ClientID:     c.OIDC.ClientID,
+			ClientSecret: c.OIDC.ClientSecret,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+		},
+		verifier:      provider.Verifier(&oidc.Config{ClientID: c.OIDC.ClientID}),
+		client:        client,
+		allowedGroups: allowedGroups,
+	}, nil
+}
+
+// authenticate verifies the given ID token and returns the admin token its user is granted,
+// along with the time the ID token expires
+func (o *oidcAuth) authenticate(ctx context.Context, rawIDToken string, nonce string) (*config.AdminToken, time.Time, error) {
+	idToken, err := o.verifier.Verify(oidc.ClientContext(ctx, o.client), rawIDToken)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	if nonce != "" && idToken.Nonce != nonce {
+		return nil, time.Time{}, errors.New("nonce does not match")
+	}
+
+	claims := make(map[string]interface{})
+	err = idToken.Claims(&claims)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	if len(o.allowedGroups) > 0 && !o.allowed(claims[o.config.GetGroupsClaim()]) {
+		return nil, time.Time{}, errOIDCGroupNotAllowed
+	}
+
+	name := idToken.Subject
+	if email, ok := claims["email"].(string); ok && email != "" {
+		name = email
+	}
+	return &config.AdminToken{
+		Name:   oidcNamePrefix + name,
+		Scopes: o.config.GetScopes(),
+	}, idToken.Expiry, nil
+}
+
+// allowed returns true if the given groups claim contains one of the allowed groups,
+// providers send either a list of groups or a single group
+func (o *oidcAuth) allowed(groups interface{}) bool {
+	switch groups := groups.(type) {
+	case string:
+		_, ok := o.allowedGroups[groups]
+		return ok
+	case []interface{}:
+		for _, group := range groups {
+			if group, ok := group.(string); ok {
+				if _, ok = o.allowedGroups[group]; ok {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// initOIDC registers the routes users sign in to the admin routes with on the given app,
+// they must be registered before the admin routes are authenticated
+func (s *Server) initOIDC(app *fiber.App) {
+	app.Get(utils.JoinStrings(api.AdminPath, oidcLoginPath), s.OIDCLogin)
+	app.Get(utils.JoinStrings(api.AdminPath, oidcCallbackPath), s.OIDCCallback)
+}
+
+// oidcRedirectURL returns the URL the provider redirects to after signing in
+func (s *Server) oidcRedirectURL() string {
+	if s.helper.Config.OIDC.RedirectURL != "" {
+		return s.helper.Config.OIDC.RedirectURL
+	}
+	return fmt.Sprintf("%s://%s%s%s", s.prefix, s.helper.Config.Domain, api.AdminPath, oidcCallbackPath)
+}
+
+// oidcCookie returns a cookie that is only sent with requests to the admin routes
+func (s *Server) oidcCookie(name string, value string, expires time.Time) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     api.AdminPath,
+		Expires:  expires,
+		Secure:   s.prefix == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	}
+}
+
+// OIDCLogin redirects to the provider to sign in, the state is stored in a cookie so that
+// the callback can check that it belongs to a sign in started by the same browser
+func (s *Server) OIDCLogin(ctx *fiber.Ctx) error {
+	if s.oidc == nil {
+		return ctx.Status(fiber.StatusServiceUnavailable).SendString("oidc provider is not available")
+	}
+
+	next := ctx.Query(oidcNext)
+	if !strings.HasPrefix(next, api.AdminPath+"/") {
+		next = utils.JoinStrings(api.AdminPath, api.DashboardPath)
+	}
+
+	state := uuid.NewString()
+	ctx.Cookie(s.oidcCookie(oidcStateCookie, utils.JoinStrings(state, " ", next), time.Now().Add(oidcStateTimeout)))
+
+	oauth2Config := *s.oidc.oauth2
+	oauth2Config.RedirectURL = s.oidcRedirectURL()
+	return ctx.Redirect(oauth2Config.AuthCodeURL(state, oidc.Nonce(state)), fiber.StatusFound)
+}
+
+// OIDCCallback exchanges the code the provider redirected with for an ID token, which is
+// stored in the session cookie that authenticates the browser until the ID token expires
+func (s *Server) OIDCCallback(ctx *fiber.Ctx) error {
+	if s.oidc == nil {
+		return ctx.Status(fiber.StatusServiceUnavailable).SendString("oidc provider is not available")
+	}
+
+	state, next, ok := strings.Cut(ctx.Cookies(oidcStateCookie), " ")
+	ctx.ClearCookie(oidcStateCookie)
+	if !ok || state == "" || ctx.Query("state") != state {
+		return ctx.Status(fiber.StatusBadRequest).SendString("invalid oidc state")
+	}
+	if errorCode := ctx.Query("error"); errorCode != "" {
+		return ctx.Status(fiber.StatusUnauthorized).SendString(fmt.Sprintf("oidc sign in failed: %s", errorCode))
+	}
+
+	oauth2Config := *s.oidc.oauth2
+	oauth2Config.RedirectURL = s.oidcRedirectURL()
+	token, err := oauth2Config.Exchange(oidc.ClientContext(ctx.UserContext(), s.oidc.client), ctx.Query("code"))
+	if err != nil {
+		s.helper.Printer.Printf("Failed to exchange oidc code (request %s): %s\n", requestID(ctx), err)
+		return ctx.Status(fiber.StatusUnauthorized).SendString("oidc sign in failed")
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return ctx.Status(fiber.StatusUnauthorized).SendString("oidc provider did not return an id token")
+	}
+
+	adminToken, expiry, err := s.oidc.authenticate(ctx.UserContext(), rawIDToken, state)
+	if err != nil {
+		s.helper.Printer.Printf("Rejected oidc sign in from %s (request %s): %s\n", ctx.IP(), requestID(ctx), err)
+		return ctx.Status(fiber.StatusForbidden).SendString("forbidden")
+	}
+
+	s.helper.Printer.Printf("%s signed in to the admin routes from %s (request %s)\n", adminToken.Name, ctx.IP(), requestID(ctx))
+	ctx.Cookie(s.oidcCookie(oidcSessionCookie, rawIDToken, expiry))
+	return ctx.Redirect(next, fiber.StatusFound)
+}
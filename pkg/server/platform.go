@@ -0,0 +1,134 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/cache"
+	"sort"
+	"strings"
+)
+
+// osAliases maps common operating system names to their GOOS values
+var osAliases = map[string]string{
+	"macos": "darwin",
+	"osx":   "darwin",
+	"win":   "windows",
+}
+
+// archAliases maps the machine names reported by uname and other tools to their GOARCH values
+var archAliases = map[string]string{
+	"x86_64":  "amd64",
+	"x64":     "amd64",
+	"x86":     "386",
+	"i386":    "386",
+	"i686":    "386",
+	"aarch64": "arm64",
+	"riscv":   "riscv64",
+}
+
+// normalizeOS returns the GOOS value for the given operating system name
+func normalizeOS(os string) string {
+	os = strings.ToLower(os)
+	if alias, ok := osAliases[os]; ok {
+		return alias
+	}
+	return os
+}
+
+// normalizeArch returns the GOARCH value for the given architecture name
+func normalizeArch(arch string) string {
+	arch = strings.ToLower(arch)
+	if alias, ok := archAliases[arch]; ok {
+		return alias
+	}
+	return arch
+}
+
+// GetPlatforms returns the os/arch pairs the given release was built for as a newline separated
+// plain text list, which the install script shows when it was not built for the installing machine
+func (s *Server) GetPlatforms(ctx *fiber.Ctx) error {
+	return s.getPlatforms(ctx, s.defaultBinaryName())
+}
+
+// GetBinaryPlatforms returns the os/arch pairs the given binary of the given release was built for
+func (s *Server) GetBinaryPlatforms(ctx *fiber.Ctx) error {
+	return s.getPlatforms(ctx, strings.ToLower(param(ctx, "binary_name")))
+}
+
+func (s *Server) getPlatforms(ctx *fiber.Ctx, binaryName string) error {
+	releaseName := s.releaseNameParam(ctx)
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	if binaryName != "" && !s.cache.BinaryNameExists(binaryName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("binary not found")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetPlatforms from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "platforms", withBinaryName(binaryName, map[string]string{"release_name": releaseName}))
+	}
+
+	var b strings.Builder
+	for _, platform := range s.releasePlatforms(binaryName, releaseName) {
+		b.WriteString(platform)
+		b.WriteString("\n")
+	}
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.SendString(b.String())
+}
+
+// releasePlatforms returns the sorted os/arch pairs the given binary of the given release was built for
+func (s *Server) releasePlatforms(binaryName string, releaseName string) []string {
+	var artifacts []*cache.Artifact
+	for _, artifact := range s.cache.GetReleaseArtifacts(releaseName) {
+		if artifact.BinaryName == binaryName {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	return platforms(artifacts)
+}
+
+// buildFromSourceURL returns the URL of the instructions for building the binary from source, which
+// defaults to the GitHub repository, or an empty string if neither is configured
+func (s *Server) buildFromSourceURL() string {
+	if s.helper.Config.BuildFromSourceURL != "" {
+		return s.helper.Config.BuildFromSourceURL
+	}
+	if s.helper.Config.RepositoryOwner != "" && s.helper.Config.Repository != "" {
+		return fmt.Sprintf("https://github.com/%s/%s", s.helper.Config.RepositoryOwner, s.helper.Config.Repository)
+	}
+	return ""
+}
+
+// platforms returns the sorted, unique os/arch pairs the given artifacts were built for
+func platforms(artifacts []*cache.Artifact) []string {
+	seen := make(map[string]struct{}, len(artifacts))
+	for _, artifact := range artifacts {
+		seen[artifact.OS+"/"+artifact.Arch] = struct{}{}
+	}
+	result := make([]string, 0, len(seen))
+	for platform := range seen {
+		result = append(result, platform)
+	}
+	sort.Strings(result)
+	return result
+}
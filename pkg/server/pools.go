@@ -17,20 +17,108 @@
 package server
 
 import (
+	"bytes"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
 	"sync"
 )
 
 var listReleaseNamesResponsePool sync.Pool
 
-func getListReleaseNamesResponse() *ListReleaseNamesResponse {
+func getListReleaseNamesResponse() *v1.ListReleaseNamesResponse {
 	r := listReleaseNamesResponsePool.Get()
 	if r == nil {
-		r = new(ListReleaseNamesResponse)
+		r = new(v1.ListReleaseNamesResponse)
 	}
-	return r.(*ListReleaseNamesResponse)
+	return r.(*v1.ListReleaseNamesResponse)
 }
 
-func putListReleaseNamesResponse(r *ListReleaseNamesResponse) {
+func putListReleaseNamesResponse(r *v1.ListReleaseNamesResponse) {
 	r.ReleaseNames = nil
+	r.Releases = nil
+	r.Total = 0
 	listReleaseNamesResponsePool.Put(r)
 }
+
+var listReleasesResponsePool sync.Pool
+
+func getListReleasesResponse() *v1.ListReleasesResponse {
+	r := listReleasesResponsePool.Get()
+	if r == nil {
+		r = new(v1.ListReleasesResponse)
+	}
+	return r.(*v1.ListReleasesResponse)
+}
+
+func putListReleasesResponse(r *v1.ListReleasesResponse) {
+	r.Releases = nil
+	r.Total = 0
+	listReleasesResponsePool.Put(r)
+}
+
+var releaseMetadataPool sync.Pool
+
+func getReleaseMetadata() *v1.ReleaseMetadata {
+	r := releaseMetadataPool.Get()
+	if r == nil {
+		r = new(v1.ReleaseMetadata)
+	}
+	return r.(*v1.ReleaseMetadata)
+}
+
+// putReleaseMetadata keeps the capacity of the artifacts slice so that
+// the next response for a release of similar size does not grow it again
+func putReleaseMetadata(r *v1.ReleaseMetadata) {
+	for i := range r.Artifacts {
+		r.Artifacts[i] = nil
+	}
+	*r = v1.ReleaseMetadata{Artifacts: r.Artifacts[:0]}
+	releaseMetadataPool.Put(r)
+}
+
+var nixSourcesResponsePool sync.Pool
+
+func getNixSourcesResponse() *v1.NixSourcesResponse {
+	r := nixSourcesResponsePool.Get()
+	if r == nil {
+		r = &v1.NixSourcesResponse{Sources: make(map[string]*v1.NixSource)}
+	}
+	return r.(*v1.NixSourcesResponse)
+}
+
+func putNixSourcesResponse(r *v1.NixSourcesResponse) {
+	r.Version = ""
+	for system := range r.Sources {
+		delete(r.Sources, system)
+	}
+	nixSourcesResponsePool.Put(r)
+}
+
+var updateInfoPool sync.Pool
+
+func getUpdateInfo() *v1.UpdateInfo {
+	r := updateInfoPool.Get()
+	if r == nil {
+		r = new(v1.UpdateInfo)
+	}
+	return r.(*v1.UpdateInfo)
+}
+
+func putUpdateInfo(r *v1.UpdateInfo) {
+	*r = v1.UpdateInfo{}
+	updateInfoPool.Put(r)
+}
+
+var templateBufferPool sync.Pool
+
+func getTemplateBuffer() *bytes.Buffer {
+	b := templateBufferPool.Get()
+	if b == nil {
+		b = new(bytes.Buffer)
+	}
+	return b.(*bytes.Buffer)
+}
+
+func putTemplateBuffer(b *bytes.Buffer) {
+	b.Reset()
+	templateBufferPool.Put(b)
+}
@@ -0,0 +1,203 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/loopholelabs/releaser/embed"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"github.com/valyala/fasttemplate"
+)
+
+// benchmarkScriptParams are the parameters of a typical install script rendered in single-binary mode
+var benchmarkScriptParams = map[string]interface{}{
+	"domain":               shellQuote("releases.example.com"),
+	"release_name":         shellQuote("v1.2.0"),
+	"prefix":               shellQuote("https"),
+	"path_prefix":          shellQuote(""),
+	"binary":               shellQuote("cli"),
+	"install_names":        "",
+	"binaries":             shellQuote(""),
+	"binary_members":       "",
+	"binary_install_names": "",
+	"analytics":            "true",
+	"source":               "docs",
+	"license_acceptance":   "false",
+	"cacert":               shellQuotePath(""),
+	"user_install":         shellQuotePath("$HOME/.local/bin"),
+	"hook_phases":          "",
+	"download_attempts":    "3",
+	"checksums":            "true",
+	"build_from_source":    shellQuote(""),
+	"pre_install_hook":     "",
+	"post_install_hook":    "",
+}
+
+func BenchmarkRenderScript(b *testing.B) {
+	template := fasttemplate.New(embed.Shell, embed.StartTag, embed.EndTag)
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf := getTemplateBuffer()
+			if _, err := template.Execute(buf, benchmarkScriptParams); err != nil {
+				b.Fatal(err)
+			}
+			_ = append([]byte(nil), buf.Bytes()...)
+			putTemplateBuffer(buf)
+		}
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf := new(bytes.Buffer)
+			if _, err := template.Execute(buf, benchmarkScriptParams); err != nil {
+				b.Fatal(err)
+			}
+			_ = append([]byte(nil), buf.Bytes()...)
+		}
+	})
+}
+
+// benchmarkArtifacts returns the artifacts of a release built for the usual platforms
+func benchmarkArtifacts() []*v1.ReleaseArtifact {
+	var artifacts []*v1.ReleaseArtifact
+	for _, os := range []string{"darwin", "linux", "windows"} {
+		for _, arch := range []string{"amd64", "arm64"} {
+			name := fmt.Sprintf("cli_v1.2.0_%s_%s.tar.gz", os, arch)
+			artifacts = append(artifacts, &v1.ReleaseArtifact{
+				OS:       os,
+				Arch:     arch,
+				Name:     name,
+				Size:     8 * 1024 * 1024,
+				Checksum: "2c26b46b68ffc68ff99b453c1d30413413422d706483bfa0f98a5e886266e7ae",
+				URL:      "https://releases.example.com/v1.2.0/" + os + "/" + arch,
+			})
+		}
+	}
+	return artifacts
+}
+
+func BenchmarkRenderReleaseMetadata(b *testing.B) {
+	artifacts := benchmarkArtifacts()
+	platforms := []string{"darwin/amd64", "darwin/arm64", "linux/amd64", "linux/arm64", "windows/amd64", "windows/arm64"}
+
+	fill := func(res *v1.ReleaseMetadata) {
+		res.Name = "v1.2.0"
+		res.Title = "Release v1.2.0"
+		res.Latest = true
+		res.PublishedAt = 1700000000
+		res.Platforms = platforms
+		for _, artifact := range artifacts {
+			copied := *artifact
+			res.Artifacts = append(res.Artifacts, &copied)
+		}
+	}
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			res := getReleaseMetadata()
+			fill(res)
+			if _, err := json.Marshal(res); err != nil {
+				b.Fatal(err)
+			}
+			putReleaseMetadata(res)
+		}
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			res := new(v1.ReleaseMetadata)
+			fill(res)
+			if _, err := json.Marshal(res); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkRenderNixSources(b *testing.B) {
+	fill := func(res *v1.NixSourcesResponse) {
+		res.Version = "1.2.0"
+		for platform, system := range nixSystems {
+			res.Sources[system] = &v1.NixSource{
+				URL:  "https://releases.example.com/v1.2.0/" + platform,
+				Hash: "sha256-LCa0a2j/xo/5m0U8HTBBNBNCLXBkg7+g+YpeiGJm564=",
+			}
+		}
+	}
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			res := getNixSourcesResponse()
+			fill(res)
+			if _, err := json.Marshal(res); err != nil {
+				b.Fatal(err)
+			}
+			putNixSourcesResponse(res)
+		}
+	})
+
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			res := &v1.NixSourcesResponse{Sources: make(map[string]*v1.NixSource)}
+			fill(res)
+			if _, err := json.Marshal(res); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkRenderedScriptLookup(b *testing.B) {
+	// multi-binary keys of pre-releases are longer than the buffer the compiler concatenates short strings in
+	releaseName, binaryName, source := "v1.12.0-rc.1", "releaser-agent", "homebrew"
+	script := []byte("#!/bin/sh")
+
+	b.Run("struct key", func(b *testing.B) {
+		scripts := &scriptCache{scripts: make(map[scriptKey][]byte)}
+		scripts.store(scriptKey{releaseName: releaseName, binaryName: binaryName, analytics: true, source: source}, script)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, ok := scripts.load(scriptKey{releaseName: releaseName, binaryName: binaryName, analytics: true, source: source}); !ok {
+				b.Fatal("script not found")
+			}
+		}
+	})
+
+	b.Run("string key", func(b *testing.B) {
+		var scripts sync.Map
+		scripts.Store(releaseName+"/"+binaryName+"/"+strconv.FormatBool(true)+"/"+source, script)
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, ok := scripts.Load(releaseName + "/" + binaryName + "/" + strconv.FormatBool(true) + "/" + source); !ok {
+				b.Fatal("script not found")
+			}
+		}
+	})
+}
@@ -0,0 +1,63 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/limiter"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// newRateLimiter returns a handler that limits the number of requests each client may send within the
+// rate limit window. Health checks and admin requests are never rate limited.
+func (s *Server) newRateLimiter() fiber.Handler {
+	return limiter.New(limiter.Config{
+		Max:        s.helper.Config.RateLimit,
+		Expiration: s.helper.Config.RateLimitWindow,
+		Next: func(ctx *fiber.Ctx) bool {
+			return ctx.Path() == api.PingPath || ctx.Path() == api.HealthPath || ctx.Path() == api.ReadyPath || strings.HasPrefix(ctx.Path(), api.AdminPath+"/")
+		},
+		LimitReached: func(ctx *fiber.Ctx) error {
+			// the limiter sets the Retry-After header to the number of seconds until the window ends
+			seconds, _ := strconv.Atoi(ctx.GetRespHeader(fiber.HeaderRetryAfter))
+			s.helper.Printer.Printf("Rate limited %s (request %s)\n", ctx.IP(), requestID(ctx))
+			return s.tooManyRequests(ctx, "rate limit exceeded", time.Duration(seconds)*time.Second)
+		},
+	})
+}
+
+// tooManyRequests responds with a 429 asking the client to retry after the given duration
+func (s *Server) tooManyRequests(ctx *fiber.Ctx, reason string, retryAfter time.Duration) error {
+	return s.retryLater(ctx, fiber.StatusTooManyRequests, reason, retryAfter)
+}
+
+// retryLater responds with the given status asking the client to retry after the given duration
+func (s *Server) retryLater(ctx *fiber.Ctx, status int, reason string, retryAfter time.Duration) error {
+	seconds := int((retryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return ctx.Status(status).JSON(&v1.ErrorResponse{
+		Error:      reason,
+		RetryAfter: seconds,
+	})
+}
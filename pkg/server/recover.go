@@ -0,0 +1,39 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/internal/log"
+	"runtime/debug"
+)
+
+// reportPanic logs a panic recovered from a handler along with its stack trace
+// and reports it as an analytics event, the request is answered with a 500
+func (s *Server) reportPanic(ctx *fiber.Ctx, e interface{}) {
+	s.helper.Printer.Printf("error: recovered from panic while handling %s (request %s): %v\n", ctx.Path(), requestID(ctx), e)
+	log.Logger.Error().
+		Str("path", ctx.Path()).
+		Str("request_id", requestID(ctx)).
+		Bytes("stack", debug.Stack()).
+		Msgf("recovered from panic: %v", e)
+	s.event(ctx, "panic", map[string]string{
+		"path":  ctx.Path(),
+		"panic": fmt.Sprint(e),
+	})
+}
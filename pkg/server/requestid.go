@@ -0,0 +1,91 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"bytes"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"github.com/google/uuid"
+	"github.com/loopholelabs/releaser/analytics"
+	"github.com/loopholelabs/releaser/pkg/api"
+)
+
+// requestIDKey is the key the request ID is stored under in the request locals
+const requestIDKey = "request_id"
+
+// newRequestID returns the middleware that assigns every request an ID, which is
+// returned in the X-Request-Id header and appended to plain text error responses
+// so that failed installations reported by users can be found in the logs. An ID
+// sent by the client is only kept if it is a UUID, since it is written to the logs
+// and analytics.
+func newRequestID() fiber.Handler {
+	assign := requestid.New(requestid.Config{
+		Header:     fiber.HeaderXRequestID,
+		Generator:  uuid.NewString,
+		ContextKey: requestIDKey,
+	})
+	return func(ctx *fiber.Ctx) error {
+		if id := ctx.Get(fiber.HeaderXRequestID); id != "" && !validRequestID(id) {
+			ctx.Request().Header.Del(fiber.HeaderXRequestID)
+		}
+
+		err := assign(ctx)
+		if err != nil || ctx.Response().StatusCode() < fiber.StatusBadRequest {
+			return err
+		}
+		// compressed and streamed bodies can not be appended to
+		if ctx.Response().IsBodyStream() || len(ctx.Response().Header.Peek(fiber.HeaderContentEncoding)) > 0 {
+			return nil
+		}
+		if bytes.HasPrefix(ctx.Response().Header.ContentType(), []byte(fiber.MIMETextPlain)) {
+			ctx.Response().AppendBodyString("\nrequest id: " + requestID(ctx) + "\n")
+		}
+		return nil
+	}
+}
+
+// validRequestID returns true if the given request ID is a UUID in its canonical form
+func validRequestID(id string) bool {
+	_, err := uuid.Parse(id)
+	return err == nil && len(id) == 36
+}
+
+// requestID returns the ID of the given request
+func requestID(ctx *fiber.Ctx) string {
+	if id, ok := ctx.Locals(requestIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// event sends an analytics event for the given request, tagged with its request ID
+func (s *Server) event(ctx *fiber.Ctx, name string, properties ...map[string]string) {
+	props := []analytics.Property{
+		analytics.DistinctID(s.distinctID(ctx)),
+		analytics.NewProperty(requestIDKey, requestID(ctx)),
+	}
+	if source := s.installSource(ctx); source != "" {
+		props = append(props, analytics.NewProperty(api.Source, source))
+	}
+	if len(properties) > 0 {
+		for key, value := range properties[0] {
+			props = append(props, analytics.NewProperty(key, value))
+		}
+	}
+	analytics.EventContext(withRequest(ctx), name, props...)
+}
@@ -0,0 +1,26 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+type InstallResultRequest struct {
+	ReleaseName string `json:"release_name" form:"release_name"`
+	Binary      string `json:"binary" form:"binary"`
+	OS          string `json:"os" form:"os"`
+	Arch        string `json:"arch" form:"arch"`
+	Success     bool   `json:"success" form:"success"`
+	Stage       string `json:"stage" form:"stage"`
+}
@@ -16,6 +16,9 @@
 
 package server
 
-type ListReleaseNamesResponse struct {
-	ReleaseNames []string `json:"release_names"`
+import "github.com/loopholelabs/releaser/pkg/cache"
+
+type IntegrityResponse struct {
+	Healthy bool                    `json:"healthy"`
+	Issues  []*cache.IntegrityIssue `json:"issues"`
 }
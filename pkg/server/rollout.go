@@ -0,0 +1,59 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/internal/config"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"hash/fnv"
+	"strconv"
+)
+
+// latestReleaseName returns the latest release for the given client, which is the release configured
+// for its cohort if it selected one, or the previous release while a rollout is in progress and the
+// client is not part of it yet
+func (s *Server) latestReleaseName(ctx *fiber.Ctx) string {
+	latestReleaseName := s.cache.GetLatestReleaseName()
+	if cohort := ctx.Query(api.Cohort); cohort != "" {
+		if releaseName, ok := s.helper.Config.GetCohortRelease(cohort); ok {
+			if releaseName == config.CohortLatest {
+				ctx.Set(api.CohortHeader, cohort)
+				return latestReleaseName
+			}
+			if canonicalName, ok := s.cache.CanonicalReleaseName(releaseName); ok {
+				ctx.Set(api.CohortHeader, cohort)
+				return canonicalName
+			}
+			s.helper.Printer.Printf("error: release %s configured for cohort %s does not exist\n", releaseName, cohort)
+		}
+	}
+
+	rollout := s.cache.GetRollout()
+	if rollout.PreviousReleaseName == "" {
+		return latestReleaseName
+	}
+	ctx.Set(api.RolloutPercentageHeader, strconv.Itoa(rollout.Percentage))
+	ctx.Set(api.RolloutPreviousReleaseHeader, rollout.PreviousReleaseName)
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(ctx.Query(api.ClientID, ctx.IP())))
+	if int(h.Sum32()%100) < rollout.Percentage {
+		return latestReleaseName
+	}
+	return rollout.PreviousReleaseName
+}
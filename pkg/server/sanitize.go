@@ -0,0 +1,130 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// maxParamLength is the maximum length of a route parameter, which is far longer than
+// any release, binary, or asset name but keeps garbage out of the lookups and analytics
+const maxParamLength = 128
+
+// knownOS and knownArch are the GOOS and GOARCH values accepted in the os and arch route
+// parameters after their aliases are resolved, "all" is used for universal binaries
+var (
+	knownOS = map[string]struct{}{
+		"aix": {}, "android": {}, "darwin": {}, "dragonfly": {}, "freebsd": {}, "illumos": {}, "ios": {}, "js": {},
+		"linux": {}, "netbsd": {}, "openbsd": {}, "plan9": {}, "solaris": {}, "wasip1": {}, "windows": {},
+	}
+	knownArch = map[string]struct{}{
+		"386": {}, "amd64": {}, "arm": {}, "arm64": {}, "loong64": {}, "mips": {}, "mipsle": {}, "mips64": {},
+		"mips64le": {}, "ppc64": {}, "ppc64le": {}, "riscv64": {}, "s390x": {}, "wasm": {}, "all": {},
+	}
+)
+
+// validateParams rejects requests with a 400 if one of their route parameters is too long, contains characters
+// that are not part of release, binary, version, platform, or asset names, or is not a known os or arch, so that
+// garbage never reaches the cache lookups, analytics, or the URLs built from the parameters
+func validateParams(ctx *fiber.Ctx) error {
+	for _, name := range ctx.Route().Params {
+		if err := validateParam(name, ctx.Params(name)); err != "" {
+			return ctx.Status(fiber.StatusBadRequest).JSON(&v1.ErrorResponse{
+				Error:     err,
+				Parameter: name,
+			})
+		}
+	}
+	return ctx.Next()
+}
+
+// validateParam returns why the given value of the given route parameter is invalid, or an empty string if it is valid
+func validateParam(name string, value string) string {
+	if len(value) == 0 {
+		return fmt.Sprintf("%s must not be empty", name)
+	}
+	if len(value) > maxParamLength {
+		return fmt.Sprintf("%s must not be longer than %d characters", name, maxParamLength)
+	}
+	for i := 0; i < len(value); i++ {
+		if !safeParamCharacter(value[i]) {
+			return fmt.Sprintf("%s contains invalid characters", name)
+		}
+	}
+
+	switch name {
+	case "os":
+		if _, ok := knownOS[normalizeOS(value)]; !ok {
+			return fmt.Sprintf("unknown os '%s'", value)
+		}
+	case "arch":
+		// variants are appended to the architecture (e.g. amd64_v1, arm_7, or armv7)
+		arch := normalizeArch(value)
+		if i := strings.IndexByte(arch, '_'); i > 0 {
+			arch = arch[:i]
+		}
+		if strings.HasPrefix(arch, "armv") {
+			arch = "arm"
+		}
+		if _, ok := knownArch[arch]; !ok {
+			return fmt.Sprintf("unknown arch '%s'", value)
+		}
+	}
+	return ""
+}
+
+// param returns the given route parameter if it only contains the characters release names, binary names,
+// versions, platforms, and asset names are made of, or an empty string otherwise. The parameters are reflected
+// in responses and URLs, so anything else (e.g. control characters or escapes) is treated as if it was missing,
+// which makes the routes respond as if the release, binary, or asset did not exist.
+func param(ctx *fiber.Ctx, name string) string {
+	value := ctx.Params(name)
+	for i := 0; i < len(value); i++ {
+		if !safeParamCharacter(value[i]) {
+			return ""
+		}
+	}
+	return value
+}
+
+func safeParamCharacter(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	case c == '.', c == '_', c == '-', c == '+', c == '~':
+		return true
+	default:
+		return false
+	}
+}
+
+// forwardedQuery returns the query string forwarded when redirecting to the script of a release, which only
+// contains the allowed parameters with normalized values so that no other parameters can be injected
+func (s *Server) forwardedQuery(ctx *fiber.Ctx) string {
+	query := url.Values{}
+	query.Set(api.Analytics, strconv.FormatBool(ctx.Query(api.Analytics, "true") != "false"))
+	if source := s.installSource(ctx); source != "" {
+		query.Set(api.Source, source)
+	}
+	return query.Encode()
+}
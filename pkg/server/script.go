@@ -0,0 +1,84 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// sortedKeys returns the keys of the given map in sorted order
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// shellQuote quotes the given value as a single shell word, so values from the config or
+// the release provider can never be interpreted as shell code by the install script
+func shellQuote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
+
+// shellQuotePath quotes the given path like shellQuote, except for a leading $HOME or ~ which is
+// left to the install script to expand to the home directory of the installing user
+func shellQuotePath(path string) string {
+	for _, home := range []string{"$HOME", "${HOME}", "~"} {
+		if path == home {
+			return `"$HOME"`
+		}
+		if rest, ok := strings.CutPrefix(path, home+"/"); ok {
+			return `"$HOME"/` + shellQuote(rest)
+		}
+	}
+	return shellQuote(path)
+}
+
+// installNames renders the per-OS install name overrides as the branches of a shell case statement
+func (s *Server) installNames() string {
+	var b strings.Builder
+	for _, os := range sortedKeys(s.helper.Config.InstallNames) {
+		b.WriteString(fmt.Sprintf("    %s) binary=%s ;;\n", shellQuote(os), shellQuote(s.helper.Config.GetInstallName(os))))
+	}
+	return b.String()
+}
+
+// binaryMembers renders the path of each configured binary inside the release artifact
+// as the branches of a shell case statement
+func (s *Server) binaryMembers() string {
+	var b strings.Builder
+	for _, binary := range s.helper.Config.Binaries {
+		b.WriteString(fmt.Sprintf("    %s) echo %s ;;\n", shellQuote(binary.Name), shellQuote(binary.GetPath())))
+	}
+	return b.String()
+}
+
+// binaryInstallNames renders the per-OS install name overrides of each configured binary
+// as the branches of a shell case statement
+func (s *Server) binaryInstallNames() string {
+	var b strings.Builder
+	for _, binary := range s.helper.Config.Binaries {
+		for _, os := range sortedKeys(binary.InstallNames) {
+			b.WriteString(fmt.Sprintf("    %s) echo %s ;;\n", shellQuote(binary.Name+"-"+os), shellQuote(binary.GetInstallName(os))))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import "sync"
+
+// scriptKey identifies a rendered install script, it is a struct rather than a formatted
+// string so that looking up the script of a request does not allocate
+type scriptKey struct {
+	releaseName string
+	binaryName  string
+	analytics   bool
+	source      string
+}
+
+// scriptCache stores the install scripts rendered while the cache was at the given generation
+type scriptCache struct {
+	generation uint64
+
+	mu      sync.RWMutex
+	scripts map[scriptKey][]byte
+}
+
+// load returns the script rendered for the given key, if any
+func (c *scriptCache) load(key scriptKey) ([]byte, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	script, ok := c.scripts[key]
+	return script, ok
+}
+
+// store caches the script rendered for the given key
+func (c *scriptCache) store(key scriptKey, script []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.scripts[key] = script
+}
+
+// renderedScripts returns the install scripts rendered for the current generation of the cache.
+// The scripts rendered before the last refresh of the cache are dropped, so that the scripts
+// of removed releases do not accumulate and the template is executed once per release and
+// refresh instead of once per request.
+func (s *Server) renderedScripts() *scriptCache {
+	generation := s.cache.Generation()
+	current := s.scripts.Load()
+	if current != nil && current.generation == generation {
+		return current
+	}
+
+	next := &scriptCache{generation: generation, scripts: make(map[scriptKey][]byte)}
+	if s.scripts.CompareAndSwap(current, next) {
+		return next
+	}
+	return s.scripts.Load()
+}
@@ -0,0 +1,58 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/helmet/v2"
+	"github.com/loopholelabs/releaser/internal/config"
+)
+
+const (
+	// strictHSTSMaxAge is the max-age of the HSTS header sent by the strict profile, which
+	// is the two years required by the HSTS preload lists
+	strictHSTSMaxAge = 63072000
+
+	// strictContentSecurityPolicy blocks everything, since the only HTML served is the
+	// go-import page, which is only read by the go tool
+	strictContentSecurityPolicy = "default-src 'none'; base-uri 'none'; form-action 'none'; frame-ancestors 'none'"
+)
+
+// securityHeaders returns the helmet config for the given security headers, which starts from
+// the defaults of the configured profile and applies the headers that are set on top of them
+func securityHeaders(headers *config.SecurityHeaders) helmet.Config {
+	var c helmet.Config
+	if headers.Profile == config.SecurityProfileStrict {
+		c.HSTSMaxAge = strictHSTSMaxAge
+		c.HSTSPreloadEnabled = true
+		c.ContentSecurityPolicy = strictContentSecurityPolicy
+		c.XFrameOptions = "DENY"
+	}
+
+	if headers.HSTSMaxAge > 0 {
+		c.HSTSMaxAge = headers.HSTSMaxAge
+	}
+	if headers.HSTSPreload {
+		c.HSTSPreloadEnabled = true
+	}
+	if headers.ReferrerPolicy != "" {
+		c.ReferrerPolicy = headers.ReferrerPolicy
+	}
+	if headers.ContentSecurityPolicy != "" {
+		c.ContentSecurityPolicy = headers.ContentSecurityPolicy
+	}
+	return c
+}
@@ -17,64 +17,169 @@
 package server
 
 import (
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/compress"
+	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/gofiber/helmet/v2"
-	"github.com/google/go-github/v55/github"
 	"github.com/loopholelabs/cmdutils"
 	"github.com/loopholelabs/releaser/analytics"
 	"github.com/loopholelabs/releaser/embed"
+	"github.com/loopholelabs/releaser/internal/audit"
 	"github.com/loopholelabs/releaser/internal/config"
-	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/internal/httpclient"
 	"github.com/loopholelabs/releaser/internal/log"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
 	"github.com/loopholelabs/releaser/pkg/cache"
+	"github.com/loopholelabs/releaser/pkg/provider"
+	"github.com/loopholelabs/releaser/pkg/reference"
+	"github.com/loopholelabs/releaser/pkg/registry"
 	"github.com/valyala/fasttemplate"
+	"golang.org/x/time/rate"
+	"html"
+	"io"
 	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
-	"regexp"
 )
 
 const (
-	LatestReleasePath     = "/"
-	PingPath              = "/ping"
-	LatestReleaseNamePath = "/latest"
-	ListReleaseNamesPath  = "/releases"
-	ChecksumPath          = "/checksum"
-
+	BinaryNameArgPath  = "/:binary_name"
 	ReleaseNameArgPath = "/:release_name"
+	VersionArgPath     = "/:version"
+	AssetNameArgPath   = "/:asset_name"
 	OSArgPath          = "/:os"
 	ArchArgPath        = "/:arch"
+	PhaseArgPath       = "/:phase"
 
-	Analytics = "analytics"
+	GoGet = "go-get"
 )
 
+// releaseNameRegex checks for anything but "v" / numerics / "."
+var releaseNameRegex = regexp.MustCompile(`^[^a-zA-Z]*[vV][^a-zA-Z]*$`)
+
 type Server struct {
 	app      *fiber.App
+	adminApp *fiber.App
 	cache    *cache.Cache
-	github   *github.Client
+	provider provider.Provider
 	helper   *cmdutils.Helper[*config.Config]
 	prefix   string
 	template *fasttemplate.Template
+
+	// scripts stores the rendered install scripts of the current cache generation, see renderedScripts
+	scripts atomic.Pointer[scriptCache]
+
+	goImportTemplate  *fasttemplate.Template
+	systemdTemplate   *fasttemplate.Template
+	dashboardTemplate *fasttemplate.Template
+
+	httpServer *http.Server
+	startTime  time.Time
+
+	maintenance atomic.Bool
+
+	// draining is set once the server is shutting down, which is reported by the readiness route
+	draining atomic.Bool
+
+	anonymizer *anonymizer
+
+	// stats is the store the stats routes are served from, it is nil unless analytics are stored locally
+	stats StatsStore
+
+	// statsSummaries caches the summaries computed from the stats store, see GetStatsSummary
+	statsSummaries *statsSummaries
+
+	// audit records the admin requests and the changes they make, it is nil if no audit log is configured
+	audit *audit.Log
+
+	// bandwidth limits the bytes per second sent across all artifact responses, it is nil if it is unlimited
+	bandwidth *rate.Limiter
+
+	// transfers limits the number of artifact responses sent at the same time, it is nil if it is unlimited
+	transfers *transferSlots
+
+	// eventSubscribers is the number of clients subscribed to the event stream, and stopEvents
+	// is closed to end their streams when the server stops
+	eventSubscribers atomic.Int64
+	stopEvents       chan struct{}
+
+	// adminTokens are the tokens accepted by the admin routes, see AdminAuth
+	adminTokens []adminToken
+
+	// oidc signs users in to the admin routes, it is nil if no OIDC provider is configured
+	oidc *oidcAuth
+
+	registry         *registry.Client
+	imageRepository  *reference.Repository
+	imageTagTemplate *fasttemplate.Template
 }
 
-func New(github *github.Client, helper *cmdutils.Helper[*config.Config]) *Server {
+func New(provider provider.Provider, helper *cmdutils.Helper[*config.Config]) *Server {
 	s := &Server{
 		app: fiber.New(fiber.Config{
 			ServerHeader:                 helper.Config.Hostname,
-			BodyLimit:                    -1,
+			BodyLimit:                    helper.Config.MaxBodySize,
+			ReadBufferSize:               helper.Config.MaxHeaderSize,
+			Concurrency:                  helper.Config.Concurrency,
 			ReadTimeout:                  time.Minute * 3,
-			WriteTimeout:                 time.Second * 30,
+			WriteTimeout:                 writeTimeout,
 			IdleTimeout:                  time.Second * 30,
-			GETOnly:                      true,
+			GETOnly:                      helper.Config.GETOnly,
 			DisableKeepalive:             true,
 			DisableStartupMessage:        true,
 			DisablePreParseMultipartForm: true,
-			ProxyHeader:                  "X-Forwarded-For",
+
+			// the client IP is only read from the X-Forwarded-For header of the trusted proxies, so that
+			// clients can not evade the rate limit by sending the header themselves
+			ProxyHeader:             fiber.HeaderXForwardedFor,
+			EnableTrustedProxyCheck: true,
+			TrustedProxies:          helper.Config.TrustedProxies,
+			EnableIPValidation:      true,
 		}),
-		github: github,
-		helper: helper,
+		provider:   provider,
+		helper:     helper,
+		anonymizer: newAnonymizer(helper.Config.AnalyticsSalt),
+		bandwidth:  newBandwidthLimiter(helper.Config.BandwidthLimit),
+		transfers:  newTransferSlots(helper.Config.MaxConcurrentTransfers, helper.Config.TransferQueueSize, helper.Config.TransferQueueTimeout),
+		stopEvents: make(chan struct{}),
+	}
+
+	s.adminTokens = newAdminTokens(helper.Config)
+	if helper.Config.AdminListenAddress != "" {
+		s.adminApp = s.newAdminApp()
+	}
+
+	if helper.Config.ImageRepository != "" {
+		s.imageRepository, _ = reference.ParseRepository(helper.Config.ImageRepository)
+	}
+
+	s.maintenance.Store(helper.Config.Maintenance)
+
+	analytics.SetDefaults(
+		analytics.NewProperty("server", helper.Config.Hostname),
+		analytics.NewProperty("domain", helper.Config.Domain),
+	)
+	analytics.SetSampleRates(helper.Config.AnalyticsSampleRates)
+	analytics.SetAllowedEvents(helper.Config.AnalyticsEvents)
+	for _, name := range helper.Config.AnalyticsEnrichers {
+		if enricher := s.enricher(name); enricher != nil {
+			analytics.Use(enricher)
+		}
 	}
 
 	s.init()
@@ -84,18 +189,50 @@ func New(github *github.Client, helper *cmdutils.Helper[*config.Config]) *Server
 
 func (s *Server) Start(address string, config *tls.Config, tlsOverride bool) (err error) {
 	s.template = fasttemplate.New(embed.Shell, embed.StartTag, embed.EndTag)
-	s.cache, err = cache.New(s.github, s.helper)
+	s.goImportTemplate = fasttemplate.New(embed.GoImport, embed.StartTag, embed.EndTag)
+	s.systemdTemplate = fasttemplate.New(embed.Systemd, embed.StartTag, embed.EndTag)
+	s.dashboardTemplate = fasttemplate.New(embed.Dashboard, embed.StartTag, embed.EndTag)
+	s.imageTagTemplate = fasttemplate.New(s.helper.Config.ImageTag, embed.StartTag, embed.EndTag)
+	s.startTime = time.Now()
+
+	if s.imageRepository != nil {
+		registryClient, err := httpclient.New(s.helper.Config.GetAPIOptions())
+		if err != nil {
+			return err
+		}
+		s.registry = registry.New(registryClient)
+	}
+
+	s.cache, err = cache.New(s.provider, s.helper, s.audit)
+	if err != nil {
+		return err
+	}
+
+	s.oidc, err = newOIDCAuth(context.Background(), s.helper.Config)
 	if err != nil {
 		return err
 	}
 
+	if s.adminApp != nil {
+		err = s.startAdmin(s.helper.Config.AdminListenAddress)
+		if err != nil {
+			return err
+		}
+	}
+
 	listener, err := net.Listen("tcp", address)
 	if err != nil {
 		return err
 	}
 
+	useHTTP2 := s.helper.Config.HTTP2 || s.helper.Config.H2C
+
 	s.prefix = "http"
 	if config != nil {
+		if useHTTP2 {
+			config = config.Clone()
+			config.NextProtos = http2NextProtos
+		}
 		listener = tls.NewListener(listener, config)
 	}
 
@@ -104,24 +241,175 @@ func (s *Server) Start(address string, config *tls.Config, tlsOverride bool) (er
 	}
 
 	s.helper.Printer.Printf("Starting server on %s://%s (domain %s)\n", s.prefix, address, s.helper.Config.Domain)
+	if useHTTP2 {
+		return s.serveHTTP2(listener, config != nil)
+	}
 	return s.app.Listener(listener)
 }
 
 func (s *Server) Stop() error {
+	s.endEventStreams()
+	if s.adminApp != nil {
+		_ = s.adminApp.Shutdown()
+	}
+	if s.httpServer != nil {
+		return s.httpServer.Shutdown(context.Background())
+	}
 	return s.app.Shutdown()
 }
 
 func (s *Server) init() {
-	s.app.Use(helmet.New())
+	s.app.Use(recover.New(recover.Config{
+		EnableStackTrace:  true,
+		StackTraceHandler: s.reportPanic,
+	}))
+	s.app.Use(newRequestID())
+	s.app.Use(s.LimitURI)
+	if s.helper.Config.RateLimit > 0 {
+		s.app.Use(s.newRateLimiter())
+	}
+	s.app.Use(helmet.New(securityHeaders(&s.helper.Config.SecurityHeaders)))
+	s.app.Use(s.Maintenance)
 
-	s.app.Get(PingPath, s.GetPing)
-	s.app.Get(LatestReleasePath, s.GetLatestReleaseShellScript)
-	s.app.Get(LatestReleaseNamePath, s.GetLatestReleaseName)
-	s.app.Get(ListReleaseNamesPath, s.ListReleaseNames)
-	s.app.Get(ReleaseNameArgPath, s.GetReleaseShellScript)
+	if s.helper.Config.GoImportPath != "" {
+		s.app.Use(s.GoImport)
+	}
+
+	// compressed is only used for metadata and script routes, since artifacts are already compressed
+	compressed := compress.New(compress.Config{
+		Level: compress.LevelBestSpeed,
+	})
+
+	s.app.Get(api.PingPath, s.GetPing)
+	s.app.Get(api.HealthPath, s.GetHealth)
+	s.app.Get(api.ReadyPath, s.GetReady)
+	s.app.Get(api.LatestReleasePath, compressed, s.GetLatestReleaseShellScript)
+
+	// The metadata routes are served under /v1 and, for compatibility with deployed install
+	// scripts and clients, without a prefix. They are registered before the release routes
+	// since those would otherwise shadow them.
+	s.initV1(s.app.Group(api.V1Path), compressed)
+	s.initV1(s.app, compressed)
+
+	s.app.Get(utils.JoinStrings(api.DownloadPath, VersionArgPath, OSArgPath, ArchArgPath), validateParams, s.GetDownloadURL)
+
+	if s.imageRepository != nil {
+		s.app.Get(api.ImagePath, s.GetLatestImageReference)
+		s.app.Get(utils.JoinStrings(api.ImagePath, ReleaseNameArgPath), validateParams, s.GetImageReference)
+		s.app.Get(api.DockerPath, s.GetLatestDockerRun)
+		s.app.Get(utils.JoinStrings(api.DockerPath, ReleaseNameArgPath), validateParams, s.GetDockerRun)
+	}
+
+	s.app.Get(utils.JoinStrings(api.AssetPath, ReleaseNameArgPath, AssetNameArgPath), validateParams, s.GetReleaseAsset)
+	s.app.Get(utils.JoinStrings(api.LicensePath, ReleaseNameArgPath), validateParams, compressed, s.GetReleaseLicense)
+	s.app.Get(utils.JoinStrings(api.WhyPath, ReleaseNameArgPath, OSArgPath, ArchArgPath), validateParams, s.GetWhy)
+	s.app.Get(utils.JoinStrings(api.HooksPath, PhaseArgPath), validateParams, s.GetHook)
+	s.app.Get(utils.JoinStrings(api.SystemdPath, ReleaseNameArgPath), validateParams, s.GetSystemdUnit)
 
-	s.app.Get(utils.JoinStrings(ChecksumPath, ReleaseNameArgPath, OSArgPath, ArchArgPath), s.GetChecksum)
-	s.app.Get(utils.JoinStrings(ReleaseNameArgPath, OSArgPath, ArchArgPath), s.GetReleaseArtifact)
+	if s.adminApp != nil {
+		s.initAdmin(s.adminApp, compressed)
+	} else if s.helper.Config.AdminAuthEnabled() {
+		s.initAdmin(s.app, compressed)
+	}
+
+	// GET routes also answer HEAD requests, the POST routes and OPTIONS are only
+	// registered if the server accepts methods other than GET and HEAD
+	if !s.helper.Config.GETOnly {
+		s.app.Post(utils.JoinStrings(api.TelemetryPath, api.InstallResultPath), limitBody(telemetryBodyLimit), s.PostInstallResult)
+		s.app.Options("/*", s.Options)
+	}
+
+	s.app.Get(ReleaseNameArgPath, validateParams, compressed, s.GetReleaseShellScript)
+
+	s.app.Get(utils.JoinStrings(ReleaseNameArgPath, OSArgPath, ArchArgPath), validateParams, s.GetReleaseArtifact)
+
+	// The binary routes are registered last so that they never shadow the fixed routes. A binary
+	// named after the first segment of a fixed route (see api.ReservedNames) would be shadowed by
+	// it instead, which is why those names are rejected by the config and skipped by the cache.
+	if s.helper.Config.MultiBinary {
+		s.app.Get(utils.JoinStrings(api.WhyPath, BinaryNameArgPath, ReleaseNameArgPath, OSArgPath, ArchArgPath), validateParams, s.GetBinaryWhy)
+		s.app.Get(utils.JoinStrings(BinaryNameArgPath, ReleaseNameArgPath, OSArgPath, ArchArgPath), validateParams, s.GetBinaryReleaseArtifact)
+		s.app.Get(utils.JoinStrings(BinaryNameArgPath, ReleaseNameArgPath), validateParams, compressed, s.GetBinaryReleaseShellScript)
+	}
+}
+
+// initV1 registers the JSON and metadata routes of version 1 of the API on the given router
+func (s *Server) initV1(router fiber.Router, compressed fiber.Handler) {
+	router.Get(api.LatestReleaseNamePath, s.GetLatestReleaseName)
+	router.Get(api.ListReleaseNamesPath, compressed, s.ListReleaseNames)
+	router.Get(utils.JoinStrings(api.APIPath, api.ListReleasesPath), compressed, s.ListReleases)
+	router.Get(utils.JoinStrings(api.APIPath, api.ListReleasesPath, ReleaseNameArgPath), validateParams, compressed, s.GetReleaseMetadata)
+	router.Get(utils.JoinStrings(api.APIPath, api.UpdatePath), s.GetUpdateInfo)
+	router.Get(utils.JoinStrings(api.APIPath, api.StatusPath), s.GetStatus)
+	router.Get(utils.JoinStrings(api.APIPath, api.ServerInfoPath), s.GetServerInfo)
+	router.Get(api.EventsPath, s.GetEvents)
+	if s.helper.Config.Winget.PackageIdentifier != "" {
+		router.Get(api.WingetPath, compressed, s.GetLatestWingetManifest)
+		router.Get(utils.JoinStrings(api.WingetPath, ReleaseNameArgPath), validateParams, compressed, s.GetWingetManifest)
+	}
+
+	router.Get(api.NixPath, compressed, s.GetLatestNixSources)
+	router.Get(utils.JoinStrings(api.NixPath, ReleaseNameArgPath), validateParams, compressed, s.GetNixSources)
+	router.Get(api.VersionsPath, compressed, s.ListVersions)
+	router.Get(utils.JoinStrings(api.StatsPath, api.SummaryPath), compressed, s.GetStatsSummary)
+
+	router.Get(utils.JoinStrings(api.ChecksumPath, ReleaseNameArgPath, OSArgPath, ArchArgPath), validateParams, s.GetChecksum)
+	router.Get(utils.JoinStrings(api.ChecksumsPath, ReleaseNameArgPath), validateParams, compressed, s.GetReleaseChecksums)
+	router.Get(utils.JoinStrings(api.SignaturePath, ReleaseNameArgPath, OSArgPath, ArchArgPath), validateParams, s.GetSignature)
+	router.Get(utils.JoinStrings(api.PlatformsPath, ReleaseNameArgPath), validateParams, s.GetPlatforms)
+	if s.helper.Config.MultiBinary {
+		router.Get(utils.JoinStrings(api.PlatformsPath, BinaryNameArgPath, ReleaseNameArgPath), validateParams, s.GetBinaryPlatforms)
+		router.Get(utils.JoinStrings(api.ChecksumPath, BinaryNameArgPath, ReleaseNameArgPath, OSArgPath, ArchArgPath), validateParams, s.GetBinaryChecksum)
+		router.Get(utils.JoinStrings(api.SignaturePath, BinaryNameArgPath, ReleaseNameArgPath, OSArgPath, ArchArgPath), validateParams, s.GetBinarySignature)
+	}
+}
+
+// releaseNameParam returns the canonical name of the release in the release_name route parameter,
+// which is matched case-insensitively, or the parameter itself if the release does not exist
+func (s *Server) releaseNameParam(ctx *fiber.Ctx) string {
+	releaseName := param(ctx, "release_name")
+	if canonicalName, ok := s.cache.CanonicalReleaseName(releaseName); ok {
+		return canonicalName
+	}
+	return releaseName
+}
+
+// defaultBinaryName returns the binary name used by the unprefixed routes, which
+// is always empty unless the server is running in multi-binary mode
+func (s *Server) defaultBinaryName() string {
+	if s.helper.Config.MultiBinary {
+		return strings.ToLower(s.helper.Config.Binary)
+	}
+	return ""
+}
+
+// GoImport returns the go-import and go-source meta tags for requests made by the go tool (?go-get=1),
+// allowing the configured domain to double as a vanity import path
+func (s *Server) GoImport(ctx *fiber.Ctx) error {
+	if ctx.Query(GoGet) != "1" {
+		return ctx.Next()
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextHTMLCharsetUTF8)
+	_, err := s.goImportTemplate.Execute(ctx.Response().BodyWriter(), map[string]interface{}{
+		"import_path":    html.EscapeString(s.helper.Config.GoImportPath),
+		"repository_url": html.EscapeString(fmt.Sprintf("https://github.com/%s/%s", s.helper.Config.RepositoryOwner, s.helper.Config.Repository)),
+	})
+	return err
+}
+
+// Options answers OPTIONS requests with the methods accepted for the requested path,
+// which is POST for the telemetry routes, additionally POST for the admin routes, and GET and HEAD otherwise
+func (s *Server) Options(ctx *fiber.Ctx) error {
+	allow := "GET, HEAD, OPTIONS"
+	switch {
+	case strings.HasPrefix(ctx.Path(), api.TelemetryPath+"/"):
+		allow = "POST, OPTIONS"
+	case strings.HasPrefix(ctx.Path(), api.AdminPath+"/"):
+		allow = "GET, HEAD, POST, OPTIONS"
+	}
+	ctx.Set(fiber.HeaderAllow, allow)
+	return ctx.SendStatus(fiber.StatusNoContent)
 }
 
 // GetPing is a simple health check endpoint that always returns 200
@@ -130,47 +418,113 @@ func (s *Server) GetPing(ctx *fiber.Ctx) error {
 }
 
 // GetLatestReleaseShellScript returns a shell script which will download the latest release of the binary
-// and install it on the system
+// and install it on the system, either directly or by redirecting to the script of the release
 func (s *Server) GetLatestReleaseShellScript(ctx *fiber.Ctx) error {
-	latestReleaseName := s.cache.GetLatestReleaseName()
+	latestReleaseName := s.latestReleaseName(ctx)
 	if len(latestReleaseName) == 0 {
 		return ctx.Status(fiber.StatusInternalServerError).SendString("no releases available")
 	}
 
-	return ctx.Redirect(fmt.Sprintf("/%s?%s=%s", latestReleaseName, Analytics, ctx.Query(Analytics, "true")), fiber.StatusFound)
+	if s.helper.Config.DirectLatestScript {
+		// the latest release changes and depends on the client, so the script must not be cached
+		ctx.Set(fiber.HeaderCacheControl, "no-cache")
+		return s.getReleaseShellScript(ctx, latestReleaseName, "")
+	}
+
+	location := url.URL{
+		Path:     "/" + latestReleaseName,
+		RawQuery: s.forwardedQuery(ctx),
+	}
+	return ctx.Redirect(location.String(), fiber.StatusFound)
 }
 
 // GetReleaseShellScript returns a shell script which will download the given release of the binary
 // and install it on the system
 func (s *Server) GetReleaseShellScript(ctx *fiber.Ctx) error {
-	releaseName := ctx.Params("release_name")
+	return s.getReleaseShellScript(ctx, s.releaseNameParam(ctx), "")
+}
 
+// GetBinaryReleaseShellScript returns a shell script which will download the given release of the given binary
+// and install it on the system
+func (s *Server) GetBinaryReleaseShellScript(ctx *fiber.Ctx) error {
+	return s.getReleaseShellScript(ctx, s.releaseNameParam(ctx), strings.ToLower(param(ctx, "binary_name")))
+}
+
+func (s *Server) getReleaseShellScript(ctx *fiber.Ctx, releaseName string, binaryName string) error {
 	if !s.cache.ReleaseNameExists(releaseName) {
 		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
 	}
 
-	if ctx.Query(Analytics) != "false" {
-		s.helper.Printer.Printf("Received GetReleaseShellScript from %s\n", ctx.IP())
-		analytics.Event(ctx.IP(), "release_shell", map[string]string{"release_name": releaseName})
+	if binaryName != "" && !s.cache.BinaryNameExists(binaryName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("binary not found")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetReleaseShellScript from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "release_shell", withBinaryName(binaryName, map[string]string{"release_name": releaseName}))
 	}
 
+	analytics := ctx.Query(api.Analytics, "true") != "false"
+	source := s.installSource(ctx)
+	key := scriptKey{releaseName: releaseName, binaryName: binaryName, analytics: analytics, source: source}
 	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
-	return ctx.SendString(s.template.ExecuteString(map[string]interface{}{
-		"domain":       s.helper.Config.Domain,
-		"release_name": releaseName,
-		"prefix":       s.prefix,
-		"binary":       s.helper.Config.Binary,
-		"analytics":    fmt.Sprintf("%t", ctx.Query(Analytics, "true") != "false"),
-	}))
+	scripts := s.renderedScripts()
+	if script, ok := scripts.load(key); ok {
+		return ctx.Send(script)
+	}
+
+	params := map[string]interface{}{
+		"domain":               shellQuote(s.helper.Config.Domain),
+		"release_name":         shellQuote(releaseName),
+		"prefix":               shellQuote(s.prefix),
+		"path_prefix":          shellQuote(""),
+		"binary":               shellQuote(s.helper.Config.GetInstallName("")),
+		"install_names":        s.installNames(),
+		"binaries":             shellQuote(strings.Join(s.helper.Config.GetBinaryNames(), " ")),
+		"binary_members":       s.binaryMembers(),
+		"binary_install_names": s.binaryInstallNames(),
+		"analytics":            strconv.FormatBool(analytics),
+		"source":               source,
+		"license_acceptance":   strconv.FormatBool(s.helper.Config.LicenseAcceptance && s.cache.HasReleaseLicense(releaseName)),
+		"cacert":               shellQuotePath(s.helper.Config.ScriptCACert),
+		"user_install":         shellQuotePath(s.helper.Config.UserInstallDirectory),
+		"hook_phases":          s.hookPhases(),
+		"download_attempts":    strconv.Itoa(s.helper.Config.ScriptDownloadAttempts),
+		"checksums":            strconv.FormatBool(s.cache.GetReleaseChecksums(releaseName) != nil),
+		"build_from_source":    shellQuote(s.buildFromSourceURL()),
+		"pre_install_hook":     s.helper.Config.Hooks.PreInstall,
+		"post_install_hook":    s.helper.Config.Hooks.PostInstall,
+	}
+
+	if binaryName != "" {
+		params["path_prefix"] = shellQuote(utils.JoinPaths(binaryName))
+		params["binary"] = shellQuote(binaryName)
+		params["install_names"] = ""
+		params["binaries"] = shellQuote("")
+		params["binary_members"] = ""
+		params["binary_install_names"] = ""
+	}
+
+	buf := getTemplateBuffer()
+	defer putTemplateBuffer(buf)
+	_, err := s.template.Execute(buf, params)
+	if err != nil {
+		return err
+	}
+
+	// the script is cached, so it must not share the pooled buffer
+	script := append([]byte(nil), buf.Bytes()...)
+	scripts.store(key, script)
+	return ctx.Send(script)
 }
 
 // GetLatestReleaseName returns the name of the latest release
 func (s *Server) GetLatestReleaseName(ctx *fiber.Ctx) error {
-	if ctx.Query(Analytics) != "false" {
-		s.helper.Printer.Printf("Received GetLatestReleaseName from %s\n", ctx.IP())
-		analytics.Event(ctx.IP(), "latest_release_name")
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetLatestReleaseName from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "latest_release_name")
 	}
-	latestReleaseName := s.cache.GetLatestReleaseName()
+	latestReleaseName := s.latestReleaseName(ctx)
 	if len(latestReleaseName) == 0 {
 		return ctx.Status(fiber.StatusInternalServerError).SendString("no releases available")
 	}
@@ -178,91 +532,501 @@ func (s *Server) GetLatestReleaseName(ctx *fiber.Ctx) error {
 	return ctx.SendString(latestReleaseName)
 }
 
-// ListReleaseNames returns a list of all available release names
+// ListReleaseNames returns a list of the available release names and when they were published,
+// newest version first unless ordering by publish date is requested, optionally filtered and paginated
 func (s *Server) ListReleaseNames(ctx *fiber.Ctx) error {
-	if ctx.Query(Analytics) != "false" {
-		s.helper.Printer.Printf("Received ListReleaseNames from %s\n", ctx.IP())
-		analytics.Event(ctx.IP(), "list_release_names")
+	releaseNames, total, err := s.queryReleaseNames(ctx)
+	if err != nil {
+		return apiError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received ListReleaseNames from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "list_release_names")
 	}
 	res := getListReleaseNamesResponse()
 	defer putListReleaseNamesResponse(res)
-	res.ReleaseNames = s.cache.GetAllReleaseNames()
+	res.ReleaseNames = releaseNames
+	res.Total = total
+	res.Releases = make([]*v1.ReleaseName, 0, len(releaseNames))
+	for _, releaseName := range releaseNames {
+		res.Releases = append(res.Releases, &v1.ReleaseName{
+			Name:        releaseName,
+			PublishedAt: unixTime(s.cache.GetReleasePublishedAt(releaseName)),
+		})
+	}
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
+	return ctx.JSON(res)
+}
+
+// queryReleaseNames returns the names of the releases matching the prefix and prerelease query parameters
+// in the requested order, limited to the requested page, and the number of matching releases across all
+// pages. Releases whose publish date is unknown are listed last when ordering by date.
+func (s *Server) queryReleaseNames(ctx *fiber.Ctx) ([]string, int, error) {
+	order := ctx.Query(api.Order, api.OrderVersion)
+	if order != api.OrderVersion && order != api.OrderDate {
+		return nil, 0, errors.New("invalid order, expected version or date")
+	}
+	prereleases, err := strconv.ParseBool(ctx.Query(api.Prerelease, "true"))
+	if err != nil {
+		return nil, 0, errors.New("invalid prerelease, expected true or false")
+	}
+	limit, err := strconv.Atoi(ctx.Query(api.Limit, "0"))
+	if err != nil || limit < 0 {
+		return nil, 0, errors.New("invalid limit, expected a positive number")
+	}
+	page, err := strconv.Atoi(ctx.Query(api.Page, "1"))
+	if err != nil || page < 1 {
+		return nil, 0, errors.New("invalid page, expected a number starting at 1")
+	}
+	prefix := strings.ToLower(ctx.Query(api.Prefix))
+
+	releaseNames := s.cache.GetAllReleaseNames()
+	matching := releaseNames[:0]
+	for _, releaseName := range releaseNames {
+		if !strings.HasPrefix(strings.ToLower(releaseName), prefix) || (!prereleases && utils.IsPrerelease(releaseName)) {
+			continue
+		}
+		matching = append(matching, releaseName)
+	}
+
+	if order == api.OrderDate {
+		sort.SliceStable(matching, func(i, j int) bool {
+			return s.cache.GetReleasePublishedAt(matching[i]).After(s.cache.GetReleasePublishedAt(matching[j]))
+		})
+	}
+
+	total := len(matching)
+	if limit > 0 {
+		start := (page - 1) * limit
+		if start > total || start < 0 {
+			start = total
+		}
+		end := start + limit
+		if end > total || end < start {
+			end = total
+		}
+		matching = matching[start:end]
+	}
+	return matching, total, nil
+}
+
+// unixTime returns the given time as seconds since the Unix epoch, or 0 if it is zero
+func unixTime(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// ListReleases returns metadata for the available releases, optionally filtered and paginated
+func (s *Server) ListReleases(ctx *fiber.Ctx) error {
+	releaseNames, total, err := s.queryReleaseNames(ctx)
+	if err != nil {
+		return apiError(ctx, fiber.StatusBadRequest, err.Error())
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received ListReleases from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "list_releases")
+	}
+	latestReleaseName := s.cache.GetLatestReleaseName()
+	binaryNames := s.helper.Config.GetBinaryNames()
+	if s.helper.Config.MultiBinary {
+		binaryNames = s.cache.GetAllBinaryNames()
+	}
+	res := getListReleasesResponse()
+	defer putListReleasesResponse(res)
+	res.Total = total
+	for _, releaseName := range releaseNames {
+		res.Releases = append(res.Releases, &v1.Release{
+			Name:        releaseName,
+			Title:       s.cache.GetReleaseTitle(releaseName),
+			Latest:      releaseName == latestReleaseName,
+			PublishedAt: unixTime(s.cache.GetReleasePublishedAt(releaseName)),
+			Binaries:    binaryNames,
+			Platforms:   platforms(s.cache.GetReleaseArtifacts(releaseName)),
+		})
+	}
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
+	return ctx.JSON(res)
+}
+
+// GetReleaseMetadata returns the platforms of the given release and the size, checksum, and download URL of
+// each of its artifacts, so that tools can check that a platform is supported before downloading
+func (s *Server) GetReleaseMetadata(ctx *fiber.Ctx) error {
+	releaseName := s.releaseNameParam(ctx)
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return apiError(ctx, fiber.StatusNotFound, "release not found")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetReleaseMetadata from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "release_metadata", map[string]string{"release_name": releaseName})
+	}
+
+	artifacts := s.cache.GetReleaseArtifacts(releaseName)
+	res := getReleaseMetadata()
+	defer putReleaseMetadata(res)
+	res.Name = releaseName
+	res.Title = s.cache.GetReleaseTitle(releaseName)
+	res.Latest = releaseName == s.cache.GetLatestReleaseName()
+	res.PublishedAt = unixTime(s.cache.GetReleasePublishedAt(releaseName))
+	res.Platforms = platforms(artifacts)
+	for _, artifact := range artifacts {
+		res.Artifacts = append(res.Artifacts, &v1.ReleaseArtifact{
+			Binary:   artifact.BinaryName,
+			OS:       artifact.OS,
+			Arch:     artifact.Arch,
+			Name:     artifact.Name,
+			Size:     artifact.Size,
+			Checksum: artifact.Checksum,
+			URL:      s.artifactURL(releaseName, artifact),
+		})
+	}
 	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
 	return ctx.JSON(res)
 }
 
 // GetChecksum returns the checksum for the given release name, os, and arch
 func (s *Server) GetChecksum(ctx *fiber.Ctx) error {
-	releaseName := ctx.Params("release_name")
-	os := ctx.Params("os")
-	arch := ctx.Params("arch")
+	return s.getChecksum(ctx, s.defaultBinaryName())
+}
+
+// GetBinaryChecksum returns the checksum for the given binary name, release name, os, and arch
+func (s *Server) GetBinaryChecksum(ctx *fiber.Ctx) error {
+	return s.getChecksum(ctx, strings.ToLower(param(ctx, "binary_name")))
+}
+
+func (s *Server) getChecksum(ctx *fiber.Ctx, binaryName string) error {
+	releaseName := s.releaseNameParam(ctx)
+	os := normalizeOS(param(ctx, "os"))
+	arch := normalizeArch(param(ctx, "arch"))
+
+	// the checksum is returned as JSON only if it is asked for explicitly, since clients accepting
+	// anything (e.g. curl) expect the bare checksum
+	ctx.Vary(fiber.HeaderAccept)
+	asJSON := strings.Contains(ctx.Get(fiber.HeaderAccept), fiber.MIMEApplicationJSON)
 
-	checksum := s.cache.GetChecksum(releaseName, os, arch)
+	checksum := s.cache.GetChecksum(binaryName, releaseName, os, arch)
 	if len(checksum) == 0 {
+		if asJSON {
+			return apiError(ctx, fiber.StatusNotFound, "checksum not found")
+		}
 		return ctx.Status(fiber.StatusNotFound).SendString("checksum not found")
 	}
 
-	if ctx.Query(Analytics) != "false" {
-		s.helper.Printer.Printf("Received GetChecksum from %s\n", ctx.IP())
-		analytics.Event(ctx.IP(), "checksum", map[string]string{
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetChecksum from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "checksum", withBinaryName(binaryName, map[string]string{
 			"release_name": releaseName,
 			"os":           os,
 			"arch":         arch,
-		})
+		}))
+	}
+
+	if asJSON {
+		res := &v1.ChecksumResponse{
+			Algorithm: v1.ChecksumAlgorithmSHA256,
+			Value:     checksum,
+		}
+		if artifact := s.cache.GetReleaseArtifact(binaryName, releaseName, os, arch); artifact != nil {
+			res.ArtifactName = artifact.Name
+			res.Size = artifact.Size
+		}
+		ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
+		return ctx.JSON(res)
 	}
 
 	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
 	return ctx.SendString(checksum)
 }
 
+// GetReleaseChecksums returns the checksums.txt of the given release, listing the checksums of all of its assets
+// in the format of sha256sum so that artifacts obtained elsewhere (e.g. from a mirror) can be verified
+func (s *Server) GetReleaseChecksums(ctx *fiber.Ctx) error {
+	releaseName := s.releaseNameParam(ctx)
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	checksums := s.cache.GetReleaseChecksums(releaseName)
+	if checksums == nil {
+		return ctx.Status(fiber.StatusNotFound).SendString("checksums not found")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetReleaseChecksums from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "checksums", map[string]string{"release_name": releaseName})
+	}
+
+	assetNames := make([]string, 0, len(checksums))
+	for assetName := range checksums {
+		assetNames = append(assetNames, assetName)
+	}
+	sort.Strings(assetNames)
+
+	var b strings.Builder
+	for _, assetName := range assetNames {
+		b.WriteString(checksums[assetName] + "  " + assetName + "\n")
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.SendString(b.String())
+}
+
 // GetReleaseArtifact returns the artifact for the given release name, os, and arch
 func (s *Server) GetReleaseArtifact(ctx *fiber.Ctx) error {
-	releaseName := strings.ToLower(ctx.Params("release_name"))
-	os := ctx.Params("os")
-	arch := ctx.Params("arch")
-
-	if s.cache.GetLatestReleaseName() == releaseName {
-               // checks for anything but "v" / numerics / ".",
-               regex, err := regexp.Compile(`^[^a-zA-Z]*[vV][^a-zA-Z]*$`)
-               if err != nil {
-                 return err
-               }
-               if !regex.MatchString(releaseName) {
-                 log.Logger.Error().Msg("Serving possible non-production builds")
-               }
-
-		artifactBytes := s.cache.GetLatestReleaseArtifact(os, arch)
-		if artifactBytes == nil {
-			return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	return s.getReleaseArtifact(ctx, s.defaultBinaryName())
+}
+
+// GetBinaryReleaseArtifact returns the artifact for the given binary name, release name, os, and arch
+func (s *Server) GetBinaryReleaseArtifact(ctx *fiber.Ctx) error {
+	return s.getReleaseArtifact(ctx, strings.ToLower(param(ctx, "binary_name")))
+}
+
+func (s *Server) getReleaseArtifact(ctx *fiber.Ctx, binaryName string) error {
+	releaseName := s.releaseNameParam(ctx)
+	os := normalizeOS(param(ctx, "os"))
+	arch := normalizeArch(param(ctx, "arch"))
+
+	// the artifacts of the latest release are either kept in memory or stored on disk, the cache checks that
+	// the release is still the latest release against the same snapshot it reads the artifact from
+	artifactBytes := s.cache.GetLatestReleaseArtifact(binaryName, releaseName, os, arch)
+	var artifactReader io.ReadCloser
+	var size int
+	if artifactBytes == nil {
+		var err error
+		artifactReader, size, err = s.cache.OpenLatestReleaseArtifact(binaryName, releaseName, os, arch)
+		if err != nil {
+			s.helper.Printer.Printf("error: unable to open stored artifact for release %s: %s\n", releaseName, err)
+			return ctx.Status(fiber.StatusInternalServerError).SendString("unable to open artifact")
+		}
+	}
+
+	if artifactBytes != nil || artifactReader != nil {
+		if !releaseNameRegex.MatchString(releaseName) {
+			log.Logger.Error().Msg("Serving possible non-production builds")
+		}
+
+		if ctx.Method() == fiber.MethodHead {
+			if artifactReader != nil {
+				_ = artifactReader.Close()
+			} else {
+				size = len(artifactBytes)
+			}
+			return s.headArtifact(ctx, releaseName, s.cache.GetReleaseArtifact(binaryName, releaseName, os, arch), size)
 		}
 
-		if ctx.Query(Analytics) != "false" {
-			s.helper.Printer.Printf("Received GetReleaseArtifact from %s\n", ctx.IP())
-			analytics.Event(ctx.IP(), "release_artifact", map[string]string{
-				"release_name": releaseName,
-				"os":           os,
-				"arch":         arch,
-			})
+		release, ok := s.acquireTransfer()
+		if !ok {
+			if artifactReader != nil {
+				_ = artifactReader.Close()
+			}
+			return s.transfersExhausted(ctx)
+		}
+		s.artifactEvent(ctx, binaryName, releaseName, os, arch)
+
+		if artifact := s.cache.GetReleaseArtifact(binaryName, releaseName, os, arch); artifact != nil {
+			setAttachment(ctx, artifact.Name)
+			s.setArtifactHeaders(ctx, releaseName, artifact)
 		}
 
 		ctx.Response().Header.SetContentType(fiber.MIMEOctetStream)
-		ctx.Response().SetBody(artifactBytes)
+		if artifactReader != nil {
+			s.sendArtifact(ctx, artifactReader, size, release)
+			return nil
+		}
+		s.sendArtifactBytes(ctx, artifactBytes, release)
 		return nil
 	}
 
-	artifactName := s.cache.GetReleaseArtifactName(releaseName, os, arch)
-	if artifactName == "" {
+	artifact := s.cache.GetReleaseArtifact(binaryName, releaseName, os, arch)
+	if artifact == nil {
 		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
 	}
 
-	if ctx.Query(Analytics) != "false" {
-		s.helper.Printer.Printf("Received GetReleaseArtifact from %s\n", ctx.IP())
-		analytics.Event(ctx.IP(), "release_artifact", map[string]string{
-			"release_name": releaseName,
-			"os":           os,
-			"arch":         arch,
-		})
+	if artifact.URL != "" {
+		if ctx.Method() != fiber.MethodHead {
+			s.artifactEvent(ctx, binaryName, releaseName, os, arch)
+		}
+		return ctx.Redirect(artifact.URL)
+	}
+
+	if ctx.Method() == fiber.MethodHead {
+		return s.headArtifact(ctx, releaseName, artifact, artifact.Size)
+	}
+
+	release, ok := s.acquireTransfer()
+	if !ok {
+		return s.transfersExhausted(ctx)
+	}
+	s.artifactEvent(ctx, binaryName, releaseName, os, arch)
+	return s.proxyArtifact(ctx, releaseName, artifact, release)
+}
+
+// headArtifact answers a HEAD request for the given artifact with the headers its download would have, without
+// taking a transfer slot, recording an install, or downloading the artifact from the provider, so that probes
+// of download managers and scanners do not compete with downloads
+func (s *Server) headArtifact(ctx *fiber.Ctx, releaseName string, artifact *cache.Artifact, size int) error {
+	if artifact != nil {
+		setAttachment(ctx, artifact.Name)
+		s.setArtifactHeaders(ctx, releaseName, artifact)
+	}
+	ctx.Response().Header.SetContentType(fiber.MIMEOctetStream)
+	ctx.Response().Header.SetContentLength(size)
+	return nil
+}
+
+// artifactEvent records the download of an artifact, it is only called once the download is served so
+// that requests rejected for lack of a transfer slot, and their retries, are not counted as installs
+func (s *Server) artifactEvent(ctx *fiber.Ctx, binaryName string, releaseName string, os string, arch string) {
+	if ctx.Query(api.Analytics) == "false" {
+		return
+	}
+	s.helper.Printer.Printf("Received GetReleaseArtifact from %s (request %s)\n", ctx.IP(), requestID(ctx))
+	s.event(ctx, "release_artifact", withBinaryName(binaryName, map[string]string{
+		"release_name": releaseName,
+		"os":           os,
+		"arch":         arch,
+	}))
+}
+
+// setAttachment sets the Content-Disposition header so that browsers and other clients
+// save the response under the given file name instead of the last path segment (e.g. amd64)
+func setAttachment(ctx *fiber.Ctx, fileName string) {
+	ctx.Set(fiber.HeaderContentDisposition, "attachment; filename="+strconv.Quote(fileName))
+}
+
+// setArtifactHeaders describes the given artifact of the given release in the headers of a response serving it,
+// so that download managers and scanners can validate it before and after the transfer. The digest is only set
+// if the artifact has a checksum, and Last-Modified falls back to when the release was published.
+func (s *Server) setArtifactHeaders(ctx *fiber.Ctx, releaseName string, artifact *cache.Artifact) {
+	if digest, err := hex.DecodeString(artifact.Checksum); err == nil && len(digest) == sha256.Size {
+		encoded := base64.StdEncoding.EncodeToString(digest)
+		ctx.Set(api.ReprDigestHeader, "sha-256=:"+encoded+":")
+		ctx.Set(api.DigestHeader, "sha-256="+encoded)
+	}
+
+	modified := artifact.UpdatedAt
+	if modified.IsZero() {
+		modified = s.cache.GetReleasePublishedAt(releaseName)
+	}
+	if !modified.IsZero() {
+		ctx.Set(fiber.HeaderLastModified, modified.UTC().Format(http.TimeFormat))
+	}
+}
+
+// proxyArtifact streams the given artifact of the given release from the provider, for artifacts that have
+// no public download URL
+func (s *Server) proxyArtifact(ctx *fiber.Ctx, releaseName string, artifact *cache.Artifact, release func()) error {
+	body, err := s.openUpstream(ctx, func(downloadCtx context.Context) (io.ReadCloser, error) {
+		return s.cache.DownloadArtifact(downloadCtx, artifact)
+	})
+	if err != nil {
+		release()
+		s.helper.Printer.Printf("error: unable to download artifact %s: %s\n", artifact.Name, err)
+		return s.upstreamError(ctx, err, "unable to download artifact")
 	}
 
-	return ctx.Redirect(fmt.Sprintf("https://github.com/%s/%s/releases/download/%s/%s", s.helper.Config.RepositoryOwner, s.helper.Config.Repository, releaseName, artifactName))
+	size := artifact.Size
+	if size == 0 {
+		size = -1
+	}
+
+	setAttachment(ctx, artifact.Name)
+	s.setArtifactHeaders(ctx, releaseName, artifact)
+	ctx.Response().Header.SetContentType(fiber.MIMEOctetStream)
+	s.sendArtifact(ctx, body, size, release)
+	return nil
+}
+
+// openUpstream starts a download from the provider for a proxied response with the given function. The download
+// is cancelled if the provider stalls for longer than the read timeout, when the server shuts down, and when
+// fasthttp closes the returned body once it has been sent or the client went away.
+func (s *Server) openUpstream(ctx *fiber.Ctx, download func(context.Context) (io.ReadCloser, error)) (*upstreamBody, error) {
+	downloadCtx, cancel := context.WithCancel(ctx.Context())
+	body := newUpstreamBody(s.helper.Config.DownloadReadTimeout, cancel)
+	reader, err := download(downloadCtx)
+	if err != nil {
+		body.stop()
+		return nil, err
+	}
+	body.reader = reader
+	return body, nil
+}
+
+// upstreamError responds to a failed download from the provider, passing its rate limit on to the client
+func (s *Server) upstreamError(ctx *fiber.Ctx, err error, message string) error {
+	var rateLimitErr *provider.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		return s.tooManyRequests(ctx, "upstream rate limit exceeded", rateLimitErr.RetryAfter)
+	}
+	return ctx.Status(fiber.StatusBadGateway).SendString(message)
+}
+
+// upstreamBody is the body of a proxied artifact response, it cancels the download from the provider
+// when it is closed or when a read does not complete within the timeout
+type upstreamBody struct {
+	reader  io.ReadCloser
+	timer   *time.Timer
+	timeout time.Duration
+	cancel  context.CancelFunc
+}
+
+// newUpstreamBody returns the body for a download that is cancelled with the given function, the timeout
+// already applies to the provider responding to the download. If the timeout is 0, reads are not limited.
+func newUpstreamBody(timeout time.Duration, cancel context.CancelFunc) *upstreamBody {
+	b := &upstreamBody{
+		timeout: timeout,
+		cancel:  cancel,
+	}
+	if timeout > 0 {
+		b.timer = time.AfterFunc(timeout, cancel)
+	}
+	return b
+}
+
+func (b *upstreamBody) Read(p []byte) (int, error) {
+	n, err := b.reader.Read(p)
+	if b.timer != nil {
+		b.timer.Reset(b.timeout)
+	}
+	return n, err
+}
+
+func (b *upstreamBody) Close() error {
+	err := b.reader.Close()
+	b.stop()
+	return err
+}
+
+// stop cancels the download and its timeout
+func (b *upstreamBody) stop() {
+	if b.timer != nil {
+		b.timer.Stop()
+	}
+	b.cancel()
+}
+
+// artifactURL returns the download URL for the given release artifact, which is the
+// provider's public URL if it has one, and otherwise the URL of this server
+func (s *Server) artifactURL(releaseName string, artifact *cache.Artifact) string {
+	if artifact.URL != "" {
+		return artifact.URL
+	}
+
+	pathPrefix := ""
+	if artifact.BinaryName != "" && artifact.BinaryName != s.defaultBinaryName() {
+		pathPrefix = utils.JoinPaths(artifact.BinaryName)
+	}
+	return fmt.Sprintf("%s://%s%s/%s/%s/%s", s.prefix, s.helper.Config.Domain, pathPrefix, releaseName, artifact.OS, artifact.Arch)
+}
+
+// withBinaryName adds the binary name to the given analytics properties if it is not empty
+func withBinaryName(binaryName string, properties map[string]string) map[string]string {
+	if binaryName != "" {
+		properties["binary_name"] = binaryName
+	}
+	return properties
 }
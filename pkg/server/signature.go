@@ -0,0 +1,93 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"context"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"io"
+	"strings"
+	"time"
+)
+
+// signatureSuffixes are the suffixes of the assets the signatures of an artifact are attached as, by format
+var signatureSuffixes = map[string]string{
+	api.SignatureFormatMinisign: ".minisig",
+	api.SignatureFormatCosign:   ".sig",
+}
+
+// GetSignature returns the signature of the artifact for the given release name, os, and arch
+func (s *Server) GetSignature(ctx *fiber.Ctx) error {
+	return s.getSignature(ctx, s.defaultBinaryName())
+}
+
+// GetBinarySignature returns the signature of the artifact for the given binary name, release name, os, and arch
+func (s *Server) GetBinarySignature(ctx *fiber.Ctx) error {
+	return s.getSignature(ctx, strings.ToLower(param(ctx, "binary_name")))
+}
+
+func (s *Server) getSignature(ctx *fiber.Ctx, binaryName string) error {
+	releaseName := s.releaseNameParam(ctx)
+	os := normalizeOS(param(ctx, "os"))
+	arch := normalizeArch(param(ctx, "arch"))
+
+	format := ctx.Query(api.SignatureFormat, api.SignatureFormatMinisign)
+	suffix, ok := signatureSuffixes[format]
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).SendString("format must be minisign or cosign")
+	}
+
+	artifactName := s.cache.GetReleaseArtifactName(binaryName, releaseName, os, arch)
+	if artifactName == "" {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	asset := s.cache.GetReleaseAsset(releaseName, artifactName+suffix)
+	if asset == nil {
+		return ctx.Status(fiber.StatusNotFound).SendString("signature not found")
+	}
+
+	// signatures are downloaded while updating the cache, only those that could not be are downloaded here
+	signature := s.cache.GetReleaseSignature(releaseName, asset.Name)
+	if signature == nil {
+		deadline, cancel := context.WithTimeout(context.Background(), time.Second*30)
+		defer cancel()
+		signatureReader, err := s.cache.DownloadAsset(deadline, asset)
+		if err == nil {
+			signature, err = io.ReadAll(signatureReader)
+			_ = signatureReader.Close()
+		}
+		if err != nil {
+			s.helper.Printer.Printf("error: unable to download signature %s for release %s: %s\n", asset.Name, releaseName, err)
+			return s.upstreamError(ctx, err, "unable to download signature")
+		}
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetSignature from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "signature", withBinaryName(binaryName, map[string]string{
+			"release_name": releaseName,
+			"os":           os,
+			"arch":         arch,
+			"format":       format,
+		}))
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.Send(signature)
+}
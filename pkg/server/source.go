@@ -0,0 +1,38 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"strings"
+)
+
+// installSource returns the installation source reported by the given request,
+// or an empty string if none or one that is not configured was reported
+func (s *Server) installSource(ctx *fiber.Ctx) string {
+	source := strings.ToLower(ctx.Query(api.Source))
+	if source == "" {
+		return ""
+	}
+	for _, allowed := range s.helper.Config.InstallSources {
+		if strings.ToLower(allowed) == source {
+			return source
+		}
+	}
+	return ""
+}
@@ -0,0 +1,230 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/analytics/bolt"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Period is the query parameter selecting how far back the stats summary goes (e.g. 30d or 12w)
+	Period = "period"
+
+	// Interval is the query parameter selecting the interval installs are grouped by (day, week, or month)
+	Interval = "interval"
+
+	// DefaultPeriod is the period of the stats summary if none is given
+	DefaultPeriod = "30d"
+
+	// maxPeriod bounds the period of the stats summary to keep summaries cheap to compute
+	maxPeriod = time.Hour * 24 * 366
+
+	// statsSummaryTTL is how long a stats summary is served before it is computed again,
+	// since computing one scans the analytics database
+	statsSummaryTTL = time.Minute * 5
+)
+
+const (
+	IntervalDay   = "day"
+	IntervalWeek  = "week"
+	IntervalMonth = "month"
+)
+
+// StatsStore aggregates the analytics events stored locally for the stats routes
+type StatsStore interface {
+	Rollups(name string, since time.Time, truncate func(time.Time) time.Time) ([]*bolt.Rollup, error)
+}
+
+// statsSummaries caches the stats summaries by period and interval, only the valid periods and
+// intervals are cached so the number of summaries is bounded
+type statsSummaries struct {
+	mu        sync.Mutex
+	summaries map[string]*statsSummary
+}
+
+// statsSummary is a stats summary that is computed once and shared until it expires
+type statsSummary struct {
+	expires time.Time
+
+	// done is closed once the summary has been computed
+	done chan struct{}
+	res  *v1.StatsSummaryResponse
+	err  error
+}
+
+// get returns the cached summary for the given key, computing it with compute if it is not cached or
+// has expired. Concurrent requests for the same summary share a single computation, and failed
+// computations are not cached.
+func (c *statsSummaries) get(key string, compute func() (*v1.StatsSummaryResponse, error)) (*v1.StatsSummaryResponse, error) {
+	now := time.Now()
+	c.mu.Lock()
+	summary, ok := c.summaries[key]
+	if ok && now.Before(summary.expires) {
+		c.mu.Unlock()
+		<-summary.done
+		return summary.res, summary.err
+	}
+	for k, expired := range c.summaries {
+		if !now.Before(expired.expires) {
+			delete(c.summaries, k)
+		}
+	}
+	summary = &statsSummary{
+		expires: now.Add(statsSummaryTTL),
+		done:    make(chan struct{}),
+	}
+	c.summaries[key] = summary
+	c.mu.Unlock()
+
+	summary.res, summary.err = compute()
+	close(summary.done)
+	if summary.err != nil {
+		c.mu.Lock()
+		if c.summaries[key] == summary {
+			delete(c.summaries, key)
+		}
+		c.mu.Unlock()
+	}
+	return summary.res, summary.err
+}
+
+// SetStatsStore sets the store the stats routes are served from, without one they return 404
+func (s *Server) SetStatsStore(store StatsStore) *Server {
+	s.stats = store
+	s.statsSummaries = &statsSummaries{
+		summaries: make(map[string]*statsSummary),
+	}
+	return s
+}
+
+// GetStatsSummary returns the number of installs per version, platform, and source over the
+// requested period, grouped by day, week, or month. Summaries are cached for statsSummaryTTL.
+func (s *Server) GetStatsSummary(ctx *fiber.Ctx) error {
+	if s.stats == nil {
+		return ctx.Status(fiber.StatusNotFound).SendString("stats are not enabled")
+	}
+
+	period := ctx.Query(Period, DefaultPeriod)
+	duration, ok := parsePeriod(period)
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).SendString("invalid period")
+	}
+
+	interval := ctx.Query(Interval, defaultInterval(duration))
+	truncate, ok := intervalTruncate(interval)
+	if !ok {
+		return ctx.Status(fiber.StatusBadRequest).SendString("invalid interval")
+	}
+
+	res, err := s.statsSummaries.get(period+"/"+interval, func() (*v1.StatsSummaryResponse, error) {
+		since := truncate(time.Now().UTC().Add(-duration))
+		rollups, err := s.stats.Rollups("release_artifact", since, truncate)
+		if err != nil {
+			return nil, err
+		}
+
+		res := &v1.StatsSummaryResponse{
+			Period:   period,
+			Interval: interval,
+			Since:    since.Format(time.DateOnly),
+			Installs: make([]*v1.StatsRollupResponse, 0, len(rollups)),
+		}
+		for _, rollup := range rollups {
+			res.Installs = append(res.Installs, &v1.StatsRollupResponse{
+				Start:    rollup.Start.Format(time.DateOnly),
+				Version:  rollup.Version,
+				Platform: rollup.Platform,
+				Source:   rollup.Source,
+				Installs: rollup.Count,
+			})
+		}
+		return res, nil
+	})
+	if err != nil {
+		s.helper.Printer.Printf("error: unable to summarize stats: %s\n", err)
+		return ctx.Status(fiber.StatusInternalServerError).SendString("unable to summarize stats")
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age="+strconv.Itoa(int(statsSummaryTTL.Seconds())))
+	return ctx.JSON(res)
+}
+
+// parsePeriod parses a period given in days (e.g. 30d) or weeks (e.g. 12w)
+func parsePeriod(period string) (time.Duration, bool) {
+	if len(period) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(period[:len(period)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+
+	var duration time.Duration
+	switch strings.ToLower(period[len(period)-1:]) {
+	case "d":
+		duration = time.Hour * 24 * time.Duration(n)
+	case "w":
+		duration = time.Hour * 24 * 7 * time.Duration(n)
+	default:
+		return 0, false
+	}
+	return duration, duration <= maxPeriod
+}
+
+// defaultInterval returns the interval installs are grouped by if none is given,
+// which keeps the number of points in the summary reasonable for a graph
+func defaultInterval(period time.Duration) string {
+	switch {
+	case period <= time.Hour*24*31:
+		return IntervalDay
+	case period <= time.Hour*24*182:
+		return IntervalWeek
+	default:
+		return IntervalMonth
+	}
+}
+
+// intervalTruncate returns the function truncating a time to the start of its interval
+func intervalTruncate(interval string) (func(time.Time) time.Time, bool) {
+	switch interval {
+	case IntervalDay:
+		return func(t time.Time) time.Time {
+			t = t.UTC()
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		}, true
+	case IntervalWeek:
+		return func(t time.Time) time.Time {
+			t = t.UTC()
+			// weeks start on monday
+			offset := (int(t.Weekday()) + 6) % 7
+			return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, time.UTC)
+		}, true
+	case IntervalMonth:
+		return func(t time.Time) time.Time {
+			t = t.UTC()
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,186 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"errors"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/internal/audit"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+	"github.com/loopholelabs/releaser/pkg/cache"
+	"github.com/loopholelabs/releaser/version"
+	"time"
+)
+
+// GetStatus returns the release being served as the latest release, any overrides (e.g. a pinned
+// release or maintenance mode) that are currently in effect, and the freshness of the cache. Unlike
+// /ping it is meant for uptime checkers and status pages, and responds with a 503 until the cache
+// has been updated once. Status pages may fetch it from any origin.
+func (s *Server) GetStatus(ctx *fiber.Ctx) error {
+	res := s.statusResponse()
+	if res.Status == v1.StatusDown {
+		ctx.Status(fiber.StatusServiceUnavailable)
+	}
+	ctx.Set(fiber.HeaderCacheControl, "no-cache")
+	ctx.Set(fiber.HeaderAccessControlAllowOrigin, "*")
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
+	return ctx.JSON(res)
+}
+
+// statusResponse returns the release being served as the latest release, the overrides in effect,
+// and the state of the cache
+func (s *Server) statusResponse() *v1.StatusResponse {
+	status := s.cache.GetStatus()
+	latestReleaseName := s.cache.GetLatestReleaseName()
+	releaseNames := s.cache.GetAllReleaseNames()
+	res := &v1.StatusResponse{
+		Status:                    v1.StatusOK,
+		LatestReleaseName:         latestReleaseName,
+		UpstreamLatestReleaseName: s.cache.GetUpstreamLatestReleaseName(),
+		PinnedReleaseName:         s.cache.GetPinnedReleaseName(),
+		Maintenance:               s.maintenance.Load(),
+		Stale:                     status.Stale,
+		Provider:                  s.provider.Name(),
+		ProviderReachable:         status.Ready && status.LastError == nil,
+		Releases:                  len(releaseNames),
+		LatestReleaseArtifacts:    len(s.cache.GetReleaseArtifacts(latestReleaseName)),
+	}
+	for _, releaseName := range releaseNames {
+		res.Artifacts += len(s.cache.GetReleaseArtifacts(releaseName))
+	}
+	if rollout := s.cache.GetRollout(); rollout.PreviousReleaseName != "" {
+		res.Rollout = &v1.RolloutResponse{
+			PreviousReleaseName: rollout.PreviousReleaseName,
+			Percentage:          rollout.Percentage,
+		}
+	}
+	switch {
+	case !status.Ready:
+		res.Status = v1.StatusDown
+	case status.Stale || status.LastError != nil:
+		res.Status = v1.StatusDegraded
+	}
+	if status.Ready {
+		res.LastUpdate = status.LastUpdate.Unix()
+		res.CacheAge = int64(time.Since(status.LastUpdate).Seconds())
+	}
+	return res
+}
+
+// PinRelease pins the given release as the latest release (?release_name=v1.2.3),
+// an empty release name removes the pin
+func (s *Server) PinRelease(ctx *fiber.Ctx) error {
+	releaseName := ctx.Query("release_name")
+	before := s.cache.GetPinnedReleaseName()
+	err := s.cache.PinRelease(releaseName)
+	if err != nil {
+		if errors.Is(err, cache.ErrReleaseNotFound) {
+			return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+		}
+		s.helper.Printer.Printf("error: unable to update cache after pinning release %s: %s\n", releaseName, err)
+		return ctx.Status(fiber.StatusBadGateway).SendString("unable to update cache")
+	}
+	s.record(ctx, &audit.Entry{
+		Action: "admin.pin",
+		Before: before,
+		After:  s.cache.GetPinnedReleaseName(),
+	})
+	s.helper.Printer.Printf("Pinned release set to '%s' from %s (request %s)\n", releaseName, ctx.IP(), requestID(ctx))
+	return s.GetStatus(ctx)
+}
+
+// RefreshCache updates the cache immediately instead of waiting for the next periodic update
+func (s *Server) RefreshCache(ctx *fiber.Ctx) error {
+	err := s.cache.Refresh()
+	if err != nil {
+		s.helper.Printer.Printf("error: unable to refresh cache (request %s): %s\n", requestID(ctx), err)
+		return ctx.Status(fiber.StatusBadGateway).SendString("unable to update cache")
+	}
+	s.record(ctx, &audit.Entry{
+		Action: "admin.refresh",
+	})
+	s.helper.Printer.Printf("Cache refreshed from %s (request %s)\n", ctx.IP(), requestID(ctx))
+	return s.GetStatus(ctx)
+}
+
+// EvictLatestRelease discards the cached artifacts of the latest release and downloads them again
+func (s *Server) EvictLatestRelease(ctx *fiber.Ctx) error {
+	latestReleaseName := s.cache.GetLatestReleaseName()
+	err := s.cache.EvictLatestRelease()
+	if err != nil {
+		s.helper.Printer.Printf("error: unable to update cache after evicting release %s (request %s): %s\n", latestReleaseName, requestID(ctx), err)
+		return ctx.Status(fiber.StatusBadGateway).SendString("unable to update cache")
+	}
+	s.record(ctx, &audit.Entry{
+		Action: "admin.evict",
+		Target: latestReleaseName,
+	})
+	s.helper.Printer.Printf("Evicted cached artifacts of release %s from %s (request %s)\n", latestReleaseName, ctx.IP(), requestID(ctx))
+	return s.GetStatus(ctx)
+}
+
+// CompleteRollout serves the latest release to all clients immediately, ending the rollout in progress
+func (s *Server) CompleteRollout(ctx *fiber.Ctx) error {
+	before := s.cache.GetRollout()
+	if !s.cache.CompleteRollout() {
+		return ctx.Status(fiber.StatusConflict).SendString("no rollout is in progress")
+	}
+	s.record(ctx, &audit.Entry{
+		Action: "admin.rollout",
+		Target: s.cache.GetLatestReleaseName(),
+		Before: before.Percentage,
+		After:  100,
+	})
+	s.helper.Printer.Printf("Rollout of release %s completed from %s (request %s)\n", s.cache.GetLatestReleaseName(), ctx.IP(), requestID(ctx))
+	return s.GetStatus(ctx)
+}
+
+// GetServerInfo returns the version and configuration of this server and the state of its cache,
+// which helps debugging mismatched deployments across environments
+func (s *Server) GetServerInfo(ctx *fiber.Ctx) error {
+	res := &v1.ServerInfoResponse{
+		Version:   version.Version,
+		GitCommit: version.GitCommit,
+		GoVersion: version.GoVersion,
+		Platform:  version.Platform,
+		BuildDate: version.BuildDate,
+		Uptime:    int64(time.Since(s.startTime).Seconds()),
+		Providers: s.helper.Config.GetProviders(),
+		Cache:     s.cacheInfo(),
+	}
+	if s.helper.Config.RepositoryOwner != "" && s.helper.Config.Repository != "" {
+		res.Repository = s.helper.Config.RepositoryOwner + "/" + s.helper.Config.Repository
+	}
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
+	return ctx.JSON(res)
+}
+
+// cacheInfo returns the freshness of the cache and the number of releases and integrity issues in it
+func (s *Server) cacheInfo() *v1.CacheInfoResponse {
+	status := s.cache.GetStatus()
+	res := &v1.CacheInfoResponse{
+		Releases:          len(s.cache.GetAllReleaseNames()),
+		LatestReleaseName: s.cache.GetLatestReleaseName(),
+		Stale:             status.Stale,
+		Failures:          status.Failures,
+		IntegrityIssues:   len(s.cache.GetIntegrityIssues()),
+	}
+	if status.Ready {
+		res.LastUpdate = status.LastUpdate.Unix()
+	}
+	return res
+}
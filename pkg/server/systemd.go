@@ -0,0 +1,70 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"path"
+	"strings"
+)
+
+const (
+	// InstallDirectory is the query parameter the install script uses to pass the
+	// directory it installed the binary to, which defaults to DefaultInstallDirectory
+	InstallDirectory        = "path"
+	DefaultInstallDirectory = "/usr/local/bin"
+)
+
+// GetSystemdUnit returns a systemd unit that runs the binary of the given release
+// from the directory given with ?path= for daemon-style binaries
+func (s *Server) GetSystemdUnit(ctx *fiber.Ctx) error {
+	releaseName := s.releaseNameParam(ctx)
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	installDirectory := ctx.Query(InstallDirectory, DefaultInstallDirectory)
+	if !path.IsAbs(installDirectory) || strings.ContainsAny(installDirectory, "\n\r") {
+		return ctx.Status(fiber.StatusBadRequest).SendString("install directory must be an absolute path")
+	}
+
+	binary := s.helper.Config.GetInstallName("linux")
+	execStart := path.Join(installDirectory, binary)
+	if s.helper.Config.Systemd.Args != "" {
+		execStart = fmt.Sprintf("%s %s", execStart, s.helper.Config.Systemd.Args)
+	}
+
+	description := s.helper.Config.Systemd.Description
+	if description == "" {
+		description = binary
+	}
+
+	user := ""
+	if s.helper.Config.Systemd.User != "" {
+		user = fmt.Sprintf("User=%s\n", s.helper.Config.Systemd.User)
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	_, err := s.systemdTemplate.Execute(ctx.Response().BodyWriter(), map[string]interface{}{
+		"description":   fmt.Sprintf("%s %s", description, releaseName),
+		"documentation": fmt.Sprintf("%s://%s/%s", s.prefix, s.helper.Config.Domain, releaseName),
+		"exec_start":    execStart,
+		"user":          user,
+	})
+	return err
+}
@@ -0,0 +1,88 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"strings"
+)
+
+// installStages are the stages of an installation the install script reports results for
+var installStages = map[string]struct{}{
+	"setup":        {},
+	"license":      {},
+	"download":     {},
+	"pre-install":  {},
+	"install":      {},
+	"post-install": {},
+	"systemd":      {},
+	"complete":     {},
+}
+
+// PostInstallResult records whether an installation started by the install script succeeded,
+// which lets maintainers distinguish downloaded scripts from completed installations.
+//
+// The install script only reports results when analytics are enabled.
+func (s *Server) PostInstallResult(ctx *fiber.Ctx) error {
+	req := new(InstallResultRequest)
+	if err := ctx.BodyParser(req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).SendString("invalid install result")
+	}
+
+	releaseName, ok := s.cache.CanonicalReleaseName(req.ReleaseName)
+	if !ok {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	binaryName := strings.ToLower(req.Binary)
+	if !s.knownInstallName(binaryName) {
+		return ctx.Status(fiber.StatusBadRequest).SendString("unknown binary")
+	}
+
+	stage := strings.ToLower(req.Stage)
+	if _, ok := installStages[stage]; !ok {
+		return ctx.Status(fiber.StatusBadRequest).SendString("unknown stage")
+	}
+
+	s.helper.Printer.Printf("Received PostInstallResult from %s (request %s)\n", ctx.IP(), requestID(ctx))
+	s.event(ctx, "install_result", map[string]string{
+		"release_name": releaseName,
+		"binary_name":  binaryName,
+		"os":           normalizeOS(req.OS),
+		"arch":         normalizeArch(req.Arch),
+		"success":      fmt.Sprintf("%t", req.Success),
+		"stage":        stage,
+	})
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// knownInstallName returns true if the install script may report the given name as the binary it
+// installed, which is either an install name from the config or a binary served in multi-binary mode
+func (s *Server) knownInstallName(name string) bool {
+	c := s.helper.Config
+	if strings.EqualFold(name, c.GetInstallName("")) || s.cache.BinaryNameExists(name) {
+		return true
+	}
+	for os := range c.InstallNames {
+		if strings.EqualFold(name, c.GetInstallName(os)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// transferSlots limits the number of artifact responses that are sent at the same time, requests
+// for artifacts wait in a bounded queue for a slot to free up and are rejected once it is full
+type transferSlots struct {
+	slots chan struct{}
+
+	queued       atomic.Int64
+	queueSize    int64
+	queueTimeout time.Duration
+}
+
+// newTransferSlots returns the given number of transfer slots, or nil if transfers are unlimited
+func newTransferSlots(limit int, queueSize int, queueTimeout time.Duration) *transferSlots {
+	if limit <= 0 {
+		return nil
+	}
+	return &transferSlots{
+		slots:        make(chan struct{}, limit),
+		queueSize:    int64(queueSize),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// acquire takes a free slot, waiting in the queue for up to the queue timeout if there is none.
+// It returns false if the queue is full or no slot freed up in time.
+func (t *transferSlots) acquire() bool {
+	select {
+	case t.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if t.queued.Add(1) > t.queueSize {
+		t.queued.Add(-1)
+		return false
+	}
+	defer t.queued.Add(-1)
+
+	timer := time.NewTimer(t.queueTimeout)
+	defer timer.Stop()
+	select {
+	case t.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+// release frees a slot taken by acquire
+func (t *transferSlots) release() {
+	<-t.slots
+}
+
+// transferBody releases the transfer slot of an artifact response once fasthttp closes its body,
+// which happens after it has been sent or when the response is discarded
+type transferBody struct {
+	reader  io.Reader
+	release func()
+	once    sync.Once
+}
+
+func (b *transferBody) Read(p []byte) (int, error) {
+	return b.reader.Read(p)
+}
+
+// WriteTo copies the body to the connection, which fasthttp prefers over Read. Since io.Copy hands
+// an *os.File to the ReadFrom of the connection, stored artifacts are still sent with sendfile.
+func (b *transferBody) WriteTo(w io.Writer) (int64, error) {
+	return io.Copy(w, b.reader)
+}
+
+func (b *transferBody) Close() error {
+	var err error
+	if closer, ok := b.reader.(io.Closer); ok {
+		err = closer.Close()
+	}
+	b.once.Do(b.release)
+	return err
+}
+
+// acquireTransfer takes a transfer slot for an artifact response, returning the function that releases
+// it. It returns false if every slot is taken and the request could not be queued for one.
+func (s *Server) acquireTransfer() (func(), bool) {
+	if s.transfers == nil {
+		return func() {}, true
+	}
+	if !s.transfers.acquire() {
+		return nil, false
+	}
+	return s.transfers.release, true
+}
+
+// transfersExhausted responds with a 503 asking the client to retry once transfer slots have freed up
+func (s *Server) transfersExhausted(ctx *fiber.Ctx) error {
+	s.helper.Printer.Printf("Rejected artifact request from %s, all transfer slots are taken (request %s)\n", ctx.IP(), requestID(ctx))
+	return s.retryLater(ctx, fiber.StatusServiceUnavailable, "too many concurrent downloads", s.helper.Config.TransferQueueTimeout)
+}
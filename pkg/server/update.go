@@ -0,0 +1,59 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/api/v1"
+)
+
+// GetUpdateInfo returns the latest release for the client and whether it differs from
+// the release the client reported it is currently running
+func (s *Server) GetUpdateInfo(ctx *fiber.Ctx) error {
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetUpdateInfo from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "update_info")
+	}
+
+	latestReleaseName := s.latestReleaseName(ctx)
+	if len(latestReleaseName) == 0 {
+		return apiError(ctx, fiber.StatusInternalServerError, "no releases available")
+	}
+
+	currentReleaseName := ctx.Query(api.Current)
+	res := getUpdateInfo()
+	defer putUpdateInfo(res)
+	res.LatestReleaseName = latestReleaseName
+	res.CurrentReleaseName = currentReleaseName
+	res.UpdateAvailable = currentReleaseName != latestReleaseName
+	res.Cohort = ctx.GetRespHeader(api.CohortHeader)
+	if ctx.GetRespHeader(api.RolloutPreviousReleaseHeader) != "" {
+		rollout := s.cache.GetRollout()
+		res.RolloutPercentage = rollout.Percentage
+		res.PreviousReleaseName = rollout.PreviousReleaseName
+	}
+	ctx.Response().Header.SetContentType(fiber.MIMEApplicationJSONCharsetUTF8)
+	return ctx.JSON(res)
+}
+
+// apiError responds with the given status code and the error envelope of the JSON routes
+func apiError(ctx *fiber.Ctx, status int, message string) error {
+	return ctx.Status(status).JSON(&v1.ErrorResponse{
+		Error: message,
+	})
+}
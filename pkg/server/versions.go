@@ -0,0 +1,78 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/internal/utils"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"sort"
+	"strings"
+)
+
+// ListVersions returns all available versions as a newline separated plain text list sorted
+// in ascending order, without the "v" prefix, as expected by asdf and mise plugins
+func (s *Server) ListVersions(ctx *fiber.Ctx) error {
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received ListVersions from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "list_versions")
+	}
+
+	releaseNames := s.cache.GetAllReleaseNames()
+	sort.Slice(releaseNames, func(i, j int) bool {
+		return utils.CompareVersions(releaseNames[i], releaseNames[j]) < 0
+	})
+
+	var b strings.Builder
+	for _, releaseName := range releaseNames {
+		b.WriteString(strings.TrimPrefix(releaseName, "v"))
+		b.WriteString("\n")
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.SendString(b.String())
+}
+
+// GetDownloadURL returns the download URL of the artifact for the given version, os, and arch
+// as plain text, the version may be given with or without the "v" prefix
+func (s *Server) GetDownloadURL(ctx *fiber.Ctx) error {
+	version := param(ctx, "version")
+	os := normalizeOS(param(ctx, "os"))
+	arch := normalizeArch(param(ctx, "arch"))
+
+	releaseName, ok := s.cache.CanonicalReleaseName(version)
+	if !ok {
+		releaseName, _ = s.cache.CanonicalReleaseName("v" + version)
+	}
+
+	artifact := s.cache.GetReleaseArtifact(s.defaultBinaryName(), releaseName, os, arch)
+	if artifact == nil {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetDownloadURL from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "download_url", map[string]string{
+			"release_name": releaseName,
+			"os":           os,
+			"arch":         arch,
+		})
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.SendString(s.artifactURL(releaseName, artifact))
+}
@@ -0,0 +1,91 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"fmt"
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"strings"
+)
+
+// GetWhy returns a human-readable explanation of why installing the given release
+// on the given os and arch may have failed, derived from the state of the cache
+func (s *Server) GetWhy(ctx *fiber.Ctx) error {
+	return s.getWhy(ctx, s.defaultBinaryName())
+}
+
+// GetBinaryWhy returns a human-readable explanation of why installing the given binary
+// of the given release on the given os and arch may have failed
+func (s *Server) GetBinaryWhy(ctx *fiber.Ctx) error {
+	return s.getWhy(ctx, strings.ToLower(param(ctx, "binary_name")))
+}
+
+func (s *Server) getWhy(ctx *fiber.Ctx, binaryName string) error {
+	releaseName := s.releaseNameParam(ctx)
+	os := normalizeOS(param(ctx, "os"))
+	arch := normalizeArch(param(ctx, "arch"))
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetWhy from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "why", withBinaryName(binaryName, map[string]string{
+			"release_name": releaseName,
+			"os":           os,
+			"arch":         arch,
+		}))
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.SendString(s.explain(binaryName, releaseName, os, arch) + "\n")
+}
+
+// explain returns the explanation served by GetWhy
+func (s *Server) explain(binaryName string, releaseName string, os string, arch string) string {
+	if !s.cache.ReleaseNameExists(releaseName) {
+		explanation := fmt.Sprintf("Release %s does not exist, it was either never published or it has been removed (yanked).", releaseName)
+		if latestReleaseName := s.cache.GetLatestReleaseName(); latestReleaseName != "" {
+			explanation += fmt.Sprintf(" The latest release is %s.", latestReleaseName)
+		}
+		return explanation
+	}
+
+	if binaryName != "" && !s.cache.BinaryNameExists(binaryName) {
+		return fmt.Sprintf("Binary %s does not exist, the available binaries are: %s.", binaryName, strings.Join(s.cache.GetAllBinaryNames(), ", "))
+	}
+
+	artifact := s.cache.GetReleaseArtifact(binaryName, releaseName, os, arch)
+	if artifact == nil {
+		available := s.releasePlatforms(binaryName, releaseName)
+		if len(available) == 0 {
+			return fmt.Sprintf("Release %s has no artifacts, it may still be uploading or its assets do not follow the name_version_os_arch.tar.gz naming scheme.", releaseName)
+		}
+		explanation := fmt.Sprintf("Release %s was not built for %s/%s, it is available for: %s.", releaseName, os, arch, strings.Join(available, ", "))
+		if url := s.buildFromSourceURL(); url != "" {
+			explanation += fmt.Sprintf(" To build it from source, see %s.", url)
+		}
+		return explanation
+	}
+
+	if artifact.Checksum == "" {
+		if s.cache.GetReleaseAsset(releaseName, "checksums.txt") == nil {
+			return fmt.Sprintf("Release %s has an artifact for %s/%s (%s) but no checksums.txt, so it can not be verified.", releaseName, os, arch, artifact.Name)
+		}
+		return fmt.Sprintf("Release %s has an artifact for %s/%s (%s) but its checksums.txt has no valid entry for it, the checksums file may be malformed.", releaseName, os, arch, artifact.Name)
+	}
+
+	return fmt.Sprintf("Release %s has an artifact for %s/%s (%s) with sha256 checksum %s, so the failure is likely on the installing machine (e.g. network, permissions, or disk space).", releaseName, os, arch, artifact.Name, artifact.Checksum)
+}
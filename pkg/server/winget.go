@@ -0,0 +1,178 @@
+/*
+	Copyright 2023 Loophole Labs
+
+	Licensed under the Apache License, Version 2.0 (the "License");
+	you may not use this file except in compliance with the License.
+	You may obtain a copy of the License at
+
+		   http://www.apache.org/licenses/LICENSE-2.0
+
+	Unless required by applicable law or agreed to in writing, software
+	distributed under the License is distributed on an "AS IS" BASIS,
+	WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+	See the License for the specific language governing permissions and
+	limitations under the License.
+*/
+
+package server
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/loopholelabs/releaser/pkg/api"
+	"github.com/loopholelabs/releaser/pkg/cache"
+	"gopkg.in/yaml.v3"
+	"strings"
+)
+
+const (
+	wingetManifestType    = "singleton"
+	wingetManifestVersion = "1.6.0"
+	wingetPackageLocale   = "en-US"
+	wingetInstallerType   = "zip"
+	wingetNestedInstaller = "portable"
+	wingetExecutable      = ".exe"
+)
+
+// wingetArchitectures maps GOARCH values to winget architectures
+var wingetArchitectures = map[string]string{
+	"amd64": "x64",
+	"386":   "x86",
+	"arm64": "arm64",
+	"arm":   "arm",
+}
+
+type WingetNestedInstallerFile struct {
+	RelativeFilePath     string `yaml:"RelativeFilePath"`
+	PortableCommandAlias string `yaml:"PortableCommandAlias,omitempty"`
+}
+
+type WingetInstaller struct {
+	Architecture         string                       `yaml:"Architecture"`
+	InstallerType        string                       `yaml:"InstallerType"`
+	NestedInstallerType  string                       `yaml:"NestedInstallerType"`
+	NestedInstallerFiles []*WingetNestedInstallerFile `yaml:"NestedInstallerFiles"`
+	InstallerUrl         string                       `yaml:"InstallerUrl"`
+	InstallerSha256      string                       `yaml:"InstallerSha256"`
+}
+
+// WingetManifest is a winget singleton manifest
+type WingetManifest struct {
+	PackageIdentifier string             `yaml:"PackageIdentifier"`
+	PackageVersion    string             `yaml:"PackageVersion"`
+	PackageLocale     string             `yaml:"PackageLocale"`
+	Publisher         string             `yaml:"Publisher"`
+	PackageName       string             `yaml:"PackageName"`
+	License           string             `yaml:"License"`
+	ShortDescription  string             `yaml:"ShortDescription"`
+	Installers        []*WingetInstaller `yaml:"Installers"`
+	ManifestType      string             `yaml:"ManifestType"`
+	ManifestVersion   string             `yaml:"ManifestVersion"`
+}
+
+// GetLatestWingetManifest returns the winget manifest for the latest release
+func (s *Server) GetLatestWingetManifest(ctx *fiber.Ctx) error {
+	latestReleaseName := s.cache.GetLatestReleaseName()
+	if len(latestReleaseName) == 0 {
+		return ctx.Status(fiber.StatusInternalServerError).SendString("no releases available")
+	}
+	return s.getWingetManifest(ctx, latestReleaseName)
+}
+
+// GetWingetManifest returns the winget manifest for the given release
+func (s *Server) GetWingetManifest(ctx *fiber.Ctx) error {
+	return s.getWingetManifest(ctx, s.releaseNameParam(ctx))
+}
+
+func (s *Server) getWingetManifest(ctx *fiber.Ctx, releaseName string) error {
+	if !s.cache.ReleaseNameExists(releaseName) {
+		return ctx.Status(fiber.StatusNotFound).SendString("release not found")
+	}
+
+	manifest := s.wingetManifest(releaseName, s.cache.GetReleaseArtifacts(releaseName))
+	if len(manifest.Installers) == 0 {
+		return ctx.Status(fiber.StatusNotFound).SendString("no windows artifacts available")
+	}
+
+	if ctx.Query(api.Analytics) != "false" {
+		s.helper.Printer.Printf("Received GetWingetManifest from %s (request %s)\n", ctx.IP(), requestID(ctx))
+		s.event(ctx, "winget_manifest", map[string]string{"release_name": releaseName})
+	}
+
+	body, err := yaml.Marshal(manifest)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).SendString("unable to generate winget manifest")
+	}
+
+	ctx.Response().Header.SetContentType(fiber.MIMETextPlainCharsetUTF8)
+	return ctx.Send(body)
+}
+
+// wingetManifest generates the winget manifest for the given release from its zip artifacts
+func (s *Server) wingetManifest(releaseName string, artifacts []*cache.Artifact) *WingetManifest {
+	winget := s.helper.Config.Winget
+	manifest := &WingetManifest{
+		PackageIdentifier: winget.PackageIdentifier,
+		PackageVersion:    strings.TrimPrefix(releaseName, "v"),
+		PackageLocale:     wingetPackageLocale,
+		Publisher:         winget.Publisher,
+		PackageName:       winget.PackageName,
+		License:           winget.License,
+		ShortDescription:  winget.ShortDescription,
+		ManifestType:      wingetManifestType,
+		ManifestVersion:   wingetManifestVersion,
+	}
+
+	if manifest.Publisher == "" {
+		manifest.Publisher = s.helper.Config.RepositoryOwner
+	}
+
+	if manifest.PackageName == "" {
+		manifest.PackageName = s.helper.Config.Repository
+	}
+
+	binaryName := s.defaultBinaryName()
+	for _, artifact := range artifacts {
+		if artifact.OS != "windows" || artifact.BinaryName != binaryName || artifact.Checksum == "" || !strings.HasSuffix(artifact.Name, ".zip") {
+			continue
+		}
+
+		architecture, ok := wingetArchitectures[artifact.Arch]
+		if !ok {
+			continue
+		}
+
+		manifest.Installers = append(manifest.Installers, &WingetInstaller{
+			Architecture:         architecture,
+			InstallerType:        wingetInstallerType,
+			NestedInstallerType:  wingetNestedInstaller,
+			NestedInstallerFiles: s.wingetNestedInstallerFiles(),
+			InstallerUrl:         s.artifactURL(releaseName, artifact),
+			InstallerSha256:      strings.ToUpper(artifact.Checksum),
+		})
+	}
+
+	return manifest
+}
+
+// wingetNestedInstallerFiles returns the executables inside a windows artifact
+func (s *Server) wingetNestedInstallerFiles() []*WingetNestedInstallerFile {
+	if len(s.helper.Config.Binaries) == 0 {
+		return []*WingetNestedInstallerFile{wingetNestedInstallerFile(s.helper.Config.GetInstallName("windows"), s.helper.Config.Binary)}
+	}
+
+	files := make([]*WingetNestedInstallerFile, 0, len(s.helper.Config.Binaries))
+	for _, binary := range s.helper.Config.Binaries {
+		files = append(files, wingetNestedInstallerFile(binary.GetPath(), binary.Name))
+	}
+	return files
+}
+
+func wingetNestedInstallerFile(path string, alias string) *WingetNestedInstallerFile {
+	if !strings.HasSuffix(path, wingetExecutable) {
+		path += wingetExecutable
+	}
+	return &WingetNestedInstallerFile{
+		RelativeFilePath:     path,
+		PortableCommandAlias: strings.TrimSuffix(alias, wingetExecutable),
+	}
+}